@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// chaosToggleRequest espelha o payload aceito por chaosAdminHandler em
+// dtm/tcc/services/payment/chaos.go - o serviço de pagamento, alvo do payment_fail_rate - para
+// ajustar a taxa de erro HTTP injetada em tempo real. scenario.ChaosAdminURL deve apontar para
+// <payment-service-tcc>/admin/chaos.
+type chaosToggleRequest struct {
+	HTTPErrorRate float64 `json:"http_error_rate"`
+}
+
+// RunChaosHook aguarda a metade da duração do cenário e então dobra o payment_fail_rate
+// configurado, permitindo observar no mesmo gráfico como cada protocolo reage a uma degradação
+// súbita (compensação em SAGA/TCC vs. bloqueio em XA)
+func RunChaosHook(ctx context.Context, scenario *Scenario) {
+	if scenario.ChaosAdminURL == "" {
+		log.Printf("⚠️ [CHAOS HOOK] chaos_admin_url not configured, skipping mid-run toggle")
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(scenario.Duration / 2):
+	}
+
+	toggledRate := scenario.FailureInjection.PaymentFailRate * 2
+	if toggledRate == 0 {
+		toggledRate = 0.5
+	}
+
+	if err := postChaosToggle(ctx, scenario.ChaosAdminURL, toggledRate); err != nil {
+		log.Printf("⚠️ [CHAOS HOOK] failed to toggle payment_fail_rate: %v", err)
+		return
+	}
+
+	log.Printf("🔥 [CHAOS HOOK] toggled payment_fail_rate to %.2f mid-run", toggledRate)
+}
+
+func postChaosToggle(ctx context.Context, adminURL string, rate float64) error {
+	body, err := json.Marshal(chaosToggleRequest{HTTPErrorRate: rate})
+	if err != nil {
+		return fmt.Errorf("failed to marshal chaos toggle request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, adminURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build chaos toggle request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("chaos toggle request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("chaos toggle request returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
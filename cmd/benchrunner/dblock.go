@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DBLockWaitSampler amostra pg_stat_activity periodicamente para medir quanto tempo as conexões
+// ativas passam esperando por locks de linha - o custo que a estratégia pessimista (FOR UPDATE)
+// usada por SAGA/TCC/XA paga sob concorrência, e que não aparece em nenhuma métrica de aplicação
+type DBLockWaitSampler struct {
+	dsn     string
+	mutex   sync.Mutex
+	samples []float64
+}
+
+// NewDBLockWaitSampler cria um sampler para o DSN informado. DSN vazio é aceito - o sampler
+// simplesmente não roda, e AverageWaitMillis retorna 0, deixando o restante do benchmark seguir
+func NewDBLockWaitSampler(dsn string) *DBLockWaitSampler {
+	return &DBLockWaitSampler{dsn: dsn}
+}
+
+// Run amostra pg_stat_activity a cada segundo até o contexto ser cancelado
+func (s *DBLockWaitSampler) Run(ctx context.Context) {
+	if s.dsn == "" {
+		return
+	}
+
+	pool, err := pgxpool.New(ctx, s.dsn)
+	if err != nil {
+		log.Printf("⚠️ [DB LOCK SAMPLER] failed to connect: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sampleOnce(ctx, pool)
+		}
+	}
+}
+
+func (s *DBLockWaitSampler) sampleOnce(ctx context.Context, pool *pgxpool.Pool) {
+	query := `
+		SELECT EXTRACT(EPOCH FROM (NOW() - query_start)) * 1000
+		FROM pg_stat_activity
+		WHERE wait_event_type = 'Lock' AND state = 'active'
+	`
+
+	rows, err := pool.Query(ctx, query)
+	if err != nil {
+		log.Printf("⚠️ [DB LOCK SAMPLER] query failed: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	var collected []float64
+	for rows.Next() {
+		var waitMillis float64
+		if err := rows.Scan(&waitMillis); err != nil {
+			continue
+		}
+		collected = append(collected, waitMillis)
+	}
+
+	if len(collected) == 0 {
+		return
+	}
+
+	s.mutex.Lock()
+	s.samples = append(s.samples, collected...)
+	s.mutex.Unlock()
+}
+
+// AverageWaitMillis retorna a média de todas as amostras de espera por lock coletadas durante a
+// execução. Retorna 0 se nenhum DSN foi informado ou nenhuma amostra foi coletada - não falha o
+// benchmark por conta de uma métrica auxiliar.
+func (s *DBLockWaitSampler) AverageWaitMillis() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.samples) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, v := range s.samples {
+		sum += v
+	}
+	return sum / float64(len(s.samples))
+}
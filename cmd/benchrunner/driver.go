@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+const (
+	// histogramMinValue/histogramMaxValue cobrem de 1ms a 1 minuto, faixa suficiente para detectar
+	// tanto a latência normal de uma fase TRY/CONFIRM quanto um bloqueio prolongado sob XA
+	histogramMinValue  = 1
+	histogramMaxValue  = 60_000
+	histogramSigFigits = 3
+)
+
+// orderSubmission é o payload enviado ao endpoint de criação de pedidos, comum aos quatro
+// protocolos - cada serviço de orders já expõe essa forma de requisição
+type orderSubmission struct {
+	UserID    string `json:"user_id"`
+	ProductID string `json:"product_id"`
+	Amount    int    `json:"amount"`
+}
+
+type orderResult struct {
+	Status string `json:"status"`
+	Phase  string `json:"phase,omitempty"`
+}
+
+// phaseHistograms agrega o histograma end-to-end e um histograma por fase (try/confirm/cancel),
+// protegidos por mutex porque múltiplos workers gravam concorrentemente
+type phaseHistograms struct {
+	mutex    sync.Mutex
+	endToEnd *hdrhistogram.Histogram
+	byPhase  map[string]*hdrhistogram.Histogram
+}
+
+func newPhaseHistograms() *phaseHistograms {
+	return &phaseHistograms{
+		endToEnd: hdrhistogram.New(histogramMinValue, histogramMaxValue, histogramSigFigits),
+		byPhase:  make(map[string]*hdrhistogram.Histogram),
+	}
+}
+
+func (p *phaseHistograms) recordEndToEnd(millis int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	_ = p.endToEnd.RecordValue(millis)
+}
+
+func (p *phaseHistograms) recordPhase(phase string, millis int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	h, ok := p.byPhase[phase]
+	if !ok {
+		h = hdrhistogram.New(histogramMinValue, histogramMaxValue, histogramSigFigits)
+		p.byPhase[phase] = h
+	}
+	_ = h.RecordValue(millis)
+}
+
+// Driver gera carga contra o serviço de orders de acordo com o Scenario e acumula os números
+// necessários para montar o Report final
+type Driver struct {
+	scenario   *Scenario
+	collector  *MetricsCollector
+	httpClient *http.Client
+	hist       *phaseHistograms
+
+	attempted   int64
+	committed   int64
+	compensated int64
+}
+
+// NewDriver monta um Driver pronto para rodar o cenário informado
+func NewDriver(scenario *Scenario, collector *MetricsCollector) *Driver {
+	return &Driver{
+		scenario:   scenario,
+		collector:  collector,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		hist:       newPhaseHistograms(),
+	}
+}
+
+// Run dispara a carga pelo tempo configurado no cenário e devolve o Report consolidado
+func (d *Driver) Run(ctx context.Context) (*Report, error) {
+	runCtx, cancel := context.WithTimeout(ctx, d.scenario.Duration)
+	defer cancel()
+
+	sampler := NewDBLockWaitSampler(d.scenario.DatabaseDSN)
+	go sampler.Run(runCtx)
+
+	semaphore := make(chan struct{}, d.scenario.Concurrency)
+	var wg sync.WaitGroup
+
+	ticker := time.NewTicker(time.Second / time.Duration(d.scenario.RPS))
+	defer ticker.Stop()
+
+	log.Printf("🚀 [BENCHRUNNER] starting run | protocol=%s rps=%d concurrency=%d duration=%s",
+		d.scenario.Protocol, d.scenario.RPS, d.scenario.Concurrency, d.scenario.Duration)
+
+loop:
+	for {
+		select {
+		case <-runCtx.Done():
+			break loop
+		case <-ticker.C:
+			semaphore <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				d.fireOnce(runCtx)
+			}()
+		}
+	}
+
+	wg.Wait()
+
+	attempted := atomic.LoadInt64(&d.attempted)
+	committed := atomic.LoadInt64(&d.committed)
+	compensated := atomic.LoadInt64(&d.compensated)
+
+	var compensationRate float64
+	if attempted > 0 {
+		compensationRate = float64(compensated) / float64(attempted)
+	}
+
+	report := &Report{
+		Protocol:            d.scenario.Protocol,
+		Attempted:           attempted,
+		Committed:           committed,
+		Compensated:         compensated,
+		CompensationRate:    compensationRate,
+		ThroughputPerSecond: float64(committed) / d.scenario.Duration.Seconds(),
+		AvgDBLockWaitMillis: sampler.AverageWaitMillis(),
+		EndToEndLatency:     histogramSummary(d.hist.endToEnd),
+		PhaseLatency:        perPhaseSummary(d.hist.byPhase),
+	}
+
+	return report, nil
+}
+
+// fireOnce submete um pedido, aguarda o resultado e registra latência/contadores
+func (d *Driver) fireOnce(ctx context.Context) {
+	atomic.AddInt64(&d.attempted, 1)
+
+	start := time.Now()
+	result, err := d.submitOrder(ctx)
+	elapsed := time.Since(start)
+
+	d.hist.recordEndToEnd(elapsed.Milliseconds())
+	d.collector.observeLatency(d.scenario.Protocol, elapsed)
+
+	if err != nil {
+		log.Printf("⚠️ [BENCHRUNNER] order submission failed: %v", err)
+		return
+	}
+
+	if result.Phase != "" {
+		d.hist.recordPhase(result.Phase, elapsed.Milliseconds())
+	}
+
+	switch result.Status {
+	case "completed", "confirmed", "committed":
+		atomic.AddInt64(&d.committed, 1)
+		d.collector.incCommit(d.scenario.Protocol)
+	case "compensated", "cancelled", "rejected", "failed":
+		atomic.AddInt64(&d.compensated, 1)
+		d.collector.incCompensation(d.scenario.Protocol)
+	}
+}
+
+// submitOrder escolhe um produto/usuário aleatório do catálogo simulado e envia a requisição de
+// criação de pedido ao serviço de orders do protocolo sob teste
+func (d *Driver) submitOrder(ctx context.Context) (*orderResult, error) {
+	submission := orderSubmission{
+		UserID:    fmt.Sprintf("user-%d", rand.Intn(d.scenario.WalletSeedAmount+1)),
+		ProductID: fmt.Sprintf("product-%d", rand.Intn(d.scenario.ProductCatalogSize)),
+		Amount:    1,
+	}
+
+	body, err := json.Marshal(submission)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal order submission: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.scenario.OrdersServiceURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build order request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("order request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result orderResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode order response: %w", err)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsCollector expõe um endpoint Prometheus para acompanhar a execução do benchmark em tempo
+// real num dashboard do Grafana. Diferente dos serviços dtm/*, que publicam métricas via OTel
+// (push, OTLP), esta ferramenta é um driver ad-hoc de linha de comando - faz mais sentido aqui
+// expor um endpoint de scrape tradicional do que subir um pipeline OTLP só para uma execução.
+type MetricsCollector struct {
+	registry           *prometheus.Registry
+	endToEndLatency    *prometheus.HistogramVec
+	commitsTotal       *prometheus.CounterVec
+	compensationsTotal *prometheus.CounterVec
+}
+
+// NewMetricsCollector registra as métricas do benchrunner num registry próprio
+func NewMetricsCollector() *MetricsCollector {
+	registry := prometheus.NewRegistry()
+	factory := promauto.With(registry)
+
+	return &MetricsCollector{
+		registry: registry,
+		endToEndLatency: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "benchrunner_end_to_end_latency_seconds",
+			Help:    "End-to-end latency of a submitted order, from request to final status",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"protocol"}),
+		commitsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "benchrunner_commits_total",
+			Help: "Total number of orders that committed successfully",
+		}, []string{"protocol"}),
+		compensationsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "benchrunner_compensations_total",
+			Help: "Total number of orders that were compensated/rolled back",
+		}, []string{"protocol"}),
+	}
+}
+
+func (m *MetricsCollector) observeLatency(protocol Protocol, d time.Duration) {
+	m.endToEndLatency.WithLabelValues(string(protocol)).Observe(d.Seconds())
+}
+
+func (m *MetricsCollector) incCommit(protocol Protocol) {
+	m.commitsTotal.WithLabelValues(string(protocol)).Inc()
+}
+
+func (m *MetricsCollector) incCompensation(protocol Protocol) {
+	m.compensationsTotal.WithLabelValues(string(protocol)).Inc()
+}
+
+// ServeMetrics sobe o endpoint /metrics e bloqueia até o contexto ser cancelado
+func ServeMetrics(ctx context.Context, addr string, collector *MetricsCollector) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(collector.registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- fmt.Errorf("metrics server error: %w", err)
+		}
+		close(errCh)
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		return err
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// LatencySummary condensa um histograma HDR nos percentis que importam para comparar protocolos
+type LatencySummary struct {
+	P50Millis int64 `json:"p50_ms"`
+	P95Millis int64 `json:"p95_ms"`
+	P99Millis int64 `json:"p99_ms"`
+	MaxMillis int64 `json:"max_ms"`
+}
+
+// Report é o resultado final de uma execução de benchmark, pronto para ser serializado em
+// JSON/CSV e comparado lado a lado com execuções de outros protocolos
+type Report struct {
+	Protocol            Protocol                  `json:"protocol"`
+	Attempted           int64                     `json:"attempted"`
+	Committed           int64                     `json:"committed"`
+	Compensated         int64                     `json:"compensated"`
+	CompensationRate    float64                   `json:"compensation_rate"`
+	ThroughputPerSecond float64                   `json:"throughput_per_second"`
+	AvgDBLockWaitMillis float64                   `json:"avg_db_lock_wait_ms"`
+	EndToEndLatency     LatencySummary            `json:"end_to_end_latency"`
+	PhaseLatency        map[string]LatencySummary `json:"phase_latency"`
+}
+
+func histogramSummary(h *hdrhistogram.Histogram) LatencySummary {
+	if h == nil || h.TotalCount() == 0 {
+		return LatencySummary{}
+	}
+	return LatencySummary{
+		P50Millis: h.ValueAtQuantile(50),
+		P95Millis: h.ValueAtQuantile(95),
+		P99Millis: h.ValueAtQuantile(99),
+		MaxMillis: h.Max(),
+	}
+}
+
+func perPhaseSummary(byPhase map[string]*hdrhistogram.Histogram) map[string]LatencySummary {
+	summary := make(map[string]LatencySummary, len(byPhase))
+	for phase, h := range byPhase {
+		summary[phase] = histogramSummary(h)
+	}
+	return summary
+}
+
+// WriteJSON grava o relatório como JSON indentado
+func (r *Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+	return nil
+}
+
+// WriteCSV grava uma única linha de resumo, pensada para ser concatenada entre execuções de
+// saga/tcc/xa e carregada direto numa planilha comparativa
+func (r *Report) WriteCSV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV report: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{
+		"protocol", "attempted", "committed", "compensated", "compensation_rate",
+		"throughput_per_second", "avg_db_lock_wait_ms",
+		"e2e_p50_ms", "e2e_p95_ms", "e2e_p99_ms", "e2e_max_ms",
+	}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	row := []string{
+		string(r.Protocol),
+		strconv.FormatInt(r.Attempted, 10),
+		strconv.FormatInt(r.Committed, 10),
+		strconv.FormatInt(r.Compensated, 10),
+		strconv.FormatFloat(r.CompensationRate, 'f', 4, 64),
+		strconv.FormatFloat(r.ThroughputPerSecond, 'f', 2, 64),
+		strconv.FormatFloat(r.AvgDBLockWaitMillis, 'f', 2, 64),
+		strconv.FormatInt(r.EndToEndLatency.P50Millis, 10),
+		strconv.FormatInt(r.EndToEndLatency.P95Millis, 10),
+		strconv.FormatInt(r.EndToEndLatency.P99Millis, 10),
+		strconv.FormatInt(r.EndToEndLatency.MaxMillis, 10),
+	}
+	if err := writer.Write(row); err != nil {
+		return fmt.Errorf("failed to write CSV row: %w", err)
+	}
+
+	return nil
+}
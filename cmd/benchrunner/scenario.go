@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Protocol identifica qual padrão de transação distribuída está sendo exercitado pelo cenário
+type Protocol string
+
+const (
+	ProtocolSaga Protocol = "saga"
+	ProtocolTCC  Protocol = "tcc"
+	ProtocolXA   Protocol = "xa"
+	ProtocolMsg  Protocol = "msg"
+)
+
+// FailureInjection controla as taxas de falha simuladas durante a execução do cenário
+type FailureInjection struct {
+	PaymentFailRate      float64 `yaml:"payment_fail_rate"`
+	InventoryTimeoutRate float64 `yaml:"inventory_timeout_rate"`
+	MidRunToggle         bool    `yaml:"mid_run_toggle"`
+}
+
+// Scenario descreve uma execução de benchmark contra um único protocolo
+type Scenario struct {
+	Protocol           Protocol         `yaml:"protocol"`
+	RPS                int              `yaml:"rps"`
+	Duration           time.Duration    `yaml:"duration"`
+	Concurrency        int              `yaml:"concurrency"`
+	ProductCatalogSize int              `yaml:"product_catalog_size"`
+	WalletSeedAmount   int              `yaml:"wallet_seed_amount"`
+	FailureInjection   FailureInjection `yaml:"failure_injection"`
+	OrdersServiceURL   string           `yaml:"orders_service_url"`
+	ChaosAdminURL      string           `yaml:"chaos_admin_url"`
+	DatabaseDSN        string           `yaml:"database_dsn"`
+}
+
+// LoadScenario lê e valida um arquivo de cenário YAML
+func LoadScenario(path string) (*Scenario, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+
+	var scenario Scenario
+	if err := yaml.Unmarshal(raw, &scenario); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario YAML: %w", err)
+	}
+
+	if err := scenario.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+
+	return &scenario, nil
+}
+
+func (s *Scenario) validate() error {
+	switch s.Protocol {
+	case ProtocolSaga, ProtocolTCC, ProtocolXA, ProtocolMsg:
+	default:
+		return fmt.Errorf("protocol must be one of saga|tcc|xa|msg, got %q", s.Protocol)
+	}
+
+	if s.RPS <= 0 {
+		return fmt.Errorf("rps must be greater than 0")
+	}
+	if s.Concurrency <= 0 {
+		return fmt.Errorf("concurrency must be greater than 0")
+	}
+	if s.Duration <= 0 {
+		return fmt.Errorf("duration must be greater than 0")
+	}
+	if s.OrdersServiceURL == "" {
+		return fmt.Errorf("orders_service_url is required")
+	}
+
+	return nil
+}
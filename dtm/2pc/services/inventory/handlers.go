@@ -1,40 +1,48 @@
 package main
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"strings"
 
 	"github.com/dtm-labs/client/dtmcli"
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // HandleXADecreaseStock handler para operação XA do 2PC
 // Usa dtmcli.XaLocalTransaction para gerenciar XA START/PREPARE/COMMIT/ROLLBACK
 func HandleXADecreaseStock(uc *InventoryUseCase, dbConf *dtmcli.DBConf) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Extrair trace context do header se presente
-		traceparent := c.GetHeader("traceparent")
+		// otelgin.Middleware já extraiu traceparent/tracestate/baggage dos headers da requisição
+		// (incluindo as chamadas de branch do DTM, que reenviam o header injetado via
+		// xa.BranchHeaders) e populou c.Request.Context() com o span context remoto
 		ctx := c.Request.Context()
-		if traceparent != "" {
-			// Parse traceparent formato: 00-{trace-id}-{parent-span-id}-{flags}
-			ctx = ExtractTraceContext(ctx, traceparent)
-		}
+
+		// branch_type distingue as duas passagens pelo mesmo endpoint: "action" é o PREPARE com
+		// payload, "commit"/"rollback" é a segunda fase, sem payload - usado aqui só para rotular
+		// o evento de transição de fase publicado no log JetStream (tx.events.xa.*)
+		branchType := c.Request.URL.Query().Get("branch_type")
+		gid := c.Request.URL.Query().Get("gid")
 
 		// dtmcli.XaLocalTransaction gerencia todo o ciclo XA automaticamente
 		// Na fase PREPARE: body contém o payload
 		// Na fase COMMIT/ROLLBACK: body é nil (DTM chama novamente)
+		noop := false
 		err := dtmcli.XaLocalTransaction(c.Request.URL.Query(), *dbConf, func(db *sql.DB, xa *dtmcli.Xa) error {
 			// Parse body apenas se não for nil (fase PREPARE)
 			body := c.Request.Body
 			if body == nil {
 				// Fase COMMIT/ROLLBACK - DTM gerencia automaticamente
 				log.Printf("✅ [XA] Commit/Rollback phase handled by DTM")
+				if branchType == "rollback" {
+					emitTxEvent(ctx, txEvents, "xa.rolledback", gid, "", "")
+				} else {
+					emitTxEvent(ctx, txEvents, "xa.committed", gid, "", "")
+				}
 				return nil
 			}
 
@@ -51,9 +59,24 @@ func HandleXADecreaseStock(uc *InventoryUseCase, dbConf *dtmcli.DBConf) gin.Hand
 
 			// Executa a operação XA (PREPARE phase)
 			log.Printf("📦 [XA PREPARE] Decreasing stock for ProductID=%s, OrderID=%s", req.ProductID, req.OrderID)
-			err := uc.DecreaseStockXA(db, req)
+			err := retryTransientXAPrepare(span, func() error {
+				if err := maybeChaosDeadlock(chaosCfg); err != nil {
+					return err
+				}
+				return uc.DecreaseStockXA(db, req)
+			})
+			if errors.Is(err, ErrNoChange) {
+				// Retentativa do DTM sobre um débito já aplicado: não é uma falha, apenas não há
+				// nada a fazer nesta passagem - deixa o DTM prosseguir (commit) normalmente
+				noop = true
+				span.SetAttributes(attribute.Bool("saga.noop", true))
+				_ = spanCtx
+				return nil
+			}
 			if err != nil {
 				span.RecordError(err)
+			} else {
+				emitTxEvent(spanCtx, txEvents, "xa.prepared", gid, req.OrderID, req.ProductID)
 			}
 			_ = spanCtx // Use context if needed
 			return err
@@ -65,6 +88,11 @@ func HandleXADecreaseStock(uc *InventoryUseCase, dbConf *dtmcli.DBConf) gin.Hand
 			return
 		}
 
+		if noop {
+			c.JSON(http.StatusOK, gin.H{"status": "unchanged"})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{"status": "xa_success"})
 	}
 }
@@ -75,33 +103,3 @@ func HandleHealth() gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"status": "healthy", "service": "inventory-service-2pc"})
 	}
 }
-
-// ExtractTraceContext extrai trace context do header traceparent formato W3C
-// Format: 00-{trace-id}-{parent-span-id}-{flags}
-func ExtractTraceContext(ctx context.Context, traceparent string) context.Context {
-	parts := strings.Split(traceparent, "-")
-	if len(parts) != 4 {
-		return ctx
-	}
-
-	traceIDStr := parts[1]
-	spanIDStr := parts[2]
-
-	traceID, err := trace.TraceIDFromHex(traceIDStr)
-	if err != nil {
-		return ctx
-	}
-
-	spanID, err := trace.SpanIDFromHex(spanIDStr)
-	if err != nil {
-		return ctx
-	}
-
-	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
-		TraceID:    traceID,
-		SpanID:     spanID,
-		TraceFlags: trace.FlagsSampled,
-	})
-
-	return trace.ContextWithSpanContext(ctx, spanContext)
-}
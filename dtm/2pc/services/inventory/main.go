@@ -12,6 +12,7 @@ import (
 	"github.com/dtm-labs/client/dtmcli"
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
+	"github.com/nats-io/nats.go/jetstream"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
@@ -22,7 +23,8 @@ import (
 )
 
 var (
-	dbConf *dtmcli.DBConf
+	dbConf   *dtmcli.DBConf
+	txEvents jetstream.JetStream
 )
 
 func main() {
@@ -42,6 +44,14 @@ func main() {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Transaction event log: stream JetStream durável com o histórico de transição de fase
+	// (xa.prepared, xa.committed, xa.rolledback) - não afeta o fluxo XA síncrono se o NATS
+	// estiver fora do ar
+	txEvents, err = initTxEvents(context.Background(), getEnv("NATS_URL", "nats://nats:4222"))
+	if err != nil {
+		log.Printf("⚠️ Failed to initialize transaction event log, continuing without it: %v", err)
+	}
+
 	// Setup repositories and use cases
 	inventoryRepository := NewPostgresInventoryRepository()
 	inventoryUseCase := NewInventoryUseCase(inventoryRepository)
@@ -53,8 +63,14 @@ func main() {
 	// Health check
 	r.GET("/health", HandleHealth())
 
-	// XA endpoint (2PC)
-	r.POST("/api/inventory/xa", HandleXADecreaseStock(inventoryUseCase, dbConf))
+	// XA endpoint (2PC) - chaosMiddleware injeta falhas configuráveis (latência/500s/conn drop)
+	// antes do PREPARE; o deadlock simulado é injetado mais fundo, no retry de
+	// retryTransientXAPrepare (ver chaos.go/handlers.go)
+	r.POST("/api/inventory/xa", chaosMiddleware(chaosCfg, "xa"), HandleXADecreaseStock(inventoryUseCase, dbConf))
+
+	// Admin endpoint - ajusta a injeção de falhas em tempo real (ver chaos.go)
+	r.GET("/admin/chaos", chaosAdminHandler(chaosCfg))
+	r.POST("/admin/chaos", chaosAdminHandler(chaosCfg))
 
 	port := getEnv("PORT", "8081")
 	log.Printf("🚀 Inventory Service (2PC/XA) listening on port %s", port)
@@ -146,7 +162,12 @@ func initTracer() (*sdktrace.TracerProvider, error) {
 	)
 
 	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
+	// Composto com Baggage (além de TraceContext) para que atributos de negócio propagados pelo
+	// orquestrador sobrevivam ao salto entre branches XA
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
 
 	return tp, nil
 }
@@ -15,14 +15,14 @@ type ProductInventory struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
-// XAActionRequest representa o payload das requisições XA (sempre 1 unidade)
+// XAActionRequest representa o payload das requisições XA (sempre 1 unidade). O trace context
+// não viaja mais neste payload - é propagado via headers (traceparent/tracestate/baggage) pelo
+// TextMapPropagator global, anexados às chamadas de branch via xa.BranchHeaders
 type XAActionRequest struct {
 	OrderID    string `json:"order_id"`
 	UserID     string `json:"user_id"`
 	ProductID  string `json:"product_id"`
 	TotalPrice int    `json:"total_price"`
-	TraceID    string `json:"trace_id"`
-	SpanID     string `json:"span_id"`
 }
 
 // InventoryRepository define as operações de persistência de inventário (sempre 1 unidade)
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"log"
 )
@@ -16,6 +18,21 @@ func NewPostgresInventoryRepository() *PostgresInventoryRepository {
 // DTM gerencia PREPARE/COMMIT automaticamente via XaLocalTransaction
 // Recebe *sql.DB que já está dentro de uma transação XA gerenciada pelo DTM
 func (r *PostgresInventoryRepository) DecreaseStockXA(db *sql.DB, productID, orderID string) error {
+	// Idempotência: se já existe uma movimentação de saída registrada para este OrderID, a
+	// retentativa do DTM não deve decrementar o estoque de novo
+	var alreadyDecreased bool
+	err := db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM inventory_movements WHERE order_id = $1 AND movement_type = 'decrease'
+		)
+	`, orderID).Scan(&alreadyDecreased)
+	if err != nil {
+		return fmt.Errorf("failed to check existing movement: %w", err)
+	}
+	if alreadyDecreased {
+		return ErrNoChange
+	}
+
 	// Atualiza current_stock (já dentro de transação XA do DTM)
 	updateQuery := `
 		UPDATE products_inventory
@@ -48,5 +65,38 @@ func (r *PostgresInventoryRepository) DecreaseStockXA(db *sql.DB, productID, ord
 	}
 
 	log.Printf("✅ [XA] Decreased 1 unit of %s", productID)
+
+	if err := appendSagaEventXA(db, "inventory", orderID, "xa", "completed", productID); err != nil {
+		log.Printf("⚠️ [SAGA LOG] failed to append XA event | OrderID=%s | Error=%v", orderID, err)
+	}
+
+	return nil
+}
+
+// appendSagaEventXA grava um evento no log de auditoria saga_events dentro da mesma
+// transação XA gerenciada pelo DTM, encadeando-o ao último evento conhecido do OrderID
+func appendSagaEventXA(db *sql.DB, service, orderID, phase, status, productID string) error {
+	payloadHash := hashXAPayload(orderID, service, productID)
+
+	var prevEventID sql.NullInt64
+	err := db.QueryRow(`SELECT id FROM saga_events WHERE order_id = $1 ORDER BY id DESC LIMIT 1`, orderID).Scan(&prevEventID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to look up previous saga event: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO saga_events (order_id, service, phase, status, payload_hash, prev_event_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`, orderID, service, phase, status, payloadHash, prevEventID)
+	if err != nil {
+		return fmt.Errorf("failed to append saga event: %w", err)
+	}
+
 	return nil
 }
+
+// hashXAPayload calcula um hash estável dos campos relevantes do payload XA
+func hashXAPayload(orderID, service, detail string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%s", orderID, service, detail)))
+	return hex.EncodeToString(sum[:])
+}
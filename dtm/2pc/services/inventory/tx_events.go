@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pkg/events já resolve a emissão de eventos de ciclo de vida da transação (xa.prepared,
+// xa.committed, xa.rolledback) de forma reutilizável, mas sem go.mod não há como importar o
+// módulo entre pastas - initTxEvents duplica localmente a parte mínima necessária (conectar,
+// garantir o stream, publicar). Mesmo stream/subject convention usado pelo orders-service SAGA,
+// para que os dois protocolos apareçam no mesmo log de eventos.
+const txEventsStreamName = "TXEVENTS"
+
+// initTxEvents conecta ao NATS e garante o stream TXEVENTS (armazenamento em arquivo, subjects
+// "tx.events.>") usado pelo emissor de eventos de ciclo de vida da transação
+func initTxEvents(ctx context.Context, natsURL string) (jetstream.JetStream, error) {
+	nc, err := nats.Connect(natsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     txEventsStreamName,
+		Subjects: []string{"tx.events.>"},
+		Storage:  jetstream.FileStorage,
+	}); err != nil {
+		return nil, err
+	}
+
+	return js, nil
+}
+
+// emitTxEvent publica um evento de transição de fase, sem interromper o fluxo de negócio em
+// caso de falha (o log de eventos é observacional, não faz parte do caminho crítico)
+func emitTxEvent(ctx context.Context, js jetstream.JetStream, eventType, gid, orderID, productID string) {
+	if js == nil {
+		return
+	}
+
+	event := TxEvent{
+		GID:       gid,
+		OrderID:   orderID,
+		ProductID: productID,
+		EventType: eventType,
+		EmittedAt: time.Now(),
+	}
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		event.TraceID = span.SpanContext().TraceID().String()
+		event.SpanID = span.SpanContext().SpanID().String()
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("⚠️ [TX EVENTS] failed to marshal event %s | OrderID=%s | Error=%v", eventType, orderID, err)
+		return
+	}
+
+	subject := "tx.events." + eventType
+	if _, err := js.Publish(ctx, subject, payload); err != nil {
+		log.Printf("⚠️ [TX EVENTS] failed to publish event %s | OrderID=%s | Error=%v", eventType, orderID, err)
+	}
+}
+
+// TxEvent é a mesma forma de pkg/events.TransactionEvent, duplicada aqui pela mesma razão de
+// initTxEvents - sem go.mod, pkg/events não pode ser importado diretamente
+type TxEvent struct {
+	GID        string    `json:"gid,omitempty"`
+	OrderID    string    `json:"order_id"`
+	TraceID    string    `json:"trace_id,omitempty"`
+	SpanID     string    `json:"span_id,omitempty"`
+	UserID     string    `json:"user_id,omitempty"`
+	ProductID  string    `json:"product_id,omitempty"`
+	EventType  string    `json:"event_type"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	EmittedAt  time.Time `json:"emitted_at"`
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 )
 
@@ -24,6 +25,10 @@ func (uc *InventoryUseCase) DecreaseStockXA(db *sql.DB, req XAActionRequest) err
 		req.ProductID, req.OrderID)
 
 	if err := uc.repository.DecreaseStockXA(db, req.ProductID, req.OrderID); err != nil {
+		if errors.Is(err, ErrNoChange) {
+			log.Printf("ℹ️ [XA] Stock already decreased for OrderID=%s, skipping", req.OrderID)
+			return ErrNoChange
+		}
 		log.Printf("❌ [XA] Failed to decrease stock: %v", err)
 		return err
 	}
@@ -34,6 +39,9 @@ func (uc *InventoryUseCase) DecreaseStockXA(db *sql.DB, req XAActionRequest) err
 // Erros customizados
 var (
 	ErrInsufficientStock = &InventoryError{Message: "insufficient stock"}
+	// ErrNoChange sinaliza que o estoque já havia sido decrementado para este OrderID (retentativa
+	// do DTM) e a escrita foi pulada
+	ErrNoChange = &InventoryError{Message: "no change: stock already decreased"}
 )
 
 type InventoryError struct {
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Política de retry aplicada ao PREPARE da transação XA local deste serviço - distinto do retry
+// de chamada HTTP de branch usado pelo orquestrador (ver dtm/2pc/services/orders/monitored_branch.go),
+// que não se aplica aqui porque este serviço é o participante, não quem chama a branch.
+const (
+	defaultXAPrepareMaxAttempts = 3
+	defaultXAPrepareBackoffBase = 50 * time.Millisecond
+)
+
+// xaPrepareMaxAttempts e xaPrepareBackoffBase lidos uma vez na carga do pacote - mesmo padrão de
+// sagaRetryPolicyFromEnv (dtm/saga/services/orders/retry_policy.go), lendo RETRY_MAX_ATTEMPTS/
+// RETRY_BASE_MS do ambiente para que o benchmark varra a configuração de retry sem recompilar.
+var (
+	xaPrepareMaxAttempts = defaultXAPrepareMaxAttempts
+	xaPrepareBackoffBase = defaultXAPrepareBackoffBase
+)
+
+func init() {
+	if v, err := strconv.Atoi(getEnv("RETRY_MAX_ATTEMPTS", "")); err == nil && v > 0 {
+		xaPrepareMaxAttempts = v
+	}
+	if v, err := strconv.Atoi(getEnv("RETRY_BASE_MS", "")); err == nil && v > 0 {
+		xaPrepareBackoffBase = time.Duration(v) * time.Millisecond
+	}
+}
+
+// retryTransientXAPrepare executa fn até xaPrepareMaxAttempts vezes, retentando apenas em erros
+// transitórios do Postgres (serialization failure 40001, deadlock 40P01, reset de conexão) -
+// qualquer outro erro (inclusive ErrNoChange/regras de negócio) é devolvido na primeira tentativa.
+// Cada tentativa é registrada como um evento no span do PREPARE.
+func retryTransientXAPrepare(span trace.Span, fn func() error) error {
+	var err error
+	backoff := xaPrepareBackoffBase
+
+	for attempt := 1; attempt <= xaPrepareMaxAttempts; attempt++ {
+		span.AddEvent("xa.prepare.attempt", trace.WithAttributes(attribute.Int("xa.prepare.attempt", attempt)))
+
+		err = fn()
+		if err == nil || attempt == xaPrepareMaxAttempts || !isTransientPGError(err) {
+			return err
+		}
+
+		jittered := backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+		span.AddEvent("xa.prepare.retrying", trace.WithAttributes(
+			attribute.Int64("xa.prepare.backoff_ms", jittered.Milliseconds()),
+		))
+		time.Sleep(jittered)
+		backoff *= 2
+	}
+
+	return err
+}
+
+// isTransientPGError reconhece os erros de Postgres que justificam retentar o PREPARE: falha de
+// serialização (40001) e deadlock (40P01) sob isolamento mais estrito, e resets de conexão que o
+// driver lib/pq devolve como erro de rede cru em vez de *pq.Error
+func isTransientPGError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40001", "40P01":
+			return true
+		}
+		return false
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "connection reset") ||
+		strings.Contains(msg, "broken pipe") ||
+		strings.Contains(msg, "EOF")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// batchWorkerPoolSize limita o número de transações XA (2PC) em voo simultaneamente por batch
+	batchWorkerPoolSize = 8
+
+	// batchRetryMaxAttempts é o número máximo de tentativas por item antes de desistir
+	batchRetryMaxAttempts = 3
+
+	// batchRetryBaseDelay é o atraso inicial do backoff exponencial entre tentativas
+	batchRetryBaseDelay = 200 * time.Millisecond
+)
+
+// BatchPlaceOrders executa em paralelo (worker pool limitado) o 2PC completo de cada item do
+// batch e retorna um OrderResult por posição, preservando a ordem de entrada. Diferente dos
+// equivalentes SAGA/TCC (onde o worker apenas registra as branches e retorna rápido), aqui cada
+// worker bloqueia até o PREPARE+COMMIT/ROLLBACK terminar - então o status final de cada item já é
+// "completed"/"failed", nunca "processing". Itens com falha são automaticamente re-tentados com
+// backoff exponencial antes de serem reportados como erro.
+func (uc *OrderUseCase) BatchPlaceOrders(ctx context.Context, reqs []CreateOrderRequest) ([]OrderResult, error) {
+	start := time.Now()
+	results := make([]OrderResult, len(reqs))
+
+	uc.placeOrdersConcurrently(ctx, reqs, results, allIndexes(len(reqs)))
+
+	for attempt := 1; attempt < batchRetryMaxAttempts; attempt++ {
+		failed := failedIndexes(results)
+		if len(failed) == 0 {
+			break
+		}
+
+		delay := batchRetryBaseDelay * time.Duration(1<<uint(attempt-1))
+		log.Printf("🔁 [BATCH] retrying %d failed order(s), attempt=%d, delay=%s", len(failed), attempt+1, delay)
+		time.Sleep(delay)
+
+		uc.placeOrdersConcurrently(ctx, reqs, results, failed)
+	}
+
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		for _, r := range results {
+			if r.GID == "" {
+				continue
+			}
+			span.AddEvent("xa.batch.item_completed", trace.WithAttributes(
+				attribute.String("xa.order_id", r.OrderID),
+				attribute.String("xa.gid", r.GID),
+				attribute.String("xa.status", r.Status),
+			))
+		}
+	}
+
+	batchM.RecordBatch(ctx, len(reqs), len(failedIndexes(results)), time.Since(start))
+
+	return results, nil
+}
+
+// placeOrdersConcurrently processa os índices informados com um worker pool limitado, gravando
+// cada resultado na posição correspondente do slice `results`
+func (uc *OrderUseCase) placeOrdersConcurrently(ctx context.Context, reqs []CreateOrderRequest, results []OrderResult, indexes []int) {
+	sem := make(chan struct{}, batchWorkerPoolSize)
+	var wg sync.WaitGroup
+
+	for _, i := range indexes {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			orderID, gid, traceID, err := uc.CreateOrder(ctx, reqs[i])
+			if err != nil {
+				results[i] = OrderResult{Index: i, OrderID: orderID, GID: gid, TraceID: traceID, Status: "failed", Error: err.Error()}
+				return
+			}
+
+			results[i] = OrderResult{Index: i, OrderID: orderID, GID: gid, TraceID: traceID, Status: "completed"}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func allIndexes(n int) []int {
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	return indexes
+}
+
+func failedIndexes(results []OrderResult) []int {
+	var indexes []int
+	for i, r := range results {
+		if r.Status == "failed" {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// batchMetrics agrupa os instrumentos emitidos por BatchPlaceOrders, separados de dtxMetrics por
+// descreverem o lote como um todo (tamanho, falhas residuais, latência ponta a ponta), não uma
+// transação isolada
+type batchMetrics struct {
+	size     metric.Int64Histogram
+	failures metric.Int64Counter
+	latency  metric.Float64Histogram
+}
+
+// newBatchMetrics registra orders.batch.size/orders.batch.failures/orders.batch.latency no meter
+// informado
+func newBatchMetrics(meter metric.Meter) (*batchMetrics, error) {
+	size, err := meter.Int64Histogram(
+		"orders.batch.size",
+		metric.WithDescription("Number of items submitted per batch order creation request"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create orders.batch.size histogram: %w", err)
+	}
+
+	failures, err := meter.Int64Counter(
+		"orders.batch.failures",
+		metric.WithDescription("Number of items that remained failed after all batch retry attempts"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create orders.batch.failures counter: %w", err)
+	}
+
+	latency, err := meter.Float64Histogram(
+		"orders.batch.latency",
+		metric.WithDescription("End-to-end duration of a batch order creation request, including retries"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create orders.batch.latency histogram: %w", err)
+	}
+
+	return &batchMetrics{size: size, failures: failures, latency: latency}, nil
+}
+
+// RecordBatch registra o tamanho, as falhas residuais e a duração de um BatchPlaceOrders
+func (m *batchMetrics) RecordBatch(ctx context.Context, size, failures int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.size.Record(ctx, int64(size))
+	m.failures.Add(ctx, int64(failures))
+	m.latency.Record(ctx, duration.Seconds())
+}
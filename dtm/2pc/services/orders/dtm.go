@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/dtm-labs/client/dtmcli"
 	"github.com/go-resty/resty/v2"
@@ -11,12 +12,13 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
 // XAOrchestrator abstrai as operações XA do DTM (2PC)
 type XAOrchestrator interface {
-	CreateOrderXA(ctx context.Context, req CreateOrderRequest) (string, string, error)
+	CreateOrderXA(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error)
 }
 
 // DTMXAOrchestrator implementa XAOrchestrator usando DTM (2PC/XA)
@@ -28,32 +30,33 @@ func NewDTMXAOrchestrator() *DTMXAOrchestrator {
 }
 
 // CreateOrderXA registra as branches XA usando 2PC
-func (xo *DTMXAOrchestrator) CreateOrderXA(ctx context.Context, req CreateOrderRequest) (string, string, error) {
+func (xo *DTMXAOrchestrator) CreateOrderXA(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error) {
 	tracer := otel.Tracer("dtm-xa-orchestrator")
 
 	// Criar span para o REGISTRO das branches XA (2PC)
 	ctx, registrationSpan := tracer.Start(ctx, "XA-Registration")
 	defer registrationSpan.End()
 
+	// XaGlobalTransaction2 bloqueia até o 2PC (PREPARE+COMMIT) terminar em todos os
+	// participantes, então - diferente do SAGA/TCC - commit/rollback aqui já é o desfecho final
+	start := time.Now()
+	dtxM.AddInflight(ctx, "xa", 1)
+	defer dtxM.AddInflight(ctx, "xa", -1)
+
 	// Gerar OrderID ANTES de registrar as branches
-	orderID := uuid.New().String()
+	orderID = uuid.New().String()
 	defer func() {
 		if r := recover(); r != nil {
 			registrationSpan.RecordError(fmt.Errorf("panic in MustGenGid due to unavailable dtm: %v", r))
 			registrationSpan.SetStatus(codes.Error, "panic in MustGenGid due to unavailable dtm")
 		}
 	}()
-	gid := dtmcli.MustGenGid(getEnv("DTM_SERVER", "http://dtm:36789/api/dtmsvr"))
+	gid = dtmcli.MustGenGid(getEnv("DTM_SERVER", "http://dtm:36789/api/dtmsvr"))
 	if gid == "" {
-		return orderID, "", fmt.Errorf("internal error: failed to generate GID")
+		return orderID, "", traceID, fmt.Errorf("internal error: failed to generate GID")
 	}
 
-	// Extract trace context from the incoming context
-	var traceID, spanID string
-	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
-		traceID = span.SpanContext().TraceID().String()
-		spanID = span.SpanContext().SpanID().String()
-	}
+	traceID = trace.SpanFromContext(ctx).SpanContext().TraceID().String()
 
 	// Adicionar atributos ao span de registro
 	registrationSpan.SetAttributes(
@@ -67,16 +70,19 @@ func (xo *DTMXAOrchestrator) CreateOrderXA(ctx context.Context, req CreateOrderR
 
 	log.Printf("🚀 Registering XA branches (2PC) | TraceID: %s | GID: %s | OrderID: %s", traceID, gid, orderID)
 
-	// Preparar payload com trace context
 	payload := XAActionRequest{
 		OrderID:    orderID,
 		UserID:     req.UserID,
 		ProductID:  req.ProductID,
 		TotalPrice: req.Amount,
-		TraceID:    traceID,
-		SpanID:     spanID,
 	}
 
+	// Propaga o trace context (traceparent/tracestate/baggage) via o TextMapPropagator global em
+	// vez de montar o header traceparent manualmente - xa.BranchHeaders é aplicado igualmente às
+	// três branches abaixo
+	branchHeaders := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, branchHeaders)
+
 	// URLs dos serviços
 	ordersServiceURL := getEnv("ORDERS_SERVICE_URL", "http://orders-service:8080")
 	inventoryServiceURL := getEnv("INVENTORY_SERVICE_URL", "http://inventory-service:8081")
@@ -84,14 +90,13 @@ func (xo *DTMXAOrchestrator) CreateOrderXA(ctx context.Context, req CreateOrderR
 
 	// Criar transação XA usando dtmcli.XaGlobalTransaction2 (2PC via HTTP)
 	// XaGlobalTransaction2 permite configurar headers para propagação de trace
-	err := dtmcli.XaGlobalTransaction2(getEnv("DTM_SERVER", "http://dtm:36789/api/dtmsvr"), gid, func(xa *dtmcli.Xa) {
-		// Configurar headers de trace para propagação entre serviços
-		xa.BranchHeaders = map[string]string{
-			"traceparent": fmt.Sprintf("00-%s-%s-01", traceID, spanID),
-		}
+	err = dtmcli.XaGlobalTransaction2(getEnv("DTM_SERVER", "http://dtm:36789/api/dtmsvr"), gid, func(xa *dtmcli.Xa) {
+		xa.BranchHeaders = branchHeaders
 	}, func(xa *dtmcli.Xa) (*resty.Response, error) {
 		// Branch 1: Orders - cria a ordem
-		resp, err := xa.CallBranch(&payload, ordersServiceURL+"/api/orders/xa")
+		resp, err := MonitoredBranchCall(ctx, tracer, "orders", ordersServiceURL+"/api/orders/xa", func() (*resty.Response, error) {
+			return xa.CallBranch(&payload, ordersServiceURL+"/api/orders/xa")
+		})
 		if err != nil {
 			registrationSpan.AddEvent("Orders XA branch failed")
 			return resp, fmt.Errorf("orders XA branch failed: %w", err)
@@ -99,7 +104,9 @@ func (xo *DTMXAOrchestrator) CreateOrderXA(ctx context.Context, req CreateOrderR
 		registrationSpan.AddEvent("Orders XA branch registered")
 
 		// Branch 2: Inventory - decrementa estoque
-		resp, err = xa.CallBranch(&payload, inventoryServiceURL+"/api/inventory/xa")
+		resp, err = MonitoredBranchCall(ctx, tracer, "inventory", inventoryServiceURL+"/api/inventory/xa", func() (*resty.Response, error) {
+			return xa.CallBranch(&payload, inventoryServiceURL+"/api/inventory/xa")
+		})
 		if err != nil {
 			registrationSpan.AddEvent("Inventory XA branch failed")
 			return resp, fmt.Errorf("inventory XA branch failed: %w", err)
@@ -107,7 +114,9 @@ func (xo *DTMXAOrchestrator) CreateOrderXA(ctx context.Context, req CreateOrderR
 		registrationSpan.AddEvent("Inventory XA branch registered")
 
 		// Branch 3: Payment - debita saldo
-		resp, err = xa.CallBranch(&payload, paymentServiceURL+"/api/payment/xa")
+		resp, err = MonitoredBranchCall(ctx, tracer, "payment", paymentServiceURL+"/api/payment/xa", func() (*resty.Response, error) {
+			return xa.CallBranch(&payload, paymentServiceURL+"/api/payment/xa")
+		})
 		if err != nil {
 			registrationSpan.AddEvent("Payment XA branch failed")
 			return resp, fmt.Errorf("payment XA branch failed: %w", err)
@@ -118,13 +127,15 @@ func (xo *DTMXAOrchestrator) CreateOrderXA(ctx context.Context, req CreateOrderR
 	})
 
 	if err != nil {
+		dtxM.RecordTransaction(ctx, "xa", "rollback", time.Since(start))
 		registrationSpan.RecordError(err)
 		registrationSpan.SetStatus(codes.Error, "XA transaction failed")
 		log.Printf("❌ XA TRANSACTION FAILED | TraceID: %s | GID: %s | Error: %v", traceID, gid, err)
-		return orderID, traceID, fmt.Errorf("XA transaction failed: %w", err)
+		return orderID, gid, traceID, fmt.Errorf("XA transaction failed: %w", err)
 	}
 
+	dtxM.RecordTransaction(ctx, "xa", "commit", time.Since(start))
 	registrationSpan.SetStatus(codes.Ok, "XA transaction completed successfully")
 	log.Printf("✅ XA COMPLETED | TraceID: %s | GID: %s | OrderID: %s", traceID, gid, orderID)
-	return orderID, traceID, nil
+	return orderID, gid, traceID, nil
 }
@@ -1,17 +1,16 @@
 package main
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/dtm-labs/client/dtmcli"
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // HandleCreateOrder handler para criação de pedidos - executa XA (2PC) síncrono
@@ -27,7 +26,7 @@ func HandleCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
 			return
 		}
 
-		orderID, traceID, err := uc.CreateOrder(ctx, req)
+		orderID, gid, traceID, err := uc.CreateOrder(ctx, req)
 		if err != nil {
 			log.Printf("❌ Failed to create order with XA: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order", "details": err.Error()})
@@ -37,6 +36,7 @@ func HandleCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
 		// Retorna 200 OK - processamento síncrono via 2PC/XA
 		c.JSON(http.StatusOK, gin.H{
 			"order_id": orderID,
+			"gid":      gid,
 			"trace_id": traceID,
 			"status":   "completed",
 			"message":  "Order created successfully via 2PC/XA",
@@ -44,25 +44,51 @@ func HandleCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
 	}
 }
 
+// HandleBatchCreateOrder handler para criação de pedidos em lote - executa um worker pool de
+// transações XA (2PC) síncronas, uma por item, com retry individual e backoff exponencial
+func HandleBatchCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := tracer.Start(c.Request.Context(), "orders.BatchCreateOrder")
+		defer span.End()
+
+		var reqs []CreateOrderRequest
+		if err := c.ShouldBindJSON(&reqs); err != nil {
+			log.Printf("❌ Invalid batch request body: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+			return
+		}
+
+		span.SetAttributes(attribute.Int("xa.batch.size", len(reqs)))
+
+		results, err := uc.BatchPlaceOrders(ctx, reqs)
+		if err != nil {
+			log.Printf("❌ Batch order creation failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process batch", "details": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"results": results})
+	}
+}
+
 // HandleXACreateOrder handler para operação XA usando dtmcli.XaLocalTransaction
 func HandleXACreateOrder(uc *OrderUseCase, dbConf *dtmcli.DBConf) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Printf("🔄 XA Handler: Received XA request | Path=%s | Query=%s", c.Request.URL.Path, c.Request.URL.RawQuery)
 
-		// Extrair trace context do header se presente
-		traceparent := c.GetHeader("traceparent")
+		// otelgin.Middleware já extraiu traceparent/tracestate/baggage dos headers da requisição
+		// (incluindo as chamadas de branch do DTM, que reenviam o header injetado via
+		// xa.BranchHeaders) e populou c.Request.Context() com o span context remoto
 		ctx := c.Request.Context()
-		if traceparent != "" {
-			// Parse traceparent formato: 00-{trace-id}-{parent-span-id}-{flags}
-			ctx = ExtractTraceContext(ctx, traceparent)
-		}
 
 		// XaLocalTransaction gerencia PREPARE e COMMIT/ROLLBACK automaticamente
 		err := dtmcli.XaLocalTransaction(c.Request.URL.Query(), *dbConf, func(db *sql.DB, xa *dtmcli.Xa) error {
 			// PREPARE phase: body tem payload
 			// COMMIT/ROLLBACK phase: body é nil
 			if c.Request.Body == nil {
+				commitStart := time.Now()
 				log.Printf("⚠️ XA: COMMIT/ROLLBACK phase - DTM handling automatically")
+				xaPhaseM.RecordCommit(ctx, "ok", time.Since(commitStart))
 				return nil
 			}
 
@@ -77,11 +103,20 @@ func HandleXACreateOrder(uc *OrderUseCase, dbConf *dtmcli.DBConf) gin.HandlerFun
 			spanCtx, span := tracer.Start(ctx, "orders.xa.createOrder")
 			defer span.End()
 
+			prepareStart := time.Now()
 			log.Printf("🔄 XA PREPARE: Creating order | OrderID=%s", req.OrderID)
-			err := uc.CreateOrderXA(db, req)
+			err := retryTransientXAPrepare(span, func() error {
+				if err := maybeChaosDeadlock(chaosCfg); err != nil {
+					return err
+				}
+				return uc.CreateOrderXA(db, req)
+			})
+			prepareOutcome := "ok"
 			if err != nil {
+				prepareOutcome = "error"
 				span.RecordError(err)
 			}
+			xaPhaseM.RecordPrepare(ctx, prepareOutcome, time.Since(prepareStart))
 			_ = spanCtx // Use context if needed
 			return err
 		})
@@ -104,32 +139,50 @@ func HandleHealth() gin.HandlerFunc {
 	}
 }
 
-// ExtractTraceContext extrai trace context do header traceparent formato W3C
-// Format: 00-{trace-id}-{parent-span-id}-{flags}
-func ExtractTraceContext(ctx context.Context, traceparent string) context.Context {
-	parts := strings.Split(traceparent, "-")
-	if len(parts) != 4 {
-		return ctx
-	}
-
-	traceIDStr := parts[1]
-	spanIDStr := parts[2]
+// SagaEventView representa um evento do log de auditoria saga_events para a resposta HTTP
+type SagaEventView struct {
+	ID          int64  `json:"id"`
+	OrderID     string `json:"order_id"`
+	Service     string `json:"service"`
+	Phase       string `json:"phase"`
+	Status      string `json:"status"`
+	PayloadHash string `json:"payload_hash"`
+	PrevEventID *int64 `json:"prev_event_id,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
 
-	traceID, err := trace.TraceIDFromHex(traceIDStr)
-	if err != nil {
-		return ctx
-	}
+// HandleGetSagaChain handler que retorna a cadeia de eventos do log de auditoria (TCC/XA)
+func HandleGetSagaChain(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orderID := c.Param("order_id")
+
+		rows, err := db.QueryContext(c.Request.Context(), `
+			SELECT id, order_id, service, phase, status, payload_hash, prev_event_id, created_at
+			FROM saga_events
+			WHERE order_id = $1
+			ORDER BY id ASC
+		`, orderID)
+		if err != nil {
+			log.Printf("❌ Failed to load saga chain | OrderID=%s | Error=%v", orderID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load saga chain", "details": err.Error()})
+			return
+		}
+		defer rows.Close()
+
+		var events []SagaEventView
+		for rows.Next() {
+			var event SagaEventView
+			var prevEventID sql.NullInt64
+			if err := rows.Scan(&event.ID, &event.OrderID, &event.Service, &event.Phase, &event.Status, &event.PayloadHash, &prevEventID, &event.CreatedAt); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to scan saga event", "details": err.Error()})
+				return
+			}
+			if prevEventID.Valid {
+				event.PrevEventID = &prevEventID.Int64
+			}
+			events = append(events, event)
+		}
 
-	spanID, err := trace.SpanIDFromHex(spanIDStr)
-	if err != nil {
-		return ctx
+		c.JSON(http.StatusOK, gin.H{"order_id": orderID, "events": events})
 	}
-
-	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
-		TraceID:    traceID,
-		SpanID:     spanID,
-		TraceFlags: trace.FlagsSampled,
-	})
-
-	return trace.ContextWithSpanContext(ctx, spanContext)
 }
@@ -14,8 +14,10 @@ import (
 	_ "github.com/lib/pq"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
@@ -23,7 +25,10 @@ import (
 )
 
 var (
-	tracer trace.Tracer
+	tracer   trace.Tracer
+	dtxM     *dtxMetrics
+	batchM   *batchMetrics
+	xaPhaseM *xaPhaseMetrics
 )
 
 func main() {
@@ -40,12 +45,51 @@ func main() {
 
 	tracer = tp.Tracer("orders-service-2pc")
 
+	mp, err := initMetrics()
+	if err != nil {
+		log.Fatalf("Failed to initialize metrics: %v", err)
+	}
+	defer func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down meter: %v", err)
+		}
+	}()
+
+	// RED metrics for the XA/2PC phases (dtx_transaction_duration_seconds, dtx_branch_duration_seconds,
+	// dtx_branch_retries_total, dtx_inflight_transactions)
+	meter := mp.Meter("orders-service-2pc")
+	dtxM, err = newDTXMetrics(meter)
+	if err != nil {
+		log.Fatalf("Failed to initialize DTM metrics: %v", err)
+	}
+
+	// Batch metrics (orders.batch.size, orders.batch.failures, orders.batch.latency) - separadas
+	// de dtxM por descreverem o lote /api/orders/batch como um todo, não uma transação isolada
+	batchM, err = newBatchMetrics(meter)
+	if err != nil {
+		log.Fatalf("Failed to initialize batch metrics: %v", err)
+	}
+
+	// Per-phase business metrics (xa.prepare.duration, xa.commit.duration) observed from this
+	// participant's own PREPARE/COMMIT handling (see HandleXACreateOrder)
+	xaPhaseM, err = newXAPhaseMetrics(meter, getEnv("SERVICE_NAME", "orders-service-2pc"), "orders")
+	if err != nil {
+		log.Fatalf("Failed to initialize XA phase metrics: %v", err)
+	}
+
 	// Initialize database for XA
 	dbConf, err := initDBForXA()
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Separate read-only connection for non-XA queries (e.g. the saga audit trail endpoint)
+	readDB, err := initReadDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize read database: %v", err)
+	}
+	defer readDB.Close()
+
 	// Setup repositories and use cases
 	// Note: repository doesn't hold the connection, it receives *sql.DB from DTM
 	orderRepository := NewPostgresOrderRepository()
@@ -59,11 +103,23 @@ func main() {
 	// Health check
 	r.GET("/health", HandleHealth())
 
+	// Saga audit trail endpoint
+	r.GET("/api/sagas/:order_id", HandleGetSagaChain(readDB))
+
 	// XA orchestrator endpoint - initiates XA transaction (retorna 200 OK após completar)
 	r.POST("/api/orders", HandleCreateOrder(orderUseCase))
 
-	// XA participant endpoint - chamado pelo DTM
-	r.POST("/api/orders/xa", HandleXACreateOrder(orderUseCase, dbConf))
+	// Batch endpoint - worker pool of synchronous 2PC transactions with per-item retry
+	r.POST("/api/orders/batch", HandleBatchCreateOrder(orderUseCase))
+
+	// XA participant endpoint - chamado pelo DTM. chaosMiddleware injeta falhas configuráveis
+	// (latência/500s/conn drop) antes do PREPARE; o deadlock simulado é injetado mais fundo, no
+	// retry de retryTransientXAPrepare (ver chaos.go/handlers.go)
+	r.POST("/api/orders/xa", chaosMiddleware(chaosCfg, "xa"), HandleXACreateOrder(orderUseCase, dbConf))
+
+	// Admin endpoint - ajusta a injeção de falhas em tempo real (ver chaos.go)
+	r.GET("/admin/chaos", chaosAdminHandler(chaosCfg))
+	r.POST("/admin/chaos", chaosAdminHandler(chaosCfg))
 
 	port := getEnv("PORT", "8080")
 	log.Printf("🚀 Orders Service (2PC/XA) listening on port %s", port)
@@ -128,6 +184,24 @@ func initDBForXA() (*dtmcli.DBConf, error) {
 	return dbConf, nil
 }
 
+func initReadDB() (*sql.DB, error) {
+	dsn := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		getEnv("DATABASE_HOST", "postgres"),
+		getEnv("DATABASE_PORT", "5432"),
+		getEnv("DATABASE_USER", "root"),
+		getEnv("DATABASE_PASSWORD", "pass"),
+		getEnv("DATABASE_NAME", "orders_db"),
+	)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open read database connection: %w", err)
+	}
+
+	return db, nil
+}
+
 func initTracer() (*sdktrace.TracerProvider, error) {
 	ctx := context.Background()
 
@@ -158,11 +232,49 @@ func initTracer() (*sdktrace.TracerProvider, error) {
 	)
 
 	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
+	// Composto com Baggage (além de TraceContext) para que atributos de negócio propagados pelo
+	// orquestrador sobrevivam ao salto entre branches XA
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
 
 	return tp, nil
 }
 
+func initMetrics() (*sdkmetric.MeterProvider, error) {
+	ctx := context.Background()
+
+	otlpEndpoint := getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "otel-collector:4318")
+
+	exporter, err := otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpoint(otlpEndpoint),
+		otlpmetrichttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(getEnv("SERVICE_NAME", "orders-service-2pc")),
+			semconv.ServiceVersion("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(res),
+	)
+
+	otel.SetMeterProvider(mp)
+
+	return mp, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -16,14 +16,14 @@ type Order struct {
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
-// XAActionRequest representa o payload das requisições XA (sempre 1 unidade)
+// XAActionRequest representa o payload das requisições XA (sempre 1 unidade). O trace context
+// não viaja mais neste payload - é propagado via headers (traceparent/tracestate/baggage) pelo
+// TextMapPropagator global, anexados às chamadas de branch via xa.BranchHeaders
 type XAActionRequest struct {
 	OrderID    string `json:"order_id"`
 	UserID     string `json:"user_id"`
 	ProductID  string `json:"product_id"`
 	TotalPrice int    `json:"total_price"`
-	TraceID    string `json:"trace_id"`
-	SpanID     string `json:"span_id"`
 }
 
 // CreateOrderRequest representa a requisição de criação de pedido (sempre 1 unidade)
@@ -38,3 +38,14 @@ type OrderRepository interface {
 	// XA: Cria ordem dentro de transação XA (recebe *sql.DB do DTM)
 	CreateOrderXA(db *sql.DB, order *Order) error
 }
+
+// OrderResult representa o resultado individual de um item processado via /api/orders/batch, no
+// mesmo formato usado pelos endpoints equivalentes dos serviços SAGA e TCC
+type OrderResult struct {
+	Index   int    `json:"index"`
+	OrderID string `json:"order_id,omitempty"`
+	GID     string `json:"gid,omitempty"`
+	TraceID string `json:"trace_id,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/sony/gobreaker"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultXABranchTimeout = 5 * time.Second
+	maxBranchRetries       = 3
+	branchBackoffBase      = 100 * time.Millisecond
+)
+
+// branchBreakers isola um circuit breaker por participante (orders/inventory/payment):
+// dtmcli.Xa.CallBranch usa seu próprio resty.Client interno, então não há como injetar um
+// cliente HTTP próprio aqui - o breaker precisa envolver a chamada por fora. Os nomes de métrica
+// (httpx_requests_total/httpx_circuit_state) seguem a convenção usada por qualquer outro cliente
+// HTTP instrumentado neste repositório, para que todos apareçam no mesmo dashboard.
+var branchBreakers = newBranchBreakerRegistry()
+
+// xaBranchTimeout lê XA_BRANCH_TIMEOUT (ex: "5s"), usando defaultXABranchTimeout quando ausente
+// ou inválida
+func xaBranchTimeout() time.Duration {
+	raw := getEnv("XA_BRANCH_TIMEOUT", "")
+	if raw == "" {
+		return defaultXABranchTimeout
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return defaultXABranchTimeout
+	}
+	return d
+}
+
+// MonitoredBranchCall executa uma chamada de branch XA (xa.CallBranch) sob um span dedicado
+// "xa.branch.<service>", com timeout por tentativa e retry com backoff exponencial + jitter para
+// erros transitórios (conexão recusada, 5xx, deadline estourado antes do deadline global) - o
+// mesmo padrão de "monitored transaction" usado para chamadas a nós em stacks blockchain/EVM,
+// necessário para que os números do benchmark continuem significativos sob perda de pacote.
+//
+// Como dtmcli.Xa.CallBranch não aceita um context.Context, o timeout por tentativa é aplicado
+// rodando a chamada numa goroutine e abandonando-a no timeout; a goroutine pode seguir em
+// background até a chamada HTTP subjacente retornar por conta própria.
+func MonitoredBranchCall(ctx context.Context, tracer trace.Tracer, service, url string, call func() (*resty.Response, error)) (*resty.Response, error) {
+	timeout := xaBranchTimeout()
+
+	ctx, span := tracer.Start(ctx, fmt.Sprintf("xa.branch.%s", service))
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("xa.branch.url", url),
+		attribute.Int64("xa.branch.timeout_ms", timeout.Milliseconds()),
+	)
+
+	breaker := branchBreakers.forService(service)
+
+	callStart := time.Now()
+	var resp *resty.Response
+	var err error
+	retries := 0
+
+attempts:
+	for attempt := 1; attempt <= maxBranchRetries; attempt++ {
+		span.SetAttributes(attribute.Int("xa.branch.attempt", attempt))
+
+		result, breakerErr := breaker.Execute(func() (any, error) {
+			return callBranchWithTimeout(ctx, timeout, call)
+		})
+		if result != nil {
+			resp = result.(*resty.Response)
+		}
+		err = breakerErr
+		branchBreakers.recordRequest(ctx, service, resp, err)
+		if err == nil {
+			break
+		}
+
+		if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+			// Circuito aberto: falha rápido sem consumir as tentativas restantes
+			break
+		}
+
+		if attempt == maxBranchRetries || !isRetryableBranchError(err, resp) {
+			break
+		}
+
+		retries++
+		backoff := jitteredBranchBackoff(attempt)
+		span.AddEvent("retrying branch call", trace.WithAttributes(
+			attribute.Int("xa.branch.attempt", attempt+1),
+			attribute.Int64("xa.branch.backoff_ms", backoff.Milliseconds()),
+		))
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break attempts
+		case <-time.After(backoff):
+		}
+	}
+
+	span.SetAttributes(attribute.Int("xa.branch.retries", retries))
+	dtxM.RecordBranch(ctx, service, "prepare", time.Since(callStart))
+	dtxM.AddBranchRetries(ctx, service, int64(retries))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "XA branch call failed")
+		return resp, fmt.Errorf("XA branch call to %s failed after %d attempt(s): %w", url, retries+1, err)
+	}
+
+	span.SetStatus(codes.Ok, "XA branch call succeeded")
+	return resp, nil
+}
+
+func callBranchWithTimeout(ctx context.Context, timeout time.Duration, call func() (*resty.Response, error)) (*resty.Response, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		resp *resty.Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := call()
+		done <- result{resp, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.resp, r.err
+	case <-attemptCtx.Done():
+		return nil, fmt.Errorf("branch call timed out after %s: %w", timeout, attemptCtx.Err())
+	}
+}
+
+func isRetryableBranchError(err error, resp *resty.Response) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if resp != nil && resp.StatusCode() >= http.StatusInternalServerError {
+		return true
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "connection refused") ||
+		strings.Contains(msg, "timeout") ||
+		strings.Contains(msg, "EOF")
+}
+
+func jitteredBranchBackoff(attempt int) time.Duration {
+	backoff := branchBackoffBase * time.Duration(1<<uint(attempt-1))
+	jitter := time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+	return backoff + jitter
+}
+
+// branchBreakerRegistry mantém um gobreaker.CircuitBreaker por serviço participante (ver
+// comentário acima de branchBreakers)
+type branchBreakerRegistry struct {
+	mu       sync.Mutex
+	breakers map[string]*gobreaker.CircuitBreaker
+
+	requestsTotal metric.Int64Counter
+	circuitState  metric.Int64ObservableGauge
+
+	statesMu sync.Mutex
+	states   map[string]gobreaker.State
+}
+
+func newBranchBreakerRegistry() *branchBreakerRegistry {
+	r := &branchBreakerRegistry{
+		breakers: make(map[string]*gobreaker.CircuitBreaker),
+		states:   make(map[string]gobreaker.State),
+	}
+
+	meter := otel.Meter("orders-service-2pc")
+
+	requestsTotal, err := meter.Int64Counter(
+		"httpx_requests_total",
+		metric.WithDescription("Total XA branch HTTP requests, by destination service and status code"),
+	)
+	if err != nil {
+		log.Printf("⚠️ [BRANCH BREAKER] failed to create httpx_requests_total counter: %v", err)
+	}
+	r.requestsTotal = requestsTotal
+
+	circuitState, err := meter.Int64ObservableGauge(
+		"httpx_circuit_state",
+		metric.WithDescription("Circuit breaker state per XA participant (0=closed, 1=half-open, 2=open)"),
+		metric.WithInt64Callback(func(_ context.Context, obs metric.Int64Observer) error {
+			r.statesMu.Lock()
+			defer r.statesMu.Unlock()
+			for service, state := range r.states {
+				obs.Observe(int64(state), metric.WithAttributes(attribute.String("service", service)))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		log.Printf("⚠️ [BRANCH BREAKER] failed to create httpx_circuit_state gauge: %v", err)
+	}
+	r.circuitState = circuitState
+
+	return r
+}
+
+func (r *branchBreakerRegistry) forService(service string) *gobreaker.CircuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if b, ok := r.breakers[service]; ok {
+		return b
+	}
+
+	b := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        fmt.Sprintf("xa-branch-%s", service),
+		MaxRequests: 1,
+		Interval:    30 * time.Second,
+		Timeout:     15 * time.Second,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			if counts.Requests < 3 {
+				return false
+			}
+			return float64(counts.TotalFailures)/float64(counts.Requests) >= 0.5
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			r.statesMu.Lock()
+			defer r.statesMu.Unlock()
+			r.states[service] = to
+		},
+	})
+	r.breakers[service] = b
+	return b
+}
+
+func (r *branchBreakerRegistry) recordRequest(ctx context.Context, service string, resp *resty.Response, err error) {
+	if r.requestsTotal == nil {
+		return
+	}
+	code := "error"
+	if resp != nil {
+		code = fmt.Sprintf("%d", resp.StatusCode())
+	}
+	r.requestsTotal.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("service", service),
+		attribute.String("code", code),
+	))
+}
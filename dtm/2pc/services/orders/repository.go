@@ -1,7 +1,9 @@
 package main
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"log"
 )
@@ -34,5 +36,38 @@ func (r *PostgresOrderRepository) CreateOrderXA(db *sql.DB, order *Order) error
 	}
 
 	log.Printf("✅ [XA] Created order %s with status '%s'", order.OrderID, order.Status)
+
+	if err := appendSagaEventXA(db, "orders", order.OrderID, "xa", "completed", order.TotalPrice); err != nil {
+		log.Printf("⚠️ [SAGA LOG] failed to append XA event | OrderID=%s | Error=%v", order.OrderID, err)
+	}
+
+	return nil
+}
+
+// appendSagaEventXA grava um evento no log de auditoria saga_events dentro da mesma
+// transação XA gerenciada pelo DTM, encadeando-o ao último evento conhecido do OrderID
+func appendSagaEventXA(db *sql.DB, service, orderID, phase, status string, totalPrice int) error {
+	payloadHash := hashXAPayload(orderID, service, totalPrice)
+
+	var prevEventID sql.NullInt64
+	err := db.QueryRow(`SELECT id FROM saga_events WHERE order_id = $1 ORDER BY id DESC LIMIT 1`, orderID).Scan(&prevEventID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to look up previous saga event: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO saga_events (order_id, service, phase, status, payload_hash, prev_event_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`, orderID, service, phase, status, payloadHash, prevEventID)
+	if err != nil {
+		return fmt.Errorf("failed to append saga event: %w", err)
+	}
+
 	return nil
 }
+
+// hashXAPayload calcula um hash estável dos campos relevantes do payload XA
+func hashXAPayload(orderID, service string, totalPrice int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d", orderID, service, totalPrice)))
+	return hex.EncodeToString(sum[:])
+}
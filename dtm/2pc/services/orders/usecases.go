@@ -8,7 +8,6 @@ import (
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/trace"
 )
 
 // OrderUseCase encapsula a lógica de negócio de pedidos (2PC/XA)
@@ -26,7 +25,7 @@ func NewOrderUseCase(repository OrderRepository, xaOrchestrator XAOrchestrator)
 }
 
 // CreateOrder registra as branches XA e retorna após completar (síncrono)
-func (uc *OrderUseCase) CreateOrder(ctx context.Context, req CreateOrderRequest) (string, string, error) {
+func (uc *OrderUseCase) CreateOrder(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error) {
 	tracer := otel.Tracer("order-service")
 
 	// Criar span para toda a operação XA (2PC)
@@ -45,15 +44,15 @@ func (uc *OrderUseCase) CreateOrder(ctx context.Context, req CreateOrderRequest)
 	// Validações básicas
 	if req.Amount <= 0 {
 		orderSpan.RecordError(ErrInvalidPrice)
-		return "", "", ErrInvalidPrice
+		return "", "", "", ErrInvalidPrice
 	}
 
 	// Executa transação XA (2PC - síncrono)
-	orderID, traceID, err := uc.xaOrchestrator.CreateOrderXA(ctx, req)
+	orderID, gid, traceID, err = uc.xaOrchestrator.CreateOrderXA(ctx, req)
 	if err != nil {
 		log.Printf("❌ XA transaction failed: %v", err)
 		orderSpan.RecordError(err)
-		return orderID, traceID, err
+		return orderID, gid, traceID, err
 	}
 
 	orderSpan.SetAttributes(
@@ -61,8 +60,8 @@ func (uc *OrderUseCase) CreateOrder(ctx context.Context, req CreateOrderRequest)
 		attribute.String("trace.id", traceID),
 	)
 
-	log.Printf("✅ XA transaction completed | OrderID=%s | TraceID=%s", orderID, traceID)
-	return orderID, traceID, nil
+	log.Printf("✅ XA transaction completed | OrderID=%s | GID=%s | TraceID=%s", orderID, gid, traceID)
+	return orderID, gid, traceID, nil
 }
 
 // CreateOrderXA implementa a operação XA - cria ordem com status "completed"
@@ -89,32 +88,6 @@ func (uc *OrderUseCase) CreateOrderXA(db *sql.DB, req XAActionRequest) error {
 	return nil
 }
 
-// extractTraceContext extrai o trace context do payload
-func extractTraceContext(ctx context.Context, traceIDHex, spanIDHex string) context.Context {
-	if traceIDHex == "" || spanIDHex == "" {
-		return ctx
-	}
-
-	traceID, err := trace.TraceIDFromHex(traceIDHex)
-	if err != nil {
-		return ctx
-	}
-
-	spanID, err := trace.SpanIDFromHex(spanIDHex)
-	if err != nil {
-		return ctx
-	}
-
-	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
-		TraceID:    traceID,
-		SpanID:     spanID,
-		TraceFlags: trace.FlagsSampled,
-		Remote:     true,
-	})
-
-	return trace.ContextWithSpanContext(ctx, spanContext)
-}
-
 // Erros customizados
 var (
 	ErrInvalidPrice = &OrderError{Message: "amount must be greater than 0"}
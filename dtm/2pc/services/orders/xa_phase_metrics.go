@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// xaPhaseMetrics instrumenta separadamente a duração de PREPARE e de COMMIT/ROLLBACK do 2PC,
+// algo que dtx_branch_duration_seconds não distingue (ele mede só o registro da branch do ponto
+// de vista do orchestrator - ver monitored_branch.go). Como dtmcli.XaLocalTransaction chama o
+// mesmo handler HTTP duas vezes (uma com payload, para PREPARE, outra sem, para COMMIT/
+// ROLLBACK), medir as duas fases separadamente aqui é o que permite ver no dashboard se o tempo
+// do 2PC está concentrado na validação local (PREPARE) ou na resolução distribuída (COMMIT).
+type xaPhaseMetrics struct {
+	service string
+	branch  string
+
+	prepareDuration metric.Float64Histogram
+	commitDuration  metric.Float64Histogram
+}
+
+// newXAPhaseMetrics registra xa.prepare.duration e xa.commit.duration no meter informado
+func newXAPhaseMetrics(meter metric.Meter, service, branch string) (*xaPhaseMetrics, error) {
+	prepareDuration, err := meter.Float64Histogram(
+		"xa.prepare.duration",
+		metric.WithDescription("Duration of the XA PREPARE phase as observed by the participant"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create xa.prepare.duration histogram: %w", err)
+	}
+
+	commitDuration, err := meter.Float64Histogram(
+		"xa.commit.duration",
+		metric.WithDescription("Duration of the XA COMMIT/ROLLBACK phase as observed by the participant"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create xa.commit.duration histogram: %w", err)
+	}
+
+	return &xaPhaseMetrics{
+		service:         service,
+		branch:          branch,
+		prepareDuration: prepareDuration,
+		commitDuration:  commitDuration,
+	}, nil
+}
+
+func (m *xaPhaseMetrics) attrs(outcome string) metric.MeasurementOption {
+	return metric.WithAttributes(
+		attribute.String("service", m.service),
+		attribute.String("branch", m.branch),
+		attribute.String("outcome", outcome),
+	)
+}
+
+// RecordPrepare registra a duração da fase PREPARE e seu desfecho ("ok" ou "error")
+func (m *xaPhaseMetrics) RecordPrepare(ctx context.Context, outcome string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.prepareDuration.Record(ctx, duration.Seconds(), m.attrs(outcome))
+}
+
+// RecordCommit registra a duração da fase COMMIT/ROLLBACK e seu desfecho
+func (m *xaPhaseMetrics) RecordCommit(ctx context.Context, outcome string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.commitDuration.Record(ctx, duration.Seconds(), m.attrs(outcome))
+}
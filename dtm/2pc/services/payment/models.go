@@ -14,14 +14,14 @@ type Wallet struct {
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
-// XAActionRequest representa o payload das requisições XA
+// XAActionRequest representa o payload das requisições XA. O trace context não viaja mais neste
+// payload - é propagado via headers (traceparent/tracestate/baggage) pelo TextMapPropagator
+// global, anexados às chamadas de branch via xa.BranchHeaders
 type XAActionRequest struct {
 	OrderID    string `json:"order_id"`
 	UserID     string `json:"user_id"`
 	ProductID  string `json:"product_id"`
 	TotalPrice int    `json:"total_price"`
-	TraceID    string `json:"trace_id"`
-	SpanID     string `json:"span_id"`
 }
 
 // PaymentRepository define as operações de persistência de pagamentos
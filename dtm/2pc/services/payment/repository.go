@@ -1,7 +1,9 @@
 package main
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"log"
 )
@@ -16,6 +18,21 @@ func NewPostgresPaymentRepository() *PostgresPaymentRepository {
 // DTM gerencia PREPARE/COMMIT automaticamente via XaLocalTransaction
 // Recebe *sql.DB que já está dentro de uma transação XA gerenciada pelo DTM
 func (r *PostgresPaymentRepository) DebitBalanceXA(db *sql.DB, userID, orderID string, amount int) error {
+	// Idempotência: se já existe um débito registrado para este OrderID, a retentativa do DTM
+	// não deve debitar a carteira de novo
+	var alreadyDebited bool
+	err := db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM payment_transactions WHERE order_id = $1 AND transaction_type = 'debit'
+		)
+	`, orderID).Scan(&alreadyDebited)
+	if err != nil {
+		return fmt.Errorf("failed to check existing debit transaction: %w", err)
+	}
+	if alreadyDebited {
+		return ErrNoChange
+	}
+
 	// Atualiza current_amount (já dentro de transação XA do DTM)
 	updateQuery := `
 		UPDATE wallets
@@ -48,5 +65,38 @@ func (r *PostgresPaymentRepository) DebitBalanceXA(db *sql.DB, userID, orderID s
 	}
 
 	log.Printf("✅ [XA] Debited %d from user %s", amount, userID)
+
+	if err := appendSagaEventXA(db, "payment", orderID, "xa", "completed", amount); err != nil {
+		log.Printf("⚠️ [SAGA LOG] failed to append XA event | OrderID=%s | Error=%v", orderID, err)
+	}
+
+	return nil
+}
+
+// appendSagaEventXA grava um evento no log de auditoria saga_events dentro da mesma
+// transação XA gerenciada pelo DTM, encadeando-o ao último evento conhecido do OrderID
+func appendSagaEventXA(db *sql.DB, service, orderID, phase, status string, amount int) error {
+	payloadHash := hashXAPayload(orderID, service, amount)
+
+	var prevEventID sql.NullInt64
+	err := db.QueryRow(`SELECT id FROM saga_events WHERE order_id = $1 ORDER BY id DESC LIMIT 1`, orderID).Scan(&prevEventID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to look up previous saga event: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO saga_events (order_id, service, phase, status, payload_hash, prev_event_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`, orderID, service, phase, status, payloadHash, prevEventID)
+	if err != nil {
+		return fmt.Errorf("failed to append saga event: %w", err)
+	}
+
 	return nil
 }
+
+// hashXAPayload calcula um hash estável dos campos relevantes do payload XA
+func hashXAPayload(orderID, service string, amount int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d", orderID, service, amount)))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 )
 
@@ -17,7 +18,10 @@ func NewPaymentUseCase(repository PaymentRepository) *PaymentUseCase {
 	}
 }
 
-// DebitWalletXA debita o saldo (XA)
+// DebitWalletXA debita o saldo (XA). Se uma retentativa do DTM chegar depois que o débito já foi
+// aplicado (payment_transactions já tem um registro para este OrderID), a escrita é pulada e
+// ErrNoChange é retornado em vez de um sucesso genérico, para que o chamador possa distinguir o
+// caso e evitar republicar o evento de débito
 // Recebe *sql.DB do DTM que já está em transação XA
 func (uc *PaymentUseCase) DebitWalletXA(db *sql.DB, req XAActionRequest) error {
 	log.Printf("💳 [XA] Debit wallet: UserID=%s, Amount=%d, OrderID=%s",
@@ -28,6 +32,10 @@ func (uc *PaymentUseCase) DebitWalletXA(db *sql.DB, req XAActionRequest) error {
 	}
 
 	if err := uc.repository.DebitBalanceXA(db, req.UserID, req.OrderID, req.TotalPrice); err != nil {
+		if errors.Is(err, ErrNoChange) {
+			log.Printf("ℹ️ [XA] Wallet already debited for OrderID=%s, skipping", req.OrderID)
+			return ErrNoChange
+		}
 		log.Printf("❌ [XA] Failed to debit wallet: %v", err)
 		return err
 	}
@@ -39,6 +47,9 @@ func (uc *PaymentUseCase) DebitWalletXA(db *sql.DB, req XAActionRequest) error {
 var (
 	ErrInvalidAmount       = &PaymentError{Message: "amount must be greater than 0"}
 	ErrInsufficientBalance = &PaymentError{Message: "insufficient balance"}
+	// ErrNoChange sinaliza que o estado persistido já era o alvo (débito já aplicado por uma
+	// retentativa anterior) e a escrita foi pulada
+	ErrNoChange = &PaymentError{Message: "no change: wallet already debited"}
 )
 
 type PaymentError struct {
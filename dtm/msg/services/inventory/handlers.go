@@ -0,0 +1,50 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/dtm-labs/client/dtmcli"
+	"github.com/gin-gonic/gin"
+)
+
+// HandleDecreaseStock handler da branch MSG de inventário. dtmcli.BarrierFromGin extrai o
+// BranchBarrier (gid/branch_id/op) que o DTM injeta como query params na chamada - CallWithDB
+// garante que o callback de negócio roda no máximo uma vez mesmo que o DTM reentregue esta
+// branch (at-least-once) por retry/timeout
+func HandleDecreaseStock(uc *InventoryUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MsgActionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("❌ Invalid request body: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		barrier, err := dtmcli.BarrierFromGin(c)
+		if err != nil {
+			log.Printf("❌ Failed to extract branch barrier: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing branch barrier"})
+			return
+		}
+
+		err = barrier.CallWithDB(db, func(tx *sql.Tx) error {
+			return uc.DecreaseStock(tx, req)
+		})
+		if err != nil {
+			log.Printf("❌ [MSG] ORDER_ID %s | Failed: %v", req.OrderID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "decrease_success", "order_id": req.OrderID})
+	}
+}
+
+// HandleHealth handler para health check
+func HandleHealth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "healthy", "service": "inventory-service-msg"})
+	}
+}
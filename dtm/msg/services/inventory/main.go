@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// db é compartilhado pelo handler da branch e pelo dtmcli.BranchBarrier - ao contrário do
+// 2PC/XA, o MSG não precisa de um dtmcli.DBConf (não há PREPARE/COMMIT gerenciado pelo DTM aqui),
+// só de um *sql.DB comum cujo search_path alcance o schema dtm_barrier
+var db *sql.DB
+
+func main() {
+	tp, err := initTracer()
+	if err != nil {
+		log.Fatalf("Failed to initialize tracer: %v", err)
+	}
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down tracer: %v", err)
+		}
+	}()
+
+	db, err = initDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	// Setup repositories and use cases
+	inventoryRepository := NewPostgresInventoryRepository()
+	inventoryUseCase := NewInventoryUseCase(inventoryRepository)
+
+	// Setup Gin router
+	r := gin.Default()
+	r.Use(otelgin.Middleware(getEnv("SERVICE_NAME", "inventory-service-msg")))
+
+	// Health check
+	r.GET("/health", HandleHealth())
+
+	// MSG participant endpoint - chamado pelo DTM pelo menos uma vez (at-least-once)
+	r.POST("/api/inventory/decrease", HandleDecreaseStock(inventoryUseCase))
+
+	port := getEnv("PORT", "8081")
+	log.Printf("🚀 Inventory Service (MSG) listening on port %s", port)
+
+	srv := &http.Server{
+		Addr:         ":" + port,
+		Handler:      r,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  30 * time.Second,
+	}
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
+
+func initDB() (*sql.DB, error) {
+	// search_path precisa incluir 'dtm_barrier' - é lá que o BranchBarrier grava o registro de
+	// dedup (gid, branch_id, op) que garante at-most-once para esta branch
+	dsn := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable search_path=public,dtm_barrier",
+		getEnv("DATABASE_HOST", "postgres"),
+		getEnv("DATABASE_PORT", "5432"),
+		getEnv("DATABASE_USER", "root"),
+		getEnv("DATABASE_PASSWORD", "pass"),
+		getEnv("DATABASE_NAME", "inventory_db"),
+	)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(time.Hour)
+
+	for i := 0; i < 30; i++ {
+		if err := db.Ping(); err == nil {
+			log.Println("✅ Connected to inventory database (MSG mode)")
+			return db, nil
+		}
+		log.Printf("⏳ Waiting for database... (%d/30)", i+1)
+		time.Sleep(1 * time.Second)
+	}
+
+	db.Close()
+	return nil, fmt.Errorf("failed to connect to database after 30 attempts")
+}
+
+func initTracer() (*sdktrace.TracerProvider, error) {
+	ctx := context.Background()
+
+	otlpEndpoint := getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "otel-collector:4318")
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpoint(otlpEndpoint),
+		otlptracehttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(getEnv("SERVICE_NAME", "inventory-service-msg")),
+			semconv.ServiceVersion("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return tp, nil
+}
+
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
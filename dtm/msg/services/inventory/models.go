@@ -0,0 +1,34 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ProductInventory representa um produto no inventário (MSG)
+// SEM stock_available e SEM version (não precisa de optimistic locking - BranchBarrier já garante
+// que esta branch roda no máximo uma vez por (gid, branch_id))
+type ProductInventory struct {
+	ProductID    string    `json:"product_id"`
+	ProductName  string    `json:"product_name"`
+	CurrentStock int       `json:"current_stock"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// MsgActionRequest representa o payload das requisições MSG (sempre 1 unidade). Duplicado em
+// relação a orders/models.go pelo mesmo motivo de sempre: sem go.mod não há como compartilhar um
+// pacote entre os serviços
+type MsgActionRequest struct {
+	OrderID    string `json:"order_id"`
+	UserID     string `json:"user_id"`
+	ProductID  string `json:"product_id"`
+	TotalPrice int    `json:"total_price"`
+}
+
+// InventoryRepository define as operações de persistência de inventário (sempre 1 unidade)
+type InventoryRepository interface {
+	// DecreaseStockTx decrementa 1 unidade do estoque dentro da transação gerenciada pelo
+	// BranchBarrier (recebe a *sql.Tx já aberta pelo CallWithDB)
+	DecreaseStockTx(tx *sql.Tx, productID, orderID string) error
+}
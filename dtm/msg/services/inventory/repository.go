@@ -0,0 +1,50 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+type PostgresInventoryRepository struct{}
+
+func NewPostgresInventoryRepository() *PostgresInventoryRepository {
+	return &PostgresInventoryRepository{}
+}
+
+// DecreaseStockTx decrementa 1 unidade do estoque dentro da transação aberta pelo
+// dtmcli.BranchBarrier.CallWithDB - o próprio barrier já garante que esta função roda no máximo
+// uma vez por (gid, branch_id, op), então não precisa de uma checagem de idempotência adicional
+// como a que o 2PC/XA faz contra inventory_movements
+func (r *PostgresInventoryRepository) DecreaseStockTx(tx *sql.Tx, productID, orderID string) error {
+	updateQuery := `
+		UPDATE products_inventory
+		SET current_stock = current_stock - 1,
+			updated_at = NOW()
+		WHERE product_id = $1
+			AND current_stock >= 1
+	`
+	result, err := tx.Exec(updateQuery, productID)
+	if err != nil {
+		return fmt.Errorf("failed to decrease stock: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("insufficient stock or product not found: %s", productID)
+	}
+
+	movementQuery := `
+		INSERT INTO inventory_movements (product_id, order_id, movement_type, created_at)
+		VALUES ($1, $2, 'decrease', NOW())
+	`
+	if _, err := tx.Exec(movementQuery, productID, orderID); err != nil {
+		return fmt.Errorf("failed to create movement: %w", err)
+	}
+
+	log.Printf("✅ [MSG] Decreased 1 unit of %s", productID)
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+// InventoryUseCase encapsula a lógica de negócio de inventário (MSG)
+type InventoryUseCase struct {
+	repository InventoryRepository
+}
+
+// NewInventoryUseCase cria uma nova instância do caso de uso
+func NewInventoryUseCase(repository InventoryRepository) *InventoryUseCase {
+	return &InventoryUseCase{
+		repository: repository,
+	}
+}
+
+// DecreaseStock decrementa 1 unidade do estoque. Recebe a *sql.Tx já aberta pelo
+// dtmcli.BranchBarrier.CallWithDB do handler
+func (uc *InventoryUseCase) DecreaseStock(tx *sql.Tx, req MsgActionRequest) error {
+	log.Printf("📦 [MSG] Decrease stock: ProductID=%s, Quantity=1, OrderID=%s",
+		req.ProductID, req.OrderID)
+
+	if err := uc.repository.DecreaseStockTx(tx, req.ProductID, req.OrderID); err != nil {
+		log.Printf("❌ [MSG] Failed to decrease stock: %v", err)
+		return err
+	}
+
+	return nil
+}
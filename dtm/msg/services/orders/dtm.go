@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dtm-labs/client/dtmcli"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MsgOrchestrator abstrai o padrão MSG (transactional outbox) do DTM
+type MsgOrchestrator interface {
+	CreateOrderMsg(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error)
+}
+
+// DTMMsgOrchestrator implementa MsgOrchestrator usando dtmcli.Msg. Ao contrário do TCC/SAGA/XA,
+// o orquestrador aqui precisa de acesso direto ao *sql.DB: a submissão da mensagem global só
+// acontece se o insert local (o outbox - o pedido como "pending") commitar, e é o próprio
+// DoAndSubmitDB que gerencia essa transação local em torno do callback de negócio
+type DTMMsgOrchestrator struct {
+	db         *sql.DB
+	repository OrderRepository
+}
+
+// NewDTMMsgOrchestrator cria uma nova instância do orquestrador MSG
+func NewDTMMsgOrchestrator(db *sql.DB, repository OrderRepository) *DTMMsgOrchestrator {
+	return &DTMMsgOrchestrator{db: db, repository: repository}
+}
+
+// CreateOrderMsg grava o pedido local como "pending" e, na mesma transação, decide se a
+// mensagem global é submetida ao DTM: (1) abre a transação local via DoAndSubmitDB, (2) insere o
+// pedido "pending" dentro dela, (3) se o insert comitar, o DTM entrega as branches de
+// inventory/payment pelo menos uma vez (at-least-once) - cada uma protegida por BranchBarrier
+func (mo *DTMMsgOrchestrator) CreateOrderMsg(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error) {
+	tracer := otel.Tracer("dtm-msg-orchestrator")
+
+	ctx, span := tracer.Start(ctx, "MSG-Submit")
+	defer span.End()
+
+	start := time.Now()
+	dtxM.AddInflight(ctx, "msg", 1)
+	defer dtxM.AddInflight(ctx, "msg", -1)
+
+	orderID = uuid.New().String()
+
+	// Injeta o trace context atual (traceparent/tracestate) nos campos Traceparent/Tracestate do
+	// payload MSG - o DTM não repassa headers HTTP arbitrários nas chamadas que faz depois. Os
+	// MsgActionRequest de inventory/payment não declaram esses campos, então os participantes
+	// não os leem
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	traceparent := carrier.Get("traceparent")
+	tracestate := carrier.Get("tracestate")
+
+	if s := trace.SpanFromContext(ctx); s.SpanContext().IsValid() {
+		traceID = s.SpanContext().TraceID().String()
+	}
+
+	dtmServer := getEnv("DTM_SERVER", "http://dtm:36789/api/dtmsvr")
+
+	defer func() {
+		if r := recover(); r != nil {
+			span.RecordError(fmt.Errorf("panic in MustGenGid due to unavailable dtm: %v", r))
+			span.SetStatus(codes.Error, "panic in MustGenGid due to unavailable dtm")
+		}
+	}()
+	gid = dtmcli.MustGenGid(dtmServer)
+	if gid == "" {
+		return orderID, "", traceID, fmt.Errorf("internal error: failed to generate GID")
+	}
+
+	span.SetAttributes(
+		attribute.String("msg.trace_id", traceID),
+		attribute.String("msg.gid", gid),
+		attribute.String("msg.order_id", orderID),
+		attribute.String("msg.user_id", req.UserID),
+		attribute.String("msg.product_id", req.ProductID),
+		attribute.Int("msg.participants", 2), // Inventory, Payment
+	)
+
+	log.Printf("🚀 Submitting MSG transaction | TraceID: %s | GID: %s | OrderID: %s", traceID, gid, orderID)
+
+	inventoryServiceURL := getEnv("INVENTORY_SERVICE_URL", "http://inventory-service:8080")
+	paymentServiceURL := getEnv("PAYMENT_SERVICE_URL", "http://payment-service:8080")
+	ordersServiceURL := getEnv("ORDERS_SERVICE_URL", "http://orders-service:8080")
+
+	payload := MsgActionRequest{
+		OrderID:     orderID,
+		UserID:      req.UserID,
+		ProductID:   req.ProductID,
+		TotalPrice:  req.Amount,
+		Traceparent: traceparent,
+		Tracestate:  tracestate,
+	}
+
+	msg := dtmcli.NewMsg(dtmServer, gid).
+		Add(inventoryServiceURL+"/api/inventory/decrease", &payload).
+		Add(paymentServiceURL+"/api/payment/debit", &payload)
+
+	queryPrepared := ordersServiceURL + "/api/orders/query-prepared?order_id=" + orderID
+
+	err = msg.DoAndSubmitDB(queryPrepared, mo.db, func(tx *sql.Tx) error {
+		return mo.repository.CreateOrderTx(tx, &Order{
+			OrderID:    orderID,
+			UserID:     req.UserID,
+			ProductID:  req.ProductID,
+			TotalPrice: req.Amount,
+			Status:     "pending",
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		})
+	})
+
+	// outcome aqui reflete apenas se o insert local comitou e a mensagem global foi aceita pelo
+	// DTM - a entrega efetiva das branches de inventory/payment acontece depois, de forma
+	// assíncrona e at-least-once
+	if err != nil {
+		dtxM.RecordTransaction(ctx, "msg", "submit_failed", time.Since(start))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "MSG submission failed")
+		log.Printf("❌ MSG SUBMISSION FAILED | TraceID: %s | GID: %s | Error: %v", traceID, gid, err)
+		return orderID, gid, traceID, fmt.Errorf("MSG submission failed: %w", err)
+	}
+
+	dtxM.RecordTransaction(ctx, "msg", "submitted", time.Since(start))
+	span.SetStatus(codes.Ok, "MSG transaction submitted successfully")
+	log.Printf("✅ MSG SUBMITTED | TraceID: %s | GID: %s | OrderID: %s (DTM delivering asynchronously)", traceID, gid, orderID)
+	return orderID, gid, traceID, nil
+}
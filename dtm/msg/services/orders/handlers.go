@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleCreateOrder handler para criação de pedidos - submete a transação MSG e retorna
+// imediatamente (processamento assíncrono via DTM)
+func HandleCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateOrderRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("❌ Invalid request body: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+			return
+		}
+
+		ctx, span := tracer.Start(c.Request.Context(), "orders.CreateOrder")
+		defer span.End()
+
+		orderID, gid, traceID, err := uc.CreateOrder(ctx, req)
+		if err != nil {
+			log.Printf("❌ Failed to submit MSG order: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit MSG order", "details": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"order_id": orderID,
+			"gid":      gid,
+			"trace_id": traceID,
+			"status":   "processing",
+			"message":  "Order is being processed asynchronously via MSG",
+		})
+	}
+}
+
+// HandleQueryPrepared implementa a branch de query-prepared consultada pelo DTM quando ele não
+// recebe a resposta do DoAndSubmitDB - o order_id vem na query string da URL de query-prepared
+// montada por CreateOrderMsg, e dtm_result na resposta traz SUCCESS (pedido já gravado) ou
+// ONGOING (pedido ainda não existe)
+func HandleQueryPrepared(uc *OrderUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orderID := c.Query("order_id")
+		if orderID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "order_id is required"})
+			return
+		}
+
+		result, err := uc.QueryPreparedOrder(c.Request.Context(), orderID)
+		if err != nil {
+			log.Printf("❌ [QUERY-PREPARED] failed to resolve OrderID=%s: %v", orderID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"dtm_result": result})
+	}
+}
+
+// HandleHealth handler para health check
+func HandleHealth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "healthy", "service": "orders-service-msg"})
+	}
+}
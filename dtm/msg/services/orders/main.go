@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	db     *sql.DB
+	tracer trace.Tracer
+	dtxM   *dtxMetrics
+)
+
+func main() {
+	tp, err := initTracer()
+	if err != nil {
+		log.Fatalf("Failed to initialize tracer: %v", err)
+	}
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down tracer: %v", err)
+		}
+	}()
+
+	tracer = tp.Tracer("orders-service-msg")
+
+	mp, err := initMetrics()
+	if err != nil {
+		log.Fatalf("Failed to initialize metrics: %v", err)
+	}
+	defer func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down meter: %v", err)
+		}
+	}()
+
+	// RED metrics for the MSG submission (dtx_transaction_duration_seconds, dtx_inflight_transactions)
+	meter := mp.Meter("orders-service-msg")
+	dtxM, err = newDTXMetrics(meter)
+	if err != nil {
+		log.Fatalf("Failed to initialize DTM metrics: %v", err)
+	}
+
+	// Initialize database - database/sql (não pgx), já que DoAndSubmitDB do dtmcli gerencia a
+	// transação local via *sql.Tx
+	db, err = initDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	// Setup repositories and use cases
+	orderRepository := NewPostgresOrderRepository()
+	msgOrchestrator := NewDTMMsgOrchestrator(db, orderRepository)
+	orderUseCase := NewOrderUseCase(orderRepository, msgOrchestrator)
+
+	// Setup Gin router
+	r := gin.New()
+	r.Use(gin.Logger())
+	r.Use(gin.RecoveryWithWriter(gin.DefaultWriter, func(c *gin.Context, recovered interface{}) {
+		log.Printf("🚨 PANIC RECOVERED: %v", recovered)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	r.Use(otelgin.Middleware(getEnv("SERVICE_NAME", "orders-service-msg")))
+
+	// Health check
+	r.GET("/health", HandleHealth())
+
+	// MSG orchestrator endpoint - submits the local outbox insert + global message (retorna 202 Accepted)
+	r.POST("/api/orders", HandleCreateOrder(orderUseCase))
+
+	// query-prepared callback - chamado pelo DTM quando não recebe a resposta do DoAndSubmitDB
+	r.GET("/api/orders/query-prepared", HandleQueryPrepared(orderUseCase))
+
+	port := getEnv("PORT", "8080")
+	log.Printf("🚀 Orders Service (MSG) listening on port %s", port)
+	srv := &http.Server{
+		Addr:         ":" + port,
+		Handler:      r,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  30 * time.Second,
+	}
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
+
+func initDB() (*sql.DB, error) {
+	dsn := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		getEnv("DATABASE_HOST", "postgres"),
+		getEnv("DATABASE_PORT", "5432"),
+		getEnv("DATABASE_USER", "root"),
+		getEnv("DATABASE_PASSWORD", "pass"),
+		getEnv("DATABASE_NAME", "orders_db"),
+	)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 30; i++ {
+		if err := db.PingContext(ctx); err == nil {
+			log.Println("✅ Connected to orders database with database/sql")
+			return db, nil
+		}
+		log.Printf("⏳ Waiting for database... (%d/30)", i+1)
+		time.Sleep(1 * time.Second)
+	}
+
+	db.Close()
+	return nil, fmt.Errorf("failed to connect to database after 30 attempts")
+}
+
+func initTracer() (*sdktrace.TracerProvider, error) {
+	ctx := context.Background()
+
+	otlpEndpoint := getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "otel-collector:4318")
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpoint(otlpEndpoint),
+		otlptracehttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(getEnv("SERVICE_NAME", "orders-service-msg")),
+			semconv.ServiceVersion("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return tp, nil
+}
+
+func initMetrics() (*sdkmetric.MeterProvider, error) {
+	ctx := context.Background()
+
+	otlpEndpoint := getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "otel-collector:4318")
+
+	exporter, err := otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpoint(otlpEndpoint),
+		otlpmetrichttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(getEnv("SERVICE_NAME", "orders-service-msg")),
+			semconv.ServiceVersion("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(res),
+	)
+
+	otel.SetMeterProvider(mp)
+
+	return mp, nil
+}
+
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// dtxMetrics agrupa os instrumentos RED (rate/errors/duration) emitidos ao longo da submissão
+// MSG. Duplicado em relação aos demais services/orders pelo mesmo motivo de sempre: sem go.mod
+// não há como compartilhar um pacote entre os serviços, então os nomes/semântica dos instrumentos
+// são mantidos idênticos "na mão" para que os dashboards continuem comparáveis entre
+// SAGA/XA/TCC/MSG.
+type dtxMetrics struct {
+	transactionDuration  metric.Float64Histogram
+	branchDuration       metric.Float64Histogram
+	branchRetries        metric.Int64Counter
+	inflightTransactions metric.Int64UpDownCounter
+}
+
+// newDTXMetrics registra os instrumentos RED no meter informado
+func newDTXMetrics(meter metric.Meter) (*dtxMetrics, error) {
+	transactionDuration, err := meter.Float64Histogram(
+		"dtx_transaction_duration_seconds",
+		metric.WithDescription("Duration of a full distributed transaction from orchestration start to terminal outcome"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dtx_transaction_duration_seconds histogram: %w", err)
+	}
+
+	branchDuration, err := meter.Float64Histogram(
+		"dtx_branch_duration_seconds",
+		metric.WithDescription("Duration of a single branch call/phase against a participant service"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dtx_branch_duration_seconds histogram: %w", err)
+	}
+
+	branchRetries, err := meter.Int64Counter(
+		"dtx_branch_retries_total",
+		metric.WithDescription("Number of retried branch call attempts"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dtx_branch_retries_total counter: %w", err)
+	}
+
+	inflightTransactions, err := meter.Int64UpDownCounter(
+		"dtx_inflight_transactions",
+		metric.WithDescription("Number of distributed transactions currently being orchestrated"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dtx_inflight_transactions counter: %w", err)
+	}
+
+	return &dtxMetrics{
+		transactionDuration:  transactionDuration,
+		branchDuration:       branchDuration,
+		branchRetries:        branchRetries,
+		inflightTransactions: inflightTransactions,
+	}, nil
+}
+
+// RecordTransaction registra a duração total de uma transação (do início da orquestração até o
+// desfecho terminal) e seu desfecho
+func (m *dtxMetrics) RecordTransaction(ctx context.Context, mode, outcome string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.transactionDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.String("mode", mode),
+		attribute.String("outcome", outcome),
+	))
+}
+
+// RecordBranch registra a duração de uma fase/branch isolada contra um serviço participante
+func (m *dtxMetrics) RecordBranch(ctx context.Context, service, phase string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.branchDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.String("service", service),
+		attribute.String("phase", phase),
+	))
+}
+
+// AddInflight incrementa (delta positivo) ou decrementa (delta negativo) o número de transações
+// em andamento para o modo informado
+func (m *dtxMetrics) AddInflight(ctx context.Context, mode string, delta int64) {
+	if m == nil {
+		return
+	}
+	m.inflightTransactions.Add(ctx, delta, metric.WithAttributes(attribute.String("mode", mode)))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Order representa um pedido no sistema (MSG/transactional outbox) - sempre 1 unidade por pedido
+type Order struct {
+	OrderID    string    `json:"order_id"`
+	UserID     string    `json:"user_id"`
+	ProductID  string    `json:"product_id"`
+	TotalPrice int       `json:"total_price"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CreateOrderRequest representa a requisição de criação de pedido (sempre 1 unidade)
+type CreateOrderRequest struct {
+	UserID    string `json:"user_id"`
+	ProductID string `json:"product_id"`
+	Amount    int    `json:"amount"`
+}
+
+// MsgActionRequest representa o payload entregue pelo DTM às branches de inventory/payment.
+// Ao contrário do TCC/SAGA, o padrão MSG não tem fase de compensação - a branch roda no mínimo
+// uma vez (at-least-once) e a idempotência fica por conta do BranchBarrier de cada participante
+type MsgActionRequest struct {
+	OrderID     string `json:"order_id"`
+	UserID      string `json:"user_id"`
+	ProductID   string `json:"product_id"`
+	TotalPrice  int    `json:"total_price"`
+	Traceparent string `json:"traceparent,omitempty"`
+	Tracestate  string `json:"tracestate,omitempty"`
+}
+
+// OrderRepository define as operações de persistência de pedidos
+type OrderRepository interface {
+	// CreateOrderTx insere o pedido como "pending" dentro da mesma transação local que o
+	// DoAndSubmitDB do DTM usa para decidir se a mensagem global é submetida ou descartada
+	CreateOrderTx(tx *sql.Tx, order *Order) error
+
+	// GetOrderStatus é consultado pelo endpoint de query-prepared, que o DTM chama quando não
+	// recebe a resposta do DoAndSubmitDB (ex: o processo caiu entre o commit local e o ACK HTTP)
+	// para descobrir se a transação local foi efetivamente commitada
+	GetOrderStatus(orderID string) (string, error)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+type PostgresOrderRepository struct{}
+
+func NewPostgresOrderRepository() *PostgresOrderRepository {
+	return &PostgresOrderRepository{}
+}
+
+// CreateOrderTx insere o pedido como "pending" dentro da transação local do DoAndSubmitDB - se
+// esta transação não commitar, o DTM nunca submete a mensagem global e nenhuma branch roda
+func (r *PostgresOrderRepository) CreateOrderTx(tx *sql.Tx, order *Order) error {
+	query := `
+		INSERT INTO orders (order_id, user_id, product_id, total_price, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := tx.Exec(query,
+		order.OrderID,
+		order.UserID,
+		order.ProductID,
+		order.TotalPrice,
+		order.Status,
+		order.CreatedAt,
+		order.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+
+	log.Printf("✅ [MSG] Created order %s with status '%s'", order.OrderID, order.Status)
+	return nil
+}
+
+// GetOrderStatus é usado pela branch de query-prepared para responder ao DTM se a transação
+// local (o insert acima) foi de fato commitada
+func (r *PostgresOrderRepository) GetOrderStatus(orderID string) (string, error) {
+	var status string
+	err := db.QueryRow(`SELECT status FROM orders WHERE order_id = $1`, orderID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query order status: %w", err)
+	}
+	return status, nil
+}
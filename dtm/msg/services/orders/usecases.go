@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// OrderUseCase encapsula a lógica de negócio de pedidos (MSG)
+type OrderUseCase struct {
+	repository      OrderRepository
+	msgOrchestrator MsgOrchestrator
+}
+
+// NewOrderUseCase cria uma nova instância do caso de uso
+func NewOrderUseCase(repository OrderRepository, msgOrchestrator MsgOrchestrator) *OrderUseCase {
+	return &OrderUseCase{
+		repository:      repository,
+		msgOrchestrator: msgOrchestrator,
+	}
+}
+
+// CreateOrder submete a transação MSG e retorna 202 Accepted - a entrega das branches de
+// inventory/payment acontece de forma assíncrona e at-least-once
+func (uc *OrderUseCase) CreateOrder(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error) {
+	log.Printf("📦 Submitting MSG order: UserID=%s, ProductID=%s, TotalPrice=%d (1 unit)",
+		req.UserID, req.ProductID, req.Amount)
+
+	if req.Amount <= 0 {
+		return "", "", "", ErrInvalidPrice
+	}
+
+	orderID, gid, traceID, err = uc.msgOrchestrator.CreateOrderMsg(ctx, req)
+	if err != nil {
+		if orderID == "" {
+			orderID = uuid.New().String()
+		}
+		log.Printf("❌ MSG submission failed: %v", err)
+		return orderID, gid, traceID, fmt.Errorf("submitting MSG order failed: %w", err)
+	}
+
+	log.Printf("✅ MSG order submitted | OrderID=%s | GID=%s | TraceID=%s (processing asynchronously)", orderID, gid, traceID)
+	return orderID, gid, traceID, nil
+}
+
+// QueryPreparedOrder responde ao callback de query-prepared do DTM: se a transação local de
+// CreateOrderMsg commitou (pedido "pending" já gravado), a mensagem global pode ser submetida
+// mesmo que o processo tenha caído entre o commit e o ACK HTTP do DoAndSubmitDB
+func (uc *OrderUseCase) QueryPreparedOrder(ctx context.Context, orderID string) (string, error) {
+	status, err := uc.repository.GetOrderStatus(orderID)
+	if err != nil {
+		return "", err
+	}
+	if status == "" {
+		// Linha ainda não existe - a transação local nunca commitou (ou ainda está em voo)
+		return "ONGOING", nil
+	}
+	return "SUCCESS", nil
+}
+
+// Erros customizados
+var (
+	ErrInvalidPrice = &OrderError{Message: "total price must be greater than 0"}
+)
+
+type OrderError struct {
+	Message string
+}
+
+func (e *OrderError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Wallet representa uma carteira de usuário (MSG)
+// SEM available_amount e SEM version (não precisa de optimistic locking - BranchBarrier já
+// garante que esta branch roda no máximo uma vez por (gid, branch_id))
+type Wallet struct {
+	UserID        string    `json:"user_id"`
+	CurrentAmount int       `json:"current_amount"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// MsgActionRequest representa o payload das requisições MSG. Duplicado em relação aos demais
+// services/payment pelo mesmo motivo de sempre: sem go.mod não há como compartilhar um pacote
+// entre os serviços
+type MsgActionRequest struct {
+	OrderID    string `json:"order_id"`
+	UserID     string `json:"user_id"`
+	ProductID  string `json:"product_id"`
+	TotalPrice int    `json:"total_price"`
+}
+
+// PaymentRepository define as operações de persistência de pagamentos
+type PaymentRepository interface {
+	// DebitBalanceTx debita o saldo dentro da transação gerenciada pelo BranchBarrier (recebe a
+	// *sql.Tx já aberta pelo CallWithDB)
+	DebitBalanceTx(tx *sql.Tx, userID, orderID string, amount int) error
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+type PostgresPaymentRepository struct{}
+
+func NewPostgresPaymentRepository() *PostgresPaymentRepository {
+	return &PostgresPaymentRepository{}
+}
+
+// DebitBalanceTx debita o saldo dentro da transação aberta pelo
+// dtmcli.BranchBarrier.CallWithDB - o próprio barrier já garante que esta função roda no máximo
+// uma vez por (gid, branch_id, op), então não precisa de uma checagem de idempotência adicional
+// como a que o 2PC/XA faz contra payment_transactions
+func (r *PostgresPaymentRepository) DebitBalanceTx(tx *sql.Tx, userID, orderID string, amount int) error {
+	updateQuery := `
+		UPDATE wallets
+		SET current_amount = current_amount - $1,
+			updated_at = NOW()
+		WHERE user_id = $2
+			AND current_amount >= $1
+	`
+	result, err := tx.Exec(updateQuery, amount, userID)
+	if err != nil {
+		return fmt.Errorf("failed to debit balance: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("insufficient balance or user not found: %s", userID)
+	}
+
+	transactionQuery := `
+		INSERT INTO payment_transactions (user_id, order_id, amount, transaction_type, created_at)
+		VALUES ($1, $2, $3, 'debit', NOW())
+	`
+	if _, err := tx.Exec(transactionQuery, userID, orderID, amount); err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	log.Printf("✅ [MSG] Debited %d from user %s", amount, userID)
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+// PaymentUseCase encapsula a lógica de negócio de pagamentos (MSG)
+type PaymentUseCase struct {
+	repository PaymentRepository
+}
+
+// NewPaymentUseCase cria uma nova instância do caso de uso
+func NewPaymentUseCase(repository PaymentRepository) *PaymentUseCase {
+	return &PaymentUseCase{
+		repository: repository,
+	}
+}
+
+// DebitWallet debita o saldo. Recebe a *sql.Tx já aberta pelo
+// dtmcli.BranchBarrier.CallWithDB do handler
+func (uc *PaymentUseCase) DebitWallet(tx *sql.Tx, req MsgActionRequest) error {
+	log.Printf("💳 [MSG] Debit wallet: UserID=%s, Amount=%d, OrderID=%s",
+		req.UserID, req.TotalPrice, req.OrderID)
+
+	if req.TotalPrice <= 0 {
+		return ErrInvalidAmount
+	}
+
+	if err := uc.repository.DebitBalanceTx(tx, req.UserID, req.OrderID, req.TotalPrice); err != nil {
+		log.Printf("❌ [MSG] Failed to debit wallet: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Erros customizados
+var (
+	ErrInvalidAmount       = &PaymentError{Message: "amount must be greater than 0"}
+	ErrInsufficientBalance = &PaymentError{Message: "insufficient balance"}
+)
+
+type PaymentError struct {
+	Message string
+}
+
+func (e *PaymentError) Error() string {
+	return e.Message
+}
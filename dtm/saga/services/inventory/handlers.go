@@ -1,12 +1,12 @@
 package main
 
 import (
-	"context"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -32,18 +32,18 @@ func (h *InventoryHandler) DecreaseStock(c *gin.Context) {
 		return
 	}
 
-	ctx, span := getOrStartSpanFromPayload(c.Request.Context(), "decrease_inventory", req)
+	ctx, span := startSpanFromPayload(c.Request.Context(), "decrease_inventory", "decrease", req)
 	defer span.End()
 
 	span.SetAttributes(
-		attribute.String("order_id", req.OrderID),
 		attribute.String("product_id", req.ProductID),
-		attribute.String("trace_id", req.TraceID),
 	)
 
 	err := h.useCase.DecreaseStock(ctx, req)
 	if err != nil {
 		log.Printf("ℹ️ [STOCK] FAILED for OrderID=%s : %s", req.OrderID, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "decrease_inventory failed")
 
 		// Determina o código de erro baseado na mensagem
 		if containsAny(err.Error(), []string{"product not found", "insufficient stock"}) {
@@ -65,18 +65,18 @@ func (h *InventoryHandler) CompensateStock(c *gin.Context) {
 		return
 	}
 
-	ctx, span := getOrStartSpanFromPayload(c.Request.Context(), "compensate_inventory", req)
+	ctx, span := startSpanFromPayload(c.Request.Context(), "compensate_inventory", "compensate", req)
 	defer span.End()
 
 	span.SetAttributes(
-		attribute.String("order_id", req.OrderID),
 		attribute.String("product_id", req.ProductID),
-		attribute.String("trace_id", req.TraceID),
 	)
 
 	err := h.useCase.CompensateStock(ctx, req)
 	if err != nil {
 		log.Printf("ℹ️ [COMPENSATE STOCK] FAILED for OrderID=%s : %s", req.OrderID, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "compensate_inventory failed")
 
 		// Determina o código de erro baseado na mensagem
 		if containsAny(err.Error(), []string{"version conflict", "max retries exceeded"}) {
@@ -108,14 +108,3 @@ func containsAny(s string, substrs []string) bool {
 	}
 	return false
 }
-
-// getOrStartSpanFromPayload garante que sempre retorna um span filho do tracing atual (ou cria um novo se não houver)
-func getOrStartSpanFromPayload(ctx context.Context, operationName string, req SagaActionRequest) (context.Context, trace.Span) {
-	span := trace.SpanFromContext(ctx)
-	if span == nil || !span.SpanContext().IsValid() {
-		return startSpanFromPayload(ctx, operationName, req)
-	}
-	// Se já existe um span válido, apenas o renomeia e retorna o contexto atual
-	span.SetName(operationName)
-	return ctx, span
-}
@@ -10,7 +10,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
@@ -24,33 +26,51 @@ var (
 	tracer trace.Tracer
 )
 
-// startSpanFromPayload creates a child span linked to the propagated trace context
-func startSpanFromPayload(ctx context.Context, operationName string, req SagaActionRequest) (context.Context, trace.Span) {
-	if req.TraceID != "" && req.SpanID != "" {
-		parsedTraceID, _ := trace.TraceIDFromHex(req.TraceID)
-		parsedSpanID, _ := trace.SpanIDFromHex(req.SpanID)
+// startSpanFromPayload extrai o trace context propagado pelo orquestrador SAGA (via o par
+// traceparent/tracestate no payload, já que o DTM chama as branches diretamente e não repassa
+// os headers HTTP da requisição original) e enriquece o span com os atributos da ação, incluindo
+// o branch_id atribuído pelo orquestrador e o op (action|compensate) derivado de phase - op
+// identifica a direção DTM chamou (forward x rollback), phase identifica a ação específica
+// dentro dessa direção (ex.: "decrease" vs "compensate")
+func startSpanFromPayload(ctx context.Context, operationName, phase string, req SagaActionRequest) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier{
+		"traceparent": req.Traceparent,
+		"tracestate":  req.Tracestate,
+	})
 
-		spanContext := trace.NewSpanContext(trace.SpanContextConfig{
-			TraceID:    parsedTraceID,
-			SpanID:     parsedSpanID,
-			TraceFlags: trace.FlagsSampled,
-			Remote:     true,
-		})
+	ctx, span := tracer.Start(ctx, operationName)
+	span.SetAttributes(
+		attribute.String("saga.phase", phase),
+		attribute.String("saga.op", sagaOpFromPhase(phase)),
+		attribute.Int("saga.branch_id", req.BranchID),
+		attribute.String("saga.order_id", req.OrderID),
+		attribute.String("db.system", "postgresql"),
+	)
+	return ctx, span
+}
 
-		ctx = trace.ContextWithSpanContext(ctx, spanContext)
+// sagaOpFromPhase reduz phase (específico de cada branch, ex.: "decrease"/"debit"/"create") ao op
+// genérico que o DTM de fato usa para decidir qual URL chamar: "compensate" nas branches de
+// rollback, "action" em toda branch forward (create/complete/debit/decrease/...)
+func sagaOpFromPhase(phase string) string {
+	if phase == "compensate" {
+		return "compensate"
 	}
-
-	return tracer.Start(ctx, operationName)
+	return "action"
 }
 
+// SagaActionRequest representa a requisição para ações da SAGA. Carrega o trace context como o
+// par traceparent/tracestate do W3C Trace Context em vez de TraceID/SpanID em hex cru. BranchID é
+// o índice (0-based) da branch dentro do saga.NewSaga().Add(...) montado em dtm.go, usado para
+// correlacionar os spans de cada serviço com a branch DTM que os disparou
 type SagaActionRequest struct {
-	OrderID   string `json:"order_id" binding:"required"`
-	UserID    string `json:"user_id"`
-	ProductID string `json:"product_id" binding:"required"`
-	Amount    int    `json:"amount"`
-	// Manual trace context propagation
-	TraceID string `json:"trace_id,omitempty"`
-	SpanID  string `json:"span_id,omitempty"`
+	OrderID     string `json:"order_id" binding:"required"`
+	UserID      string `json:"user_id"`
+	ProductID   string `json:"product_id" binding:"required"`
+	Amount      int    `json:"amount"`
+	BranchID    int    `json:"branch_id"`
+	Traceparent string `json:"traceparent,omitempty"`
+	Tracestate  string `json:"tracestate,omitempty"`
 }
 
 func main() {
@@ -76,21 +96,44 @@ func main() {
 
 	// Setup Gin router
 	r := gin.Default()
+	r.Use(otelgin.Middleware(getEnv("SERVICE_NAME", "inventory-service")))
 
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 	})
 
-	repository := NewInventoryRepository(dbPool)
+	repository, closeRepository := initInventoryRepository(dbPool)
+	defer closeRepository()
+	outboxRepository := NewPostgresOutboxRepository(dbPool)
 
-	usecases := NewInventoryUseCase(repository, tracer)
+	usecases := NewInventoryUseCase(repository, outboxRepository, tracer)
 
 	handler := NewInventoryHandler(usecases, tracer)
 
-	// SAGA action endpoints
-	r.POST("/api/inventory/decrease", handler.DecreaseStock)
-	r.POST("/api/inventory/compensate", handler.CompensateStock)
+	idempotencyStore := NewPostgresIdempotencyStore(dbPool)
+	idemMetrics, err := newIdempotencyMetrics(idempotencyMeter)
+	if err != nil {
+		log.Fatalf("Failed to initialize idempotency metrics: %v", err)
+	}
+
+	// Outbox relayer: publishes inventory_outbox_events (decrease/compensate) to the configured
+	// webhook, closing the dual-write gap between the stock mutation above and downstream
+	// consumers being notified
+	publisher := NewHTTPPublisher(getEnv("INVENTORY_OUTBOX_CALLBACK_URL", "http://event-relay:8080/webhooks/inventory-events"))
+	relay := NewOutboxRelay(outboxRepository, publisher, 20)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	relay.Start(relayCtx, 2*time.Second)
+
+	// SAGA action endpoints - chaosMiddleware injeta falhas configuráveis (ver chaos.go) antes do
+	// IdempotencyMiddleware, que curto-circuita reentregas do DTM pela chave order_id+phase
+	r.POST("/api/inventory/decrease", chaosMiddleware(chaosCfg, "decrease"), IdempotencyMiddleware(idempotencyStore, idemMetrics, "inventory.decrease_stock", "decrease"), handler.DecreaseStock)
+	r.POST("/api/inventory/compensate", chaosMiddleware(chaosCfg, "compensate"), IdempotencyMiddleware(idempotencyStore, idemMetrics, "inventory.compensate_stock", "compensate"), handler.CompensateStock)
+
+	// Admin endpoint - ajusta a injeção de falhas em tempo real (ver chaos.go)
+	r.GET("/admin/chaos", chaosAdminHandler(chaosCfg))
+	r.POST("/admin/chaos", chaosAdminHandler(chaosCfg))
 
 	port := getEnv("PORT", "8080")
 	log.Printf("🚀 Inventory Service listening on port %s", port)
@@ -121,6 +164,10 @@ func initDB() (*pgxpool.Pool, error) {
 	config.MaxConnIdleTime = 30 * time.Minute
 	config.HealthCheckPeriod = 1 * time.Minute
 
+	// Instrumenta cada Query/Exec/BeginTx com um span "db.query", para decompor o tempo gasto no
+	// Postgres dentro do flame graph de cada fase da SAGA
+	config.ConnConfig.Tracer = newDBQueryTracer(getEnv("SERVICE_NAME", "inventory-service"))
+
 	ctx := context.Background()
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
@@ -128,17 +175,30 @@ func initDB() (*pgxpool.Pool, error) {
 	}
 
 	// Wait for database to be ready
+	connected := false
 	for i := 0; i < 30; i++ {
 		if err := pool.Ping(ctx); err == nil {
 			log.Println("✅ Connected to inventory database with connection pool")
-			return pool, nil
+			connected = true
+			break
 		}
 		log.Printf("⏳ Waiting for database... (%d/30)", i+1)
 		time.Sleep(1 * time.Second)
 	}
+	if !connected {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database after 30 attempts")
+	}
+
+	// IdempotencyMiddleware depende de idempotency_keys existir antes do primeiro POST em
+	// /api/inventory/{decrease,compensate} - sem isso, toda requisição falharia com
+	// "relation idempotency_keys does not exist"
+	if _, err := pool.Exec(ctx, ensureIdempotencyKeysTableSQL); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ensure idempotency_keys table: %w", err)
+	}
 
-	pool.Close()
-	return nil, fmt.Errorf("failed to connect to database after 30 attempts")
+	return pool, nil
 }
 
 func initTracer() (*sdktrace.TracerProvider, error) {
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var outboxMeter = otel.Meter("inventory-service-saga-outbox")
+
+// HTTPPublisher implementa Publisher entregando o payload do evento via POST a uma URL fixa
+// (webhook configurável). É o único backend disponível hoje - a interface Publisher existe para
+// que um backend Kafka/NATS seja adicionado depois sem alterar OutboxRelay.
+type HTTPPublisher struct {
+	url        string
+	httpClient *http.Client
+}
+
+func NewHTTPPublisher(url string) *HTTPPublisher {
+	return &HTTPPublisher{url: url, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *HTTPPublisher) Publish(ctx context.Context, event OutboxEvent) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(event.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to build publish request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Outbox-Event-Type", event.EventType)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("publish request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("publish endpoint returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// OutboxRelay varre periodicamente o outbox em busca de eventos pendentes cujo backoff já
+// expirou e os entrega via publisher, marcando-os como publicados numa entrega bem-sucedida ou
+// incrementando failed_attempts (que adia a próxima tentativa - ver ClaimPendingEvents) numa
+// falha. Várias instâncias podem rodar concorrentemente, mas sem lotes disjuntos: o lock de
+// ClaimPendingEvents termina com o SELECT, então um evento ainda não marcado como publicado pode
+// ser entregue por mais de uma réplica.
+type OutboxRelay struct {
+	outbox    OutboxRepository
+	publisher Publisher
+	batchSize int
+
+	published metric.Int64Counter
+	failed    metric.Int64Counter
+	backlog   metric.Int64Histogram
+}
+
+// NewOutboxRelay cria um OutboxRelay e registra os contadores OTel de publicação
+func NewOutboxRelay(outbox OutboxRepository, publisher Publisher, batchSize int) *OutboxRelay {
+	relay := &OutboxRelay{outbox: outbox, publisher: publisher, batchSize: batchSize}
+
+	published, err := outboxMeter.Int64Counter(
+		"inventory_outbox_published_total",
+		metric.WithDescription("Number of inventory outbox events successfully published"),
+	)
+	if err != nil {
+		log.Printf("⚠️ [OUTBOX RELAY] failed to create published counter: %v", err)
+	}
+	relay.published = published
+
+	failed, err := outboxMeter.Int64Counter(
+		"inventory_outbox_publish_failures_total",
+		metric.WithDescription("Number of inventory outbox publish attempts that failed"),
+	)
+	if err != nil {
+		log.Printf("⚠️ [OUTBOX RELAY] failed to create failed counter: %v", err)
+	}
+	relay.failed = failed
+
+	backlog, err := outboxMeter.Int64Histogram(
+		"inventory_outbox_backlog_size",
+		metric.WithDescription("Number of pending inventory outbox events observed at each relay tick"),
+	)
+	if err != nil {
+		log.Printf("⚠️ [OUTBOX RELAY] failed to create backlog histogram: %v", err)
+	}
+	relay.backlog = backlog
+
+	return relay
+}
+
+// Start inicia a goroutine de varredura periódica; encerra quando ctx é cancelado
+func (r *OutboxRelay) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Sweep(ctx)
+			}
+		}
+	}()
+}
+
+// Sweep executa uma única varredura - exposto separadamente do Start para permitir disparo
+// manual (ex: um endpoint administrativo ou um teste)
+func (r *OutboxRelay) Sweep(ctx context.Context) {
+	events, err := r.outbox.ClaimPendingEvents(ctx, r.batchSize)
+	if err != nil {
+		log.Printf("❌ [OUTBOX RELAY] failed to claim pending events: %v", err)
+		return
+	}
+
+	if r.backlog != nil {
+		r.backlog.Record(ctx, int64(len(events)))
+	}
+
+	for _, event := range events {
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			log.Printf("❌ [OUTBOX RELAY] failed to publish event id=%d OrderID=%s (attempt %d): %v",
+				event.ID, event.OrderID, event.FailedAttempts+1, err)
+			if recErr := r.outbox.RecordPublishFailure(ctx, event.ID); recErr != nil {
+				log.Printf("❌ [OUTBOX RELAY] failed to record publish failure for event id=%d: %v", event.ID, recErr)
+			}
+			if r.failed != nil {
+				r.failed.Add(ctx, 1)
+			}
+			continue
+		}
+
+		if err := r.outbox.MarkPublished(ctx, event.ID); err != nil {
+			log.Printf("❌ [OUTBOX RELAY] failed to mark event id=%d as published: %v", event.ID, err)
+			continue
+		}
+
+		if r.published != nil {
+			r.published.Add(ctx, 1)
+		}
+		log.Printf("📤 [OUTBOX RELAY] published event id=%d OrderID=%s EventType=%s", event.ID, event.OrderID, event.EventType)
+	}
+}
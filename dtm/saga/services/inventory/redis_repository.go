@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisInventoryRepository implementa InventoryRepository sobre pipelines transacionais do Redis
+// (MULTI/EXEC via Client.TxPipeline), selecionado por INVENTORY_STOCK_BACKEND=redis (ver main.go),
+// mesmo par com RedisPaymentRepository em dtm/saga/services/payments/redis_repository.go - compara
+// o lock pessimista do Postgres (FOR UPDATE) contra o pipeline do Redis sob o mesmo orquestrador
+// SAGA. Duplicado localmente em vez de um pkg/tx compartilhado: não há go.mod ligando
+// dtm/*/services/*.
+//
+// Estoque: hash "product:{productID}" com os campos stock/version. Idempotência de movimento:
+// SET em "movement:{orderID}:{type}". Chave de idempotência da própria SAGA (AcquireIdempotencyKey):
+// SETNX em "idempotency:{key}".
+type RedisInventoryRepository struct {
+	client *redis.Client
+
+	mu     sync.Mutex
+	pipes  map[int64]redis.Pipeliner
+	nextID int64
+}
+
+// NewRedisInventoryRepository cria um RedisInventoryRepository apoiado no client informado
+func NewRedisInventoryRepository(client *redis.Client) InventoryRepository {
+	return &RedisInventoryRepository{client: client, pipes: make(map[int64]redis.Pipeliner)}
+}
+
+// redisTx implementa Tx sobre um único redis.Pipeliner
+type redisTx struct {
+	ctx  context.Context
+	repo *RedisInventoryRepository
+	id   int64
+	pipe redis.Pipeliner
+	done bool
+}
+
+// BeginTx abre um novo pipeline transacional e o registra no índice interno, para que Commit/
+// Rollback o localizem pelo id sem precisar carregá-lo por fora
+func (r *RedisInventoryRepository) BeginTx(ctx context.Context) (Tx, error) {
+	id := atomic.AddInt64(&r.nextID, 1)
+	pipe := r.client.TxPipeline()
+
+	r.mu.Lock()
+	r.pipes[id] = pipe
+	r.mu.Unlock()
+
+	return &redisTx{ctx: ctx, repo: r, id: id, pipe: pipe}, nil
+}
+
+func (r *RedisInventoryRepository) release(id int64) {
+	r.mu.Lock()
+	delete(r.pipes, id)
+	r.mu.Unlock()
+}
+
+// Commit executa o pipeline (EXEC) e libera o índice interno
+func (t *redisTx) Commit() error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+	defer t.repo.release(t.id)
+
+	if _, err := t.pipe.Exec(t.ctx); err != nil {
+		return fmt.Errorf("failed to exec redis pipeline: %w", err)
+	}
+	return nil
+}
+
+// Rollback descarta os comandos enfileirados sem executá-los - seguro chamar depois de um Commit
+// bem-sucedido (no-op), para manter o padrão `defer tx.Rollback()` usado logo após BeginTx
+func (t *redisTx) Rollback() error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+	defer t.repo.release(t.id)
+
+	return t.pipe.Discard()
+}
+
+func productKey(productID string) string { return "product:" + productID }
+
+func movementKey(orderID, movementType string) string { return "movement:" + orderID + ":" + movementType }
+
+func redisIdempotencyKey(key string) string { return "idempotency:" + key }
+
+// GetProductInventory busca o inventário do produto
+func (r *RedisInventoryRepository) GetProductInventory(ctx context.Context, productID string) (*ProductInventory, error) {
+	vals, err := r.client.HMGet(ctx, productKey(productID), "stock", "version").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product inventory: %w", err)
+	}
+	if vals[0] == nil {
+		return nil, fmt.Errorf("product inventory not found for product %s", productID)
+	}
+
+	stock, _ := strconv.Atoi(fmt.Sprint(vals[0]))
+	version := 0
+	if vals[1] != nil {
+		version, _ = strconv.Atoi(fmt.Sprint(vals[1]))
+	}
+
+	return &ProductInventory{ID: productID, CurrentStock: stock, Version: version}, nil
+}
+
+// GetProductForUpdate não trava nada de fato - ao contrário do FOR UPDATE do Postgres, o ponto de
+// comparação deste backend é justamente não pagar o custo de um lock de linha e, em vez disso,
+// aplicar a mutação dentro do mesmo EXEC do pipeline (ver DecreaseStock/IncreaseStock) como a
+// garantia de atomicidade
+func (r *RedisInventoryRepository) GetProductForUpdate(ctx context.Context, tx Tx, productID string) (*ProductInventory, error) {
+	return r.GetProductInventory(ctx, productID)
+}
+
+// GetMovementByOrderIDAndType lê diretamente contra o client (fora do pipeline) para devolver uma
+// resposta imediata ao chamador antes de enfileirar a mutação, o mesmo papel que
+// GetProductForUpdate cumpre aqui
+func (r *RedisInventoryRepository) GetMovementByOrderIDAndType(ctx context.Context, tx Tx, orderID string, movementType string) (bool, error) {
+	exists, err := r.client.Exists(ctx, movementKey(orderID, movementType)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check movement record: %w", err)
+	}
+	return exists == 1, nil
+}
+
+// DecreaseStock enfileira a baixa de estoque e o registro do movimento no pipeline da tx - os
+// dois só são aplicados atomicamente quando Commit executa o EXEC
+func (r *RedisInventoryRepository) DecreaseStock(ctx context.Context, tx Tx, productID string, orderID string) error {
+	pipe := tx.(*redisTx).pipe
+	pipe.HIncrBy(ctx, productKey(productID), "stock", -1)
+	pipe.Set(ctx, movementKey(orderID, "decreased"), uuid.New().String(), 0)
+	return nil
+}
+
+// IncreaseStock é o equivalente de DecreaseStock para o aumento (compensação)
+func (r *RedisInventoryRepository) IncreaseStock(ctx context.Context, tx Tx, productID string, orderID string) error {
+	pipe := tx.(*redisTx).pipe
+	pipe.HIncrBy(ctx, productKey(productID), "stock", 1)
+	pipe.Set(ctx, movementKey(orderID, "increased"), uuid.New().String(), 0)
+	return nil
+}
+
+// AcquireIdempotencyKey roda um SETNX direto contra o client, fora do pipeline: os chamadores
+// (ver usecases.go) precisam do resultado (acquired) imediatamente para decidir se seguem com
+// a ação, o mesmo motivo pelo qual GetProductForUpdate também não enfileira no pipeline.
+func (r *RedisInventoryRepository) AcquireIdempotencyKey(ctx context.Context, tx Tx, key, operation, phase string) (bool, error) {
+	acquired, err := r.client.SetNX(ctx, redisIdempotencyKey(key),operation+":"+phase, idempotencyKeyTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire idempotency key %q: %w", key, err)
+	}
+	return acquired, nil
+}
+
+// initInventoryRepository monta o InventoryRepository configurado via INVENTORY_STOCK_BACKEND
+// ("postgres", o padrão, ou "redis"), mesmo padrão de initPaymentRepository em
+// dtm/saga/services/payments/redis_repository.go. Devolve uma função de shutdown a ser chamada
+// via defer em main(); no Postgres, é um no-op (o dbPool já é fechado separadamente em main()).
+func initInventoryRepository(dbPool *pgxpool.Pool) (InventoryRepository, func()) {
+	if getEnv("INVENTORY_STOCK_BACKEND", "postgres") != "redis" {
+		return NewInventoryRepository(dbPool), func() {}
+	}
+
+	opts, err := redis.ParseURL(getEnv("REDIS_URL", "redis://redis:6379/0"))
+	if err != nil {
+		log.Fatalf("Failed to parse REDIS_URL for inventory repository: %v", err)
+	}
+
+	client := redis.NewClient(opts)
+	return NewRedisInventoryRepository(client), func() {
+		if err := client.Close(); err != nil {
+			log.Printf("Error closing inventory repository redis client: %v", err)
+		}
+	}
+}
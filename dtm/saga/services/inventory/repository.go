@@ -17,9 +17,15 @@ type InventoryRepository interface {
 	DecreaseStock(ctx context.Context, tx Tx, productID string, orderID string) error
 	IncreaseStock(ctx context.Context, tx Tx, productID string, orderID string) error
 	BeginTx(ctx context.Context) (Tx, error)
+
+	// AcquireIdempotencyKey grava `key` na tabela idempotency_keys dentro da mesma transação de
+	// negócio, devolvendo acquired=false quando a chave já existia (reentrega já processada). Ver
+	// idempotency.go para o motivo da chave ser order_id+phase em vez de gid+branch_id+op.
+	AcquireIdempotencyKey(ctx context.Context, tx Tx, key, operation, phase string) (acquired bool, err error)
 }
 
-// Tx interface para transações
+// Tx abstrai o que o repositório precisa de uma transação de negócio (PostgresTx, que envolve um
+// pgx.Tx, e redisTx a implementam), para que AcquireIdempotencyKey e as operações de estoque não dependam do driver concreto.
 type Tx interface {
 	Commit() error
 	Rollback() error
@@ -72,6 +78,23 @@ func (r *PostgresInventoryRepository) GetMovementByOrderIDAndType(ctx context.Co
 	return exists, nil
 }
 
+// AcquireIdempotencyKey insere a chave com ON CONFLICT DO NOTHING; acquired=false sinaliza que a
+// chave já existia e portanto esta ação já foi processada em uma entrega anterior
+func (r *PostgresInventoryRepository) AcquireIdempotencyKey(ctx context.Context, tx Tx, key, operation, phase string) (bool, error) {
+	pgTx := tx.(*PostgresTx).tx
+
+	tag, err := pgTx.Exec(ctx, `
+		INSERT INTO idempotency_keys (key, operation, phase, created_at, expires_at)
+		VALUES ($1, $2, $3, NOW(), NOW() + $4 * INTERVAL '1 second')
+		ON CONFLICT (key) DO NOTHING
+	`, key, operation, phase, idempotencyKeyTTL.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire idempotency key %q: %w", key, err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
 // PostgresTx implementa a interface Tx
 type PostgresTx struct {
 	tx pgx.Tx
@@ -96,6 +119,10 @@ func (r *PostgresInventoryRepository) BeginTx(ctx context.Context) (Tx, error) {
 
 // GetProductForUpdate obtém o produto com lock pessimista (FOR UPDATE)
 func (r *PostgresInventoryRepository) GetProductForUpdate(ctx context.Context, tx Tx, productID string) (*ProductInventory, error) {
+	if err := maybeChaosDeadlock(chaosCfg); err != nil {
+		return nil, err
+	}
+
 	pgTx := tx.(*PostgresTx).tx
 
 	query := `
@@ -0,0 +1,198 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Nenhum fixture compartilhado: este arquivo sobe seu próprio testcontainers.ContainerRequest,
+// igual a payments/repository_integration_test.go - não há um go.mod comum que permitisse os dois
+// importarem um helper de um pacote testutil.
+//
+// inventoryTestSchema cobre o que PostgresInventoryRepository de fato lê/escreve, mais
+// ensureIdempotencyKeysTableSQL (ver idempotency.go, já a fonte da verdade para essa tabela).
+// Não há migrações versionadas neste repositório, então o schema de teste é montado a partir das
+// colunas referenciadas nas próprias queries de repository.go.
+const inventoryTestSchema = `
+CREATE TABLE IF NOT EXISTS products_inventory (
+	id            TEXT PRIMARY KEY,
+	current_stock INTEGER NOT NULL,
+	version       INTEGER NOT NULL DEFAULT 1,
+	created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS inventory_movements (
+	id              TEXT PRIMARY KEY,
+	inventory_id    TEXT NOT NULL REFERENCES products_inventory(id),
+	order_id        TEXT NOT NULL,
+	change_quantity INTEGER NOT NULL,
+	movement_type   TEXT NOT NULL,
+	created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+` + ensureIdempotencyKeysTableSQL
+
+// newInventoryTestPool sobe um PostgreSQL 16 efêmero e aplica inventoryTestSchema, sem depender
+// de um helper compartilhado: este serviço não tem um go.mod que permita importar um pacote de
+// fixtures de outro diretório como módulo.
+func newInventoryTestPool(ctx context.Context, t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	pgContainer, err := postgres.Run(ctx, "postgres:16-alpine",
+		postgres.WithDatabase("inventory_test"),
+		postgres.WithUsername("inventory_test"),
+		postgres.WithPassword("inventory_test"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(30*time.Second),
+		),
+	)
+	require.NoError(t, err, "failed to start postgres container")
+
+	dsn, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err, "failed to obtain postgres connection string")
+
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err, "failed to connect to test postgres container")
+
+	_, err = pool.Exec(ctx, inventoryTestSchema)
+	require.NoError(t, err, "failed to apply test schema")
+
+	t.Cleanup(func() {
+		pool.Close()
+		_ = pgContainer.Terminate(context.Background())
+	})
+
+	return pool
+}
+
+func seedProduct(ctx context.Context, t *testing.T, pool *pgxpool.Pool, productID string, stock int) {
+	t.Helper()
+
+	_, err := pool.Exec(ctx, `
+		INSERT INTO products_inventory (id, current_stock, version)
+		VALUES ($1, $2, 1)
+	`, productID, stock)
+	require.NoError(t, err, "failed to seed product")
+}
+
+// TestPostgresInventoryRepository_GetProductForUpdate_Serializes comprova que FOR UPDATE
+// serializa duas goroutines disputando o mesmo produto, do mesmo jeito que
+// TestPostgresPaymentRepository_GetWalletForUpdate_Serializes comprova para wallets.
+func TestPostgresInventoryRepository_GetProductForUpdate_Serializes(t *testing.T) {
+	ctx := context.Background()
+	pool := newInventoryTestPool(ctx, t)
+	repo := NewInventoryRepository(pool)
+
+	productID := "product-" + uuid.New().String()
+	seedProduct(ctx, t, pool, productID, 100)
+
+	firstHolds := make(chan struct{})
+	releaseFirst := make(chan struct{})
+	var secondAcquiredAt, firstReleasedAt time.Time
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		tx, err := repo.BeginTx(ctx)
+		require.NoError(t, err)
+		_, err = repo.GetProductForUpdate(ctx, tx, productID)
+		require.NoError(t, err)
+
+		close(firstHolds)
+		<-releaseFirst
+		firstReleasedAt = time.Now()
+		require.NoError(t, tx.Commit())
+	}()
+
+	go func() {
+		defer wg.Done()
+		<-firstHolds
+
+		tx, err := repo.BeginTx(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		close(releaseFirst)
+		_, err = repo.GetProductForUpdate(ctx, tx, productID)
+		require.NoError(t, err)
+		secondAcquiredAt = time.Now()
+	}()
+
+	wg.Wait()
+
+	assert.False(t, secondAcquiredAt.Before(firstReleasedAt),
+		"second GetProductForUpdate must not acquire the row before the first transaction commits")
+}
+
+// TestPostgresInventoryRepository_DecreaseStock_DuplicateCaughtByExistenceCheck comprova que uma
+// segunda tentativa de decrease para o mesmo order_id, em outra transação, é detectada por
+// GetMovementByOrderIDAndType antes de baixar o estoque de novo - o mesmo precheck que
+// InventoryUseCase.DecreaseStock já faz em produção.
+func TestPostgresInventoryRepository_DecreaseStock_DuplicateCaughtByExistenceCheck(t *testing.T) {
+	ctx := context.Background()
+	pool := newInventoryTestPool(ctx, t)
+	repo := NewInventoryRepository(pool)
+
+	productID := "product-" + uuid.New().String()
+	orderID := "order-" + uuid.New().String()
+	seedProduct(ctx, t, pool, productID, 100)
+
+	tx1, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.DecreaseStock(ctx, tx1, productID, orderID))
+	require.NoError(t, tx1.Commit())
+
+	tx2, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	defer tx2.Rollback()
+
+	exists, err := repo.GetMovementByOrderIDAndType(ctx, tx2, orderID, "decreased")
+	require.NoError(t, err)
+	assert.True(t, exists, "duplicate decrease attempt for the same order_id must be caught by GetMovementByOrderIDAndType")
+
+	inventory, err := repo.GetProductInventory(ctx, productID)
+	require.NoError(t, err)
+	assert.Equal(t, 99, inventory.CurrentStock, "stock must only be decreased once across both attempts")
+}
+
+// TestPostgresInventoryRepository_Rollback_LeavesMovementsEmpty comprova que um Rollback
+// explícito não deixa nenhum rastro em inventory_movements, nem a atualização de estoque em
+// products_inventory.
+func TestPostgresInventoryRepository_Rollback_LeavesMovementsEmpty(t *testing.T) {
+	ctx := context.Background()
+	pool := newInventoryTestPool(ctx, t)
+	repo := NewInventoryRepository(pool)
+
+	productID := "product-" + uuid.New().String()
+	orderID := "order-" + uuid.New().String()
+	seedProduct(ctx, t, pool, productID, 100)
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.DecreaseStock(ctx, tx, productID, orderID))
+	require.NoError(t, tx.Rollback())
+
+	var count int
+	require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM inventory_movements WHERE order_id = $1`, orderID).Scan(&count))
+	assert.Equal(t, 0, count, "inventory_movements must be empty after a rollback")
+
+	inventory, err := repo.GetProductInventory(ctx, productID)
+	require.NoError(t, err)
+	assert.Equal(t, 100, inventory.CurrentStock, "stock must be unchanged after a rollback")
+}
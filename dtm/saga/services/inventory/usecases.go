@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 
@@ -15,24 +16,48 @@ var (
 // InventoryUseCase contém a lógica de negócio do inventário
 type InventoryUseCase struct {
 	repository InventoryRepository
+	outbox     OutboxRepository
 	tracer     trace.Tracer
 }
 
 // NewInventoryUseCase cria uma nova instância de InventoryUseCase
 func NewInventoryUseCase(
 	repository InventoryRepository,
+	outbox OutboxRepository,
 	tracer trace.Tracer,
 ) *InventoryUseCase {
 	return &InventoryUseCase{
 		repository: repository,
+		outbox:     outbox,
 		tracer:     tracer,
 	}
 }
 
+// enqueueOutboxEvent grava no outbox transacional, dentro da mesma tx de negócio, um evento
+// descrevendo a mudança de estoque que acabou de ser aplicada. uc.outbox é opcional (nil em
+// testes/ambientes que ainda não o configuram) para não quebrar chamadores existentes.
+func (uc *InventoryUseCase) enqueueOutboxEvent(ctx context.Context, tx Tx, req SagaActionRequest, eventType string) error {
+	if uc.outbox == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	event := &OutboxEvent{
+		OrderID:   req.OrderID,
+		EventType: eventType,
+		Payload:   payload,
+	}
+	return uc.outbox.EnqueueOutboxEvent(ctx, tx, event)
+}
+
 // DecreaseStock diminui o estoque usando Lock Pessimista
 func (uc *InventoryUseCase) DecreaseStock(ctx context.Context, req SagaActionRequest) error {
-	log.Printf("➡️ [DECREASE STOCK] TraceID: %s | OrderID: %s | ProductID: %s",
-		req.TraceID, req.OrderID, req.ProductID)
+	log.Printf("➡️ [DECREASE STOCK] OrderID: %s | ProductID: %s",
+		req.OrderID, req.ProductID)
 
 	// 1. Inicia a transação
 	tx, err := uc.repository.BeginTx(ctx)
@@ -41,7 +66,18 @@ func (uc *InventoryUseCase) DecreaseStock(ctx context.Context, req SagaActionReq
 	}
 	defer tx.Rollback()
 
-	// 2. Obtém o produto com LOCK PESSIMISTA (SELECT FOR UPDATE)
+	// 2. Adquire a chave de idempotência dentro da própria transação - se já existia, esta
+	// entrega já foi processada (ver idempotency.go para o porquê da duplicação local)
+	acquired, err := uc.repository.AcquireIdempotencyKey(ctx, tx, idempotencyKey(req.OrderID, "decrease"), "inventory.decrease_stock", "decrease")
+	if err != nil {
+		return fmt.Errorf("erro ao verificar idempotência: %w", err)
+	}
+	if !acquired {
+		log.Printf("ℹ️  [IDEMPOTENCY] key já processada para OrderID=%s phase=decrease", req.OrderID)
+		return nil
+	}
+
+	// 3. Obtém o produto com LOCK PESSIMISTA (SELECT FOR UPDATE)
 	// Isso bloqueia a linha no banco até o Commit ou Rollback
 	product, err := uc.repository.GetProductForUpdate(ctx, tx, req.ProductID)
 	if err != nil {
@@ -49,7 +85,7 @@ func (uc *InventoryUseCase) DecreaseStock(ctx context.Context, req SagaActionReq
 		return err
 	}
 
-	// 3. Verificar idempotência dentro da transação
+	// 4. Verificar idempotência dentro da transação
 	exists, err := uc.repository.GetMovementByOrderIDAndType(ctx, tx, req.OrderID, "decreased")
 	if err != nil {
 		return fmt.Errorf("error to check idempotency: %w", err)
@@ -60,20 +96,26 @@ func (uc *InventoryUseCase) DecreaseStock(ctx context.Context, req SagaActionReq
 		return nil // Retorna sucesso para manter idempotência
 	}
 
-	// 4. Regra de Negócio: Verifica estoque
+	// 5. Regra de Negócio: Verifica estoque
 	if product.CurrentStock < 1 {
 		log.Printf("❌ DECREASE FAILED: Insufficient stock | ProductID=%s", req.ProductID)
 		return fmt.Errorf("insufficient stock for product %s", req.ProductID)
 	}
 
-	// 5. Executa a atualização do estoque e cria o registro de movimento
+	// 6. Executa a atualização do estoque e cria o registro de movimento
 	// Como estamos com Lock Pessimista, não precisamos checar 'version' no WHERE
 	if err := uc.repository.DecreaseStock(ctx, tx, req.ProductID, req.OrderID); err != nil {
 		log.Printf("❌ [DECREASE] | OrderID=%s Failed to update: %v", req.OrderID, err)
 		return err
 	}
 
-	// 6. Commit da transação
+	// 6.1 Registra o evento no outbox transacional, na mesma transação do estoque
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, "inventory.decreased"); err != nil {
+		log.Printf("❌ [DECREASE] | OrderID=%s Failed to enqueue outbox event: %v", req.OrderID, err)
+		return err
+	}
+
+	// 7. Commit da transação
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("erro ao comitar decrease: %w", err)
 	}
@@ -84,8 +126,8 @@ func (uc *InventoryUseCase) DecreaseStock(ctx context.Context, req SagaActionReq
 
 // CompensateStock aumenta o estoque de volta (compensação) com idempotência e lock pessimista
 func (uc *InventoryUseCase) CompensateStock(ctx context.Context, req SagaActionRequest) error {
-	log.Printf("↩️ [COMPENSATE STOCK] TraceID: %s | OrderID: %s | ProductID: %s",
-		req.TraceID, req.OrderID, req.ProductID)
+	log.Printf("↩️ [COMPENSATE STOCK] OrderID: %s | ProductID: %s",
+		req.OrderID, req.ProductID)
 
 	// 1. Inicia a transação
 	tx, err := uc.repository.BeginTx(ctx)
@@ -94,14 +136,24 @@ func (uc *InventoryUseCase) CompensateStock(ctx context.Context, req SagaActionR
 	}
 	defer tx.Rollback()
 
-	// 2. Obtém o produto com LOCK PESSIMISTA (SELECT FOR UPDATE)
+	// 2. Adquire a chave de idempotência dentro da própria transação
+	acquired, err := uc.repository.AcquireIdempotencyKey(ctx, tx, idempotencyKey(req.OrderID, "compensate"), "inventory.compensate_stock", "compensate")
+	if err != nil {
+		return fmt.Errorf("erro ao verificar idempotência: %w", err)
+	}
+	if !acquired {
+		log.Printf("ℹ️  [IDEMPOTENCY] key já processada para OrderID=%s phase=compensate", req.OrderID)
+		return nil
+	}
+
+	// 3. Obtém o produto com LOCK PESSIMISTA (SELECT FOR UPDATE)
 	_, err = uc.repository.GetProductForUpdate(ctx, tx, req.ProductID)
 	if err != nil {
 		log.Printf("❌ COMPENSATE FAILED: GetProductForUpdate | OrderID=%s | Error=%v", req.OrderID, err)
 		return err
 	}
 
-	// 3. Verificar idempotência - se já existe movimento de 'increased' para este order_id
+	// 4. Verificar idempotência - se já existe movimento de 'increased' para este order_id
 	exists, err := uc.repository.GetMovementByOrderIDAndType(ctx, tx, req.OrderID, "increased")
 	if err != nil {
 		return fmt.Errorf("erro ao verificar idempotência: %w", err)
@@ -112,13 +164,19 @@ func (uc *InventoryUseCase) CompensateStock(ctx context.Context, req SagaActionR
 		return nil
 	}
 
-	// 4. Executa a compensação (aumento) e cria o registro de movimento
+	// 5. Executa a compensação (aumento) e cria o registro de movimento
 	if err := uc.repository.IncreaseStock(ctx, tx, req.ProductID, req.OrderID); err != nil {
 		log.Printf("❌ [COMPENSATE] | OrderID=%s Failed to update: %v", req.OrderID, err)
 		return err
 	}
 
-	// 5. Commit da transação
+	// 5.1 Registra o evento no outbox transacional, na mesma transação do estoque
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, "inventory.increased"); err != nil {
+		log.Printf("❌ [COMPENSATE] | OrderID=%s Failed to enqueue outbox event: %v", req.OrderID, err)
+		return err
+	}
+
+	// 6. Commit da transação
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("erro ao comitar compensação: %w", err)
 	}
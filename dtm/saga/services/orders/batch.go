@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// batchWorkerPoolSize limita o número de SAGAs submetidas simultaneamente por batch
+	batchWorkerPoolSize = 8
+
+	// defaultBatchRetryMaxAttempts é o número máximo de tentativas de submissão por item antes de
+	// desistir, usado quando BATCH_RETRY_MAX_ATTEMPTS não está definida
+	defaultBatchRetryMaxAttempts = 3
+
+	// defaultBatchRetryBaseDelay é o atraso inicial do backoff exponencial entre tentativas, usado
+	// quando BATCH_RETRY_BASE_MS não está definida
+	defaultBatchRetryBaseDelay = 200 * time.Millisecond
+
+	// defaultBatchRetryJitter é a fração (0-1) do atraso calculado sorteada aleatoriamente, usada
+	// quando BATCH_RETRY_JITTER não está definida - evita que todos os itens com falha de um
+	// mesmo batch colidam no mesmo instante de retentativa
+	defaultBatchRetryJitter = 0.3
+
+	// defaultBatchRetryMaxDelay limita o backoff exponencial, usado quando BATCH_RETRY_MAX_MS não
+	// está definida - sem teto, BATCH_RETRY_MAX_ATTEMPTS alto faria BatchPlaceOrders dormir por
+	// dezenas de minutos na goroutine da própria requisição HTTP
+	defaultBatchRetryMaxDelay = 5 * time.Second
+)
+
+// batchRetryPolicy controla a retentativa de BatchPlaceOrders abaixo, reduzida aos campos que a
+// retentativa de submissão de batch usa.
+type batchRetryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	jitter      float64
+}
+
+// batchRetryPolicyFromEnv lê BATCH_RETRY_MAX_ATTEMPTS, BATCH_RETRY_BASE_MS, BATCH_RETRY_MAX_MS e
+// BATCH_RETRY_JITTER do ambiente, caindo para os defaults acima quando ausentes ou inválidas
+func batchRetryPolicyFromEnv() batchRetryPolicy {
+	p := batchRetryPolicy{
+		maxAttempts: defaultBatchRetryMaxAttempts,
+		baseDelay:   defaultBatchRetryBaseDelay,
+		maxDelay:    defaultBatchRetryMaxDelay,
+		jitter:      defaultBatchRetryJitter,
+	}
+
+	if v, err := strconv.Atoi(getEnv("BATCH_RETRY_MAX_ATTEMPTS", "")); err == nil && v > 0 {
+		p.maxAttempts = v
+	}
+	if v, err := strconv.Atoi(getEnv("BATCH_RETRY_BASE_MS", "")); err == nil && v > 0 {
+		p.baseDelay = time.Duration(v) * time.Millisecond
+	}
+	if v, err := strconv.Atoi(getEnv("BATCH_RETRY_MAX_MS", "")); err == nil && v > 0 {
+		p.maxDelay = time.Duration(v) * time.Millisecond
+	}
+	if v, err := strconv.ParseFloat(getEnv("BATCH_RETRY_JITTER", ""), 64); err == nil && v >= 0 && v <= 1 {
+		p.jitter = v
+	}
+
+	return p
+}
+
+// delayForAttempt calcula o backoff exponencial para `attempt` (1-based, relativo à primeira
+// retentativa), limitado por maxDelay, com jitter aleatório de +/- p.jitter aplicado sobre o
+// valor calculado
+func (p batchRetryPolicy) delayForAttempt(attempt int) time.Duration {
+	// Cap the shift itself: past ~20 attempts 1<<(attempt-1) already dwarfs any sane maxDelay, and
+	// letting the shift run unchecked into the 60s would overflow the signed int64 Duration into a
+	// negative/garbage value, silently defeating the maxDelay cap below.
+	shift := attempt - 1
+	if shift > 20 {
+		shift = 20
+	}
+	base := p.baseDelay * time.Duration(1<<uint(shift))
+	if base > p.maxDelay {
+		base = p.maxDelay
+	}
+	if p.jitter <= 0 {
+		return base
+	}
+
+	spread := float64(base) * p.jitter
+	offset := (rand.Float64()*2 - 1) * spread
+	return base + time.Duration(offset)
+}
+
+// OrderResult representa o resultado individual de um item processado via /api/orders/batch,
+// no mesmo formato usado pelo endpoint equivalente do serviço TCC
+type OrderResult struct {
+	Index   int    `json:"index"`
+	OrderID string `json:"order_id,omitempty"`
+	GID     string `json:"gid,omitempty"`
+	TraceID string `json:"trace_id,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BatchPlaceOrders submete em paralelo (worker pool limitado) a SAGA de cada item do batch e
+// retorna um OrderResult por posição, preservando a ordem de entrada. Itens com falha na
+// submissão são automaticamente re-tentados com backoff exponencial. Assim como no serviço TCC
+// (ver dtm/tcc/services/orders/batch.go), o span do chamador recebe um evento por item com o GID
+// registrado em vez de um trace.Link, já que os GIDs só existem depois da submissão de cada item.
+func (uc *OrderUseCase) BatchPlaceOrders(ctx context.Context, reqs []CreateOrderRequest) ([]OrderResult, error) {
+	start := time.Now()
+	results := make([]OrderResult, len(reqs))
+	policy := batchRetryPolicyFromEnv()
+
+	uc.placeOrdersConcurrently(ctx, reqs, results, allIndexes(len(reqs)))
+
+	for attempt := 1; attempt < policy.maxAttempts; attempt++ {
+		failed := failedIndexes(results)
+		if len(failed) == 0 {
+			break
+		}
+
+		delay := policy.delayForAttempt(attempt)
+		log.Printf("🔁 [BATCH] retrying %d failed order(s), attempt=%d, delay=%s", len(failed), attempt+1, delay)
+		time.Sleep(delay)
+
+		uc.placeOrdersConcurrently(ctx, reqs, results, failed)
+	}
+
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		for _, r := range results {
+			if r.GID == "" {
+				continue
+			}
+			span.AddEvent("saga.batch.item_submitted", trace.WithAttributes(
+				attribute.String("saga.order_id", r.OrderID),
+				attribute.String("saga.gid", r.GID),
+				attribute.String("saga.status", r.Status),
+			))
+		}
+	}
+
+	uc.batchMetrics.RecordBatch(ctx, len(reqs), len(failedIndexes(results)), time.Since(start))
+
+	return results, nil
+}
+
+// placeOrdersConcurrently processa os índices informados com um worker pool limitado, gravando
+// cada resultado na posição correspondente do slice `results`
+func (uc *OrderUseCase) placeOrdersConcurrently(ctx context.Context, reqs []CreateOrderRequest, results []OrderResult, indexes []int) {
+	sem := make(chan struct{}, batchWorkerPoolSize)
+	var wg sync.WaitGroup
+
+	for _, i := range indexes {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			orderID, gid, traceID, err := uc.CreateOrderSaga(ctx, reqs[i])
+			if err != nil {
+				results[i] = OrderResult{Index: i, OrderID: orderID, GID: gid, TraceID: traceID, Status: "failed", Error: err.Error()}
+				return
+			}
+
+			results[i] = OrderResult{Index: i, OrderID: orderID, GID: gid, TraceID: traceID, Status: "submitted"}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func allIndexes(n int) []int {
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	return indexes
+}
+
+func failedIndexes(results []OrderResult) []int {
+	var indexes []int
+	for i, r := range results {
+		if r.Status == "failed" {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// batchMetrics agrupa os instrumentos emitidos por BatchPlaceOrders, separados de dtxMetrics por
+// descreverem o lote como um todo (tamanho, falhas residuais, latência ponta a ponta), não uma
+// fase/branch individual
+type batchMetrics struct {
+	size     metric.Int64Histogram
+	failures metric.Int64Counter
+	latency  metric.Float64Histogram
+}
+
+// newBatchMetrics registra orders.batch.size/orders.batch.failures/orders.batch.latency no meter
+// informado
+func newBatchMetrics(meter metric.Meter) (*batchMetrics, error) {
+	size, err := meter.Int64Histogram(
+		"orders.batch.size",
+		metric.WithDescription("Number of items submitted per batch order creation request"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create orders.batch.size histogram: %w", err)
+	}
+
+	failures, err := meter.Int64Counter(
+		"orders.batch.failures",
+		metric.WithDescription("Number of items that remained failed after all batch retry attempts"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create orders.batch.failures counter: %w", err)
+	}
+
+	latency, err := meter.Float64Histogram(
+		"orders.batch.latency",
+		metric.WithDescription("End-to-end duration of a batch order creation request, including retries"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create orders.batch.latency histogram: %w", err)
+	}
+
+	return &batchMetrics{size: size, failures: failures, latency: latency}, nil
+}
+
+// RecordBatch registra o tamanho, as falhas residuais e a duração de um BatchPlaceOrders
+func (m *batchMetrics) RecordBatch(ctx context.Context, size, failures int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.size.Record(ctx, int64(size))
+	m.failures.Add(ctx, int64(failures))
+	m.latency.Record(ctx, duration.Seconds())
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Mesmo desenho de dtm/tcc/services/payment/chaos.go e dtm/saga/services/{inventory,payments}/chaos.go,
+// duplicado localmente aqui. Sem deadlockRate/maybeChaosDeadlock: ao contrário de inventory
+// (GetProductForUpdate) e payments (GetWalletForUpdate), orders não faz SELECT ... FOR UPDATE em
+// nenhuma branch, então não há um ponto de injeção de deadlock realista aqui.
+type chaosConfig struct {
+	mu sync.RWMutex
+
+	enabled bool
+
+	latencyMsP50 int
+	latencyMsP99 int
+
+	httpErrorRate float64
+	connDropRate  float64
+
+	// phases restringe a injeção a fases específicas ("create", "complete", "compensate"). Um
+	// mapa vazio significa "todas as fases"
+	phases map[string]bool
+}
+
+var chaosCfg = newChaosConfigFromEnv()
+
+func newChaosConfigFromEnv() *chaosConfig {
+	return &chaosConfig{
+		enabled:       os.Getenv("CHAOS_ENABLED") == "true",
+		latencyMsP50:  chaosEnvInt("CHAOS_LATENCY_MS_P50", 0),
+		latencyMsP99:  chaosEnvInt("CHAOS_LATENCY_MS_P99", 0),
+		httpErrorRate: chaosEnvFloat("CHAOS_HTTP_ERROR_RATE", 0),
+		connDropRate:  chaosEnvFloat("CHAOS_CONN_DROP_RATE", 0),
+		phases:        chaosEnvPhases("CHAOS_PHASES"),
+	}
+}
+
+type chaosUpdateRequest struct {
+	Enabled       *bool           `json:"enabled,omitempty"`
+	LatencyMsP50  *int            `json:"latency_ms_p50,omitempty"`
+	LatencyMsP99  *int            `json:"latency_ms_p99,omitempty"`
+	HTTPErrorRate *float64        `json:"http_error_rate,omitempty"`
+	ConnDropRate  *float64        `json:"conn_drop_rate,omitempty"`
+	Phases        map[string]bool `json:"phases,omitempty"`
+}
+
+func (c *chaosConfig) update(req chaosUpdateRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if req.Enabled != nil {
+		c.enabled = *req.Enabled
+	}
+	if req.LatencyMsP50 != nil {
+		c.latencyMsP50 = *req.LatencyMsP50
+	}
+	if req.LatencyMsP99 != nil {
+		c.latencyMsP99 = *req.LatencyMsP99
+	}
+	if req.HTTPErrorRate != nil {
+		c.httpErrorRate = *req.HTTPErrorRate
+	}
+	if req.ConnDropRate != nil {
+		c.connDropRate = *req.ConnDropRate
+	}
+	if req.Phases != nil {
+		c.phases = req.Phases
+	}
+}
+
+func (c *chaosConfig) snapshot() chaosUpdateRequest {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	enabled, p50, p99, httpRate, connRate := c.enabled, c.latencyMsP50, c.latencyMsP99, c.httpErrorRate, c.connDropRate
+	return chaosUpdateRequest{
+		Enabled:       &enabled,
+		LatencyMsP50:  &p50,
+		LatencyMsP99:  &p99,
+		HTTPErrorRate: &httpRate,
+		ConnDropRate:  &connRate,
+		Phases:        c.phases,
+	}
+}
+
+func (c *chaosConfig) phaseEnabled(phase string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.phases) == 0 {
+		return true
+	}
+	return c.phases[phase]
+}
+
+func (c *chaosConfig) snapshotRates() (enabled bool, p50, p99 int, httpRate, connRate float64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.enabled, c.latencyMsP50, c.latencyMsP99, c.httpErrorRate, c.connDropRate
+}
+
+// chaosMiddleware injeta latência, erros HTTP e quedas de conexão nas ações SAGA deste serviço,
+// respeitando o toggle por fase (create/complete/compensate) configurado em chaosConfig.phases
+func chaosMiddleware(cfg *chaosConfig, phase string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		enabled, p50, p99, httpRate, connRate := cfg.snapshotRates()
+		if !enabled || !cfg.phaseEnabled(phase) {
+			c.Next()
+			return
+		}
+
+		if p50 > 0 || p99 > 0 {
+			time.Sleep(chaosRandomLatency(p50, p99))
+		}
+
+		if httpRate > 0 && rand.Float64() < httpRate {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "chaos: injected failure"})
+			return
+		}
+
+		if connRate > 0 && rand.Float64() < connRate {
+			hijacker, ok := c.Writer.(http.Hijacker)
+			if ok {
+				conn, _, err := hijacker.Hijack()
+				if err == nil {
+					conn.Close()
+					c.Abort()
+					return
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func chaosRandomLatency(p50, p99 int) time.Duration {
+	if p99 <= p50 {
+		return time.Duration(p50) * time.Millisecond
+	}
+	ms := p50 + rand.Intn(p99-p50+1)
+	return time.Duration(ms) * time.Millisecond
+}
+
+// chaosAdminHandler expõe POST/GET /admin/chaos para ajustar a injeção de falhas em tempo real
+func chaosAdminHandler(cfg *chaosConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet {
+			c.JSON(http.StatusOK, cfg.snapshot())
+			return
+		}
+
+		var req chaosUpdateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chaos config: " + err.Error()})
+			return
+		}
+
+		cfg.update(req)
+		c.JSON(http.StatusOK, cfg.snapshot())
+	}
+}
+
+func chaosEnvInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+func chaosEnvFloat(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fallback
+	}
+	return f
+}
+
+func chaosEnvPhases(key string) map[string]bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+	phases := make(map[string]bool)
+	for _, p := range strings.Split(v, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			phases[p] = true
+		}
+	}
+	return phases
+}
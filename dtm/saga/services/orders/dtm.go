@@ -4,41 +4,56 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/dtm-labs/client/dtmcli"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
 // SagaOrchestrator abstrai as operações SAGA do DTM
 type SagaOrchestrator interface {
-	CreateOrderSaga(ctx context.Context, req CreateOrderRequest) (string, string, error)
+	CreateOrderSaga(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error)
 }
 
 // DTMSagaOrchestrator implementa SagaOrchestrator usando DTM
-type DTMSagaOrchestrator struct{}
+type DTMSagaOrchestrator struct {
+	metrics  *dtxMetrics
+	tracking TransactionTrackingRepository
+	events   txEventsPublisher
+}
 
 // NewDTMSagaOrchestrator cria uma nova instância do orquestrador SAGA
-func NewDTMSagaOrchestrator() *DTMSagaOrchestrator {
-	return &DTMSagaOrchestrator{}
+func NewDTMSagaOrchestrator(metrics *dtxMetrics, tracking TransactionTrackingRepository, events txEventsPublisher) *DTMSagaOrchestrator {
+	return &DTMSagaOrchestrator{metrics: metrics, tracking: tracking, events: events}
 }
 
 // CreateOrderSaga orquestra a transação SAGA
-func (so *DTMSagaOrchestrator) CreateOrderSaga(ctx context.Context, req CreateOrderRequest) (string, string, error) {
-	orderID := uuid.New().String()
+func (so *DTMSagaOrchestrator) CreateOrderSaga(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error) {
+	start := time.Now()
+	so.metrics.AddInflight(ctx, "saga", 1)
+	defer so.metrics.AddInflight(ctx, "saga", -1)
+
+	orderID = uuid.New().String()
+
+	// Injeta o trace context atual como o par traceparent/tracestate do W3C Trace Context, já
+	// que o DTM chama as branches diretamente e não repassa os headers HTTP desta requisição
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	traceparent := carrier.Get("traceparent")
+	tracestate := carrier.Get("tracestate")
 
-	// Extract trace context from the incoming context
-	var traceID, spanID string
 	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
 		traceID = span.SpanContext().TraceID().String()
-		spanID = span.SpanContext().SpanID().String()
 	}
 
 	defer func() {
 		if r := recover(); r != nil {
 		}
 	}()
-	gid := dtmcli.MustGenGid(getEnv("DTM_SERVER", "http://dtm:36789/api/dtmsvr"))
+	gid = dtmcli.MustGenGid(getEnv("DTM_SERVER", "http://dtm:36789/api/dtmsvr"))
 
 	log.Printf("🚀 Starting SAGA | TraceID: %s | GID: %s | OrderID: %s", traceID, gid, orderID)
 
@@ -47,62 +62,89 @@ func (so *DTMSagaOrchestrator) CreateOrderSaga(ctx context.Context, req CreateOr
 			getEnv("SERVICE_URL", "http://orders-service:8080")+"/api/orders/create",
 			getEnv("SERVICE_URL", "http://orders-service:8080")+"/api/orders/compensate",
 			&SagaActionRequest{
-				OrderID:   orderID,
-				UserID:    req.UserID,
-				ProductID: req.ProductID,
-				Amount:    req.Amount,
-				TraceID:   traceID,
-				SpanID:    spanID,
+				OrderID:     orderID,
+				UserID:      req.UserID,
+				ProductID:   req.ProductID,
+				Amount:      req.Amount,
+				BranchID:    0,
+				Traceparent: traceparent,
+				Tracestate:  tracestate,
 			},
 		).
 		Add(
 			getEnv("INVENTORY_SERVICE_URL", "http://inventory-service:8080")+"/api/inventory/decrease",
 			getEnv("INVENTORY_SERVICE_URL", "http://inventory-service:8080")+"/api/inventory/compensate",
 			&SagaActionRequest{
-				OrderID:   orderID,
-				UserID:    req.UserID,
-				ProductID: req.ProductID,
-				Amount:    req.Amount,
-				TraceID:   traceID,
-				SpanID:    spanID,
+				OrderID:     orderID,
+				UserID:      req.UserID,
+				ProductID:   req.ProductID,
+				Amount:      req.Amount,
+				BranchID:    1,
+				Traceparent: traceparent,
+				Tracestate:  tracestate,
 			},
 		).
 		Add(
 			getEnv("PAYMENTS_SERVICE_URL", "http://payments-service:8080")+"/api/payments/debit",
 			getEnv("PAYMENTS_SERVICE_URL", "http://payments-service:8080")+"/api/payments/compensate",
 			&SagaActionRequest{
-				OrderID:   orderID,
-				UserID:    req.UserID,
-				ProductID: req.ProductID,
-				Amount:    req.Amount,
-				TraceID:   traceID,
-				SpanID:    spanID,
+				OrderID:     orderID,
+				UserID:      req.UserID,
+				ProductID:   req.ProductID,
+				Amount:      req.Amount,
+				BranchID:    2,
+				Traceparent: traceparent,
+				Tracestate:  tracestate,
 			},
 		).
 		Add(
 			getEnv("SERVICE_URL", "http://orders-service:8080")+"/api/orders/complete",
 			"",
 			&SagaActionRequest{
-				OrderID:   orderID,
-				UserID:    req.UserID,
-				ProductID: req.ProductID,
-				Amount:    req.Amount,
-				TraceID:   traceID,
-				SpanID:    spanID,
+				OrderID:     orderID,
+				UserID:      req.UserID,
+				ProductID:   req.ProductID,
+				Amount:      req.Amount,
+				BranchID:    3,
+				Traceparent: traceparent,
+				Tracestate:  tracestate,
 			},
 		)
 
-	// saga.WithRetryLimit(30)
-	// saga.RetryInterval = 60
+	// Política de retry do DTM para as branches desta SAGA (comentada antes porque os valores
+	// fixos de 30 tentativas / 60s não tinham como ser ajustados sem recompilar - ver
+	// sagaRetryPolicyFromEnv e RETRY_MAX_ATTEMPTS/RETRY_BASE_MS no ambiente)
+	retryPolicy := sagaRetryPolicyFromEnv()
+	saga.WithRetryLimit(retryPolicy.maxAttempts)
+	saga.RetryInterval = retryPolicy.intervalSeconds
 
-	err := saga.Submit()
+	err = saga.Submit()
 
+	// A submissão apenas registra a SAGA no DTM - a execução das branches acontece de forma
+	// assíncrona, então "outcome" aqui reflete o aceite da submissão, não o desfecho final da
+	// SAGA (commit/compensate acontecem depois, fora deste método)
 	if err != nil {
+		so.metrics.RecordTransaction(ctx, "saga", "submit_failed", time.Since(start))
 		log.Printf("❌ SAGA failed: %v", err)
-		return orderID, gid, fmt.Errorf("failed to process order: %w", err)
+		return orderID, gid, traceID, fmt.Errorf("failed to process order: %w", err)
 	}
 
+	so.metrics.RecordTransaction(ctx, "saga", "submitted", time.Since(start))
 	log.Printf("✅ SAGA submitted successfully - GID: %s, OrderID: %s", gid, orderID)
 
-	return orderID, gid, nil
+	// Grava o rastreamento logo após o Submit, não no desfecho final (que é assíncrono e
+	// acontece fora deste método) - é o que permite ao endpoint de tracking responder mesmo
+	// depois do DTM já ter feito GC do registro original da transação
+	if err := so.tracking.RecordTracking(ctx, &TransactionTracking{
+		GID:      gid,
+		OrderID:  orderID,
+		TraceID:  traceID,
+		Protocol: "SAGA",
+	}); err != nil {
+		log.Printf("⚠️ [TRACKING] failed to record transaction tracking | GID: %s | Error: %v", gid, err)
+	}
+
+	emitTxEvent(ctx, so.events, "saga.submitted", gid, orderID, req.UserID, req.ProductID, start)
+
+	return orderID, gid, traceID, nil
 }
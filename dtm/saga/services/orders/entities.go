@@ -38,6 +38,10 @@ func (o *Order) Fail() error {
 	return nil
 }
 
+// ErrNoChange sinaliza que o status já era o alvo (ex: retry do DTM após o commit) e a escrita
+// foi pulada, em vez de tratado como falha
+var ErrNoChange = errors.New("no change: order status already matches target")
+
 // OrderStatus representa os possíveis status de um pedido
 const (
 	OrderStatusPending   = "pending"
@@ -2,32 +2,41 @@ package main
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
 // OrderUseCaseInterface define a interface para o use case
 type OrderUseCaseInterface interface {
-	CreateOrderSaga(ctx context.Context, req CreateOrderRequest) (string, string, error)
+	CreateOrderSaga(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error)
 	CreateOrder(ctx context.Context, req SagaActionRequest) error
 	CompleteOrder(ctx context.Context, req SagaActionRequest) error
 	CancelOrder(ctx context.Context, req SagaActionRequest) error
+	GetTransactionTracking(ctx context.Context, gid string) (*TransactionStatusResponse, error)
+	BatchPlaceOrders(ctx context.Context, reqs []CreateOrderRequest) ([]OrderResult, error)
 }
 
 // OrderHandler contém os handlers HTTP
 type OrderHandler struct {
 	useCase OrderUseCaseInterface
 	tracer  trace.Tracer
+	metrics *dtxMetrics
+	events  txEventsPublisher
 }
 
 // NewOrderHandler cria uma nova instância de OrderHandler
-func NewOrderHandler(useCase OrderUseCaseInterface, tracer trace.Tracer) *OrderHandler {
+func NewOrderHandler(useCase OrderUseCaseInterface, tracer trace.Tracer, metrics *dtxMetrics, events txEventsPublisher) *OrderHandler {
 	return &OrderHandler{
 		useCase: useCase,
 		tracer:  tracer,
+		metrics: metrics,
+		events:  events,
 	}
 }
 
@@ -56,7 +65,7 @@ func (h *OrderHandler) CreateOrderSaga(c *gin.Context) {
 		attribute.String("component", "dtm-coordinator"),
 	)
 
-	orderID, gid, err := h.useCase.CreateOrderSaga(ctxDTM, req)
+	orderID, gid, _, err := h.useCase.CreateOrderSaga(ctxDTM, req)
 
 	if err != nil {
 		spanDTM.RecordError(err)
@@ -84,6 +93,38 @@ func (h *OrderHandler) CreateOrderSaga(c *gin.Context) {
 	})
 }
 
+// CreateOrderBatch inicia em paralelo uma SAGA por item de reqs, retornando um OrderResult por
+// posição. Usado pelo harness de benchmark para comparar throughput entre TCC/SAGA/XA sem
+// precisar disparar N goroutines por fora contra /api/orders.
+func (h *OrderHandler) CreateOrderBatch(c *gin.Context) {
+	ctx, span := h.tracer.Start(c.Request.Context(), "create_order_saga_batch")
+	defer span.End()
+
+	var reqs []CreateOrderRequest
+	if err := c.ShouldBindJSON(&reqs); err != nil {
+		span.RecordError(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	span.SetAttributes(attribute.Int("saga.batch.size", len(reqs)))
+
+	results, err := h.useCase.BatchPlaceOrders(ctx, reqs)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "batch order submission failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// trace_id no nível do lote identifica o span pai "create_order_saga_batch" (ver
+	// placeOrdersConcurrently para o trace_id individual de cada item, que é o da própria SAGA)
+	c.JSON(http.StatusOK, gin.H{
+		"trace_id": span.SpanContext().TraceID().String(),
+		"results":  results,
+	})
+}
+
 // CreateOrder é um endpoint SAGA para criar um pedido
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	var req SagaActionRequest
@@ -92,24 +133,25 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	ctx, span := getOrStartSpanFromPayload(c.Request.Context(), "create_order", req)
+	ctx, span := startSpanFromPayload(c.Request.Context(), "create_order", "create", req)
 	defer span.End()
 
 	span.SetAttributes(
-		attribute.String("order_id", req.OrderID),
-		attribute.String("user_id", req.UserID),
 		attribute.String("product_id", req.ProductID),
 		attribute.Int("amount", req.Amount),
-		attribute.String("trace_id", req.TraceID),
 	)
 
+	start := time.Now()
 	err := h.useCase.CreateOrder(ctx, req)
+	h.metrics.RecordBranch(ctx, "orders", "create", time.Since(start))
 	if err != nil {
 		span.RecordError(err)
+		span.SetStatus(codes.Error, "create_order failed")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	emitTxEvent(ctx, h.events, "saga.branch.action.ok", "", req.OrderID, req.UserID, req.ProductID, start)
 	c.JSON(http.StatusOK, gin.H{"result": "success"})
 }
 
@@ -121,21 +163,26 @@ func (h *OrderHandler) CompleteOrder(c *gin.Context) {
 		return
 	}
 
-	ctx, span := getOrStartSpanFromPayload(c.Request.Context(), "complete_order", req)
+	ctx, span := startSpanFromPayload(c.Request.Context(), "complete_order", "complete", req)
 	defer span.End()
 
-	span.SetAttributes(
-		attribute.String("order_id", req.OrderID),
-		attribute.String("trace_id", req.TraceID),
-	)
-
+	start := time.Now()
 	err := h.useCase.CompleteOrder(ctx, req)
-	if err != nil {
+	h.metrics.RecordBranch(ctx, "orders", "complete", time.Since(start))
+	if err != nil && !errors.Is(err, ErrNoChange) {
 		span.RecordError(err)
+		span.SetStatus(codes.Error, "complete_order failed")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if errors.Is(err, ErrNoChange) {
+		span.SetAttributes(attribute.Bool("saga.noop", true))
+		c.JSON(http.StatusOK, gin.H{"result": "success", "status": "unchanged"})
+		return
+	}
+
+	emitTxEvent(ctx, h.events, "saga.branch.action.ok", "", req.OrderID, req.UserID, req.ProductID, start)
 	c.JSON(http.StatusOK, gin.H{"result": "success"})
 }
 
@@ -147,24 +194,48 @@ func (h *OrderHandler) CompensateOrder(c *gin.Context) {
 		return
 	}
 
-	ctx, span := getOrStartSpanFromPayload(c.Request.Context(), "compensate_order", req)
+	ctx, span := startSpanFromPayload(c.Request.Context(), "compensate_order", "compensate", req)
 	defer span.End()
 
-	span.SetAttributes(
-		attribute.String("order_id", req.OrderID),
-		attribute.String("trace_id", req.TraceID),
-	)
-
+	start := time.Now()
 	err := h.useCase.CancelOrder(ctx, req)
-	if err != nil {
+	h.metrics.RecordBranch(ctx, "orders", "compensate", time.Since(start))
+	if err != nil && !errors.Is(err, ErrNoChange) {
 		span.RecordError(err)
+		span.SetStatus(codes.Error, "compensate_order failed")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if errors.Is(err, ErrNoChange) {
+		span.SetAttributes(attribute.Bool("saga.noop", true))
+		c.JSON(http.StatusOK, gin.H{"result": "success", "status": "unchanged"})
+		return
+	}
+
+	emitTxEvent(ctx, h.events, "saga.branch.compensate.ok", "", req.OrderID, req.UserID, req.ProductID, start)
 	c.JSON(http.StatusOK, gin.H{"result": "success"})
 }
 
+// GetTransactionTracking responde com o estado agregado (DTM + domínio local) de uma transação
+// SAGA submetida por CreateOrderSaga, identificada pelo gid retornado naquela chamada
+func (h *OrderHandler) GetTransactionTracking(c *gin.Context) {
+	gid := c.Param("gid")
+
+	ctx, span := h.tracer.Start(c.Request.Context(), "get_transaction_tracking")
+	defer span.End()
+	span.SetAttributes(attribute.String("saga.gid", gid))
+
+	status, err := h.useCase.GetTransactionTracking(ctx, gid)
+	if err != nil {
+		span.RecordError(err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
 // HealthCheck verifica a saúde do serviço
 func (h *OrderHandler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -172,13 +243,3 @@ func (h *OrderHandler) HealthCheck(c *gin.Context) {
 		"service": "orders-service",
 	})
 }
-
-// getOrStartSpanFromPayload garante que sempre retorna um span filho do tracing atual (ou cria um novo se não houver)
-func getOrStartSpanFromPayload(ctx context.Context, operationName string, req SagaActionRequest) (context.Context, trace.Span) {
-	span := trace.SpanFromContext(ctx)
-	if span == nil || !span.SpanContext().IsValid() {
-		return startSpanFromPayload(ctx, operationName, req)
-	}
-	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer("")
-	return tracer.Start(ctx, operationName)
-}
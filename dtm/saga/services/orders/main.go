@@ -10,7 +10,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
@@ -28,39 +30,53 @@ type CreateOrderRequest struct {
 	Amount    int    `json:"amount" binding:"required,gt=0"`
 }
 
-// SagaActionRequest representa a requisição para ações da SAGA
+// SagaActionRequest representa a requisição para ações da SAGA. Carrega o trace context como o
+// par traceparent/tracestate do W3C Trace Context (em vez de TraceID/SpanID em hex cru), já que
+// o DTM chama as branches diretamente e não repassa os headers HTTP da requisição original.
+// BranchID é o índice (0-based) da branch dentro do saga.NewSaga().Add(...) montado em dtm.go,
+// usado para correlacionar os spans de cada serviço com a branch DTM que os disparou
 type SagaActionRequest struct {
-	OrderID   string `json:"order_id" binding:"required"`
-	UserID    string `json:"user_id" binding:"required"`
-	ProductID string `json:"product_id" binding:"required"`
-	Amount    int    `json:"amount" binding:"required,gt=0"`
-	// Manual trace context propagation (DTM doesn't propagate W3C headers)
-	TraceID string `json:"trace_id,omitempty"`
-	SpanID  string `json:"span_id,omitempty"`
+	OrderID     string `json:"order_id" binding:"required"`
+	UserID      string `json:"user_id" binding:"required"`
+	ProductID   string `json:"product_id" binding:"required"`
+	Amount      int    `json:"amount" binding:"required,gt=0"`
+	BranchID    int    `json:"branch_id"`
+	Traceparent string `json:"traceparent,omitempty"`
+	Tracestate  string `json:"tracestate,omitempty"`
 }
 
-// startSpanFromPayload creates a child span linked to the propagated trace context
-func startSpanFromPayload(ctx context.Context, operationName string, req SagaActionRequest) (context.Context, trace.Span) {
-	// If we have propagated TraceID and SpanID, reconstruct the trace context
-	if req.TraceID != "" && req.SpanID != "" {
-		parsedTraceID, _ := trace.TraceIDFromHex(req.TraceID)
-		parsedSpanID, _ := trace.SpanIDFromHex(req.SpanID)
-
-		spanContext := trace.NewSpanContext(trace.SpanContextConfig{
-			TraceID:    parsedTraceID,
-			SpanID:     parsedSpanID,
-			TraceFlags: trace.FlagsSampled,
-			Remote:     true,
-		})
+// startSpanFromPayload extrai o trace context propagado pelo orquestrador SAGA (via o par
+// traceparent/tracestate no payload) usando o TextMapPropagator configurado, e enriquece o
+// span com os atributos semânticos da ação, incluindo o branch_id atribuído pelo orquestrador e
+// o op (action|compensate) derivado de phase - op identifica a direção que o DTM chamou (forward
+// x rollback), phase identifica a ação específica dentro dessa direção
+func startSpanFromPayload(ctx context.Context, operationName, phase string, req SagaActionRequest) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier{
+		"traceparent": req.Traceparent,
+		"tracestate":  req.Tracestate,
+	})
 
-		ctx = trace.ContextWithSpanContext(ctx, spanContext)
-	}
-
-	// Get the global tracer
 	tracer := otel.Tracer("orders-service")
+	ctx, span := tracer.Start(ctx, operationName)
+	span.SetAttributes(
+		attribute.String("saga.phase", phase),
+		attribute.String("saga.op", sagaOpFromPhase(phase)),
+		attribute.Int("saga.branch_id", req.BranchID),
+		attribute.String("saga.order_id", req.OrderID),
+		attribute.String("saga.user_id", req.UserID),
+		attribute.String("db.system", "postgresql"),
+	)
+	return ctx, span
+}
 
-	// Create span with the reconstructed context
-	return tracer.Start(ctx, operationName)
+// sagaOpFromPhase reduz phase (específico de cada branch, ex.: "create"/"complete"/"compensate")
+// ao op genérico que o DTM de fato usa para decidir qual URL chamar: "compensate" nas branches de
+// rollback, "action" em toda branch forward
+func sagaOpFromPhase(phase string) string {
+	if phase == "compensate" {
+		return "compensate"
+	}
+	return "action"
 }
 
 func main() {
@@ -92,16 +108,51 @@ func main() {
 	}
 	defer dbPool.Close()
 
+	// RED metrics for the SAGA phases (dtx_transaction_duration_seconds, dtx_branch_duration_seconds,
+	// dtx_branch_retries_total, dtx_inflight_transactions) plus pgx pool occupancy gauges
+	meter := mp.Meter("orders-service")
+	dtxM, err := newDTXMetrics(meter)
+	if err != nil {
+		log.Fatalf("Failed to initialize DTM metrics: %v", err)
+	}
+	if err := registerPoolGauges(meter, dbPool); err != nil {
+		log.Fatalf("Failed to register pgx pool gauges: %v", err)
+	}
+
+	// Batch metrics (orders.batch.size, orders.batch.failures, orders.batch.latency) - separadas
+	// de dtxM por descreverem o lote /api/orders/batch como um todo, não uma fase/branch isolada
+	batchM, err := newBatchMetrics(meter)
+	if err != nil {
+		log.Fatalf("Failed to initialize batch metrics: %v", err)
+	}
+
+	// Transaction event log: histórico de transição de fase (saga.submitted,
+	// saga.branch.action.ok, saga.branch.compensate.ok), usado por um eventual coletor de
+	// benchmark/auditoria - não afeta o fluxo síncrono se o transporte estiver fora do ar.
+	// TRANSPORTS seleciona entre "nats" (JetStream, padrão) e "http" (POST a
+	// TX_EVENTS_CALLBACK_URL) - ver tx_events.go
+	txEventsPublisher, err := initTxEvents(context.Background(), getEnv("NATS_URL", "nats://nats:4222"))
+	if err != nil {
+		log.Printf("⚠️ Failed to initialize transaction event log, continuing without it: %v", err)
+	}
+
 	// Initialize dependencies
 	repository := NewOrderRepository(dbPool)
-	sagaOrchestrator := NewDTMSagaOrchestrator()
+	trackingRepository := NewPostgresTransactionTrackingRepository(dbPool)
+	sagaOrchestrator := NewDTMSagaOrchestrator(dtxM, trackingRepository, txEventsPublisher)
 	tracer := tp.Tracer("orders-service")
-	useCase := NewOrderUseCase(repository, sagaOrchestrator)
-	handler := NewOrderHandler(useCase, tracer)
+	useCase := NewOrderUseCase(repository, sagaOrchestrator, trackingRepository, batchM)
+	handler := NewOrderHandler(useCase, tracer, dtxM, txEventsPublisher)
+
+	idempotencyStore := NewPostgresIdempotencyStore(dbPool)
+	idemMetrics, err := newIdempotencyMetrics(meter)
+	if err != nil {
+		log.Fatalf("Failed to initialize idempotency metrics: %v", err)
+	}
 
 	// Setup Gin router
 	r := gin.Default()
-	// Middleware otelgin removido para evitar spans automáticos duplicados
+	r.Use(otelgin.Middleware(getEnv("SERVICE_NAME", "orders-service")))
 
 	// Health check
 	r.GET("/health", handler.HealthCheck)
@@ -109,10 +160,21 @@ func main() {
 	// Orchestrator endpoint - initiates SAGA
 	r.POST("/api/orders", handler.CreateOrderSaga)
 
-	// SAGA action endpoints
-	r.POST("/api/orders/create", handler.CreateOrder)
-	r.POST("/api/orders/complete", handler.CompleteOrder)
-	r.POST("/api/orders/compensate", handler.CompensateOrder)
+	// Batch endpoint - per-item partial-failure semantics with bounded concurrency
+	r.POST("/api/orders/batch", handler.CreateOrderBatch)
+
+	// SAGA action endpoints - chaosMiddleware injeta falhas configuráveis (ver chaos.go) antes do
+	// IdempotencyMiddleware, que curto-circuita reentregas do DTM pela chave order_id+phase
+	r.POST("/api/orders/create", chaosMiddleware(chaosCfg, "create"), IdempotencyMiddleware(idempotencyStore, idemMetrics, "orders.create", "create"), handler.CreateOrder)
+	r.POST("/api/orders/complete", chaosMiddleware(chaosCfg, "complete"), IdempotencyMiddleware(idempotencyStore, idemMetrics, "orders.complete", "complete"), handler.CompleteOrder)
+	r.POST("/api/orders/compensate", chaosMiddleware(chaosCfg, "compensate"), IdempotencyMiddleware(idempotencyStore, idemMetrics, "orders.compensate", "compensate"), handler.CompensateOrder)
+
+	// Admin endpoint - ajusta a injeção de falhas em tempo real (ver chaos.go)
+	r.GET("/admin/chaos", chaosAdminHandler(chaosCfg))
+	r.POST("/admin/chaos", chaosAdminHandler(chaosCfg))
+
+	// Transaction tracking endpoint - status agregado (DTM + domínio local) de uma SAGA pelo gid
+	r.GET("/api/transactions/:gid", handler.GetTransactionTracking)
 
 	port := getEnv("PORT", "8080")
 	log.Printf("🚀 Orders Service listening on port %s", port)
@@ -151,6 +213,10 @@ func initDB() (*pgxpool.Pool, error) {
 	config.MaxConnIdleTime = 30 * time.Minute
 	config.HealthCheckPeriod = 1 * time.Minute
 
+	// Instrumenta cada Query/Exec/BeginTx com um span "db.query", para decompor o tempo gasto no
+	// Postgres dentro do flame graph de cada fase da SAGA
+	config.ConnConfig.Tracer = newDBQueryTracer(getEnv("SERVICE_NAME", "orders-service"))
+
 	ctx := context.Background()
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
@@ -158,17 +224,30 @@ func initDB() (*pgxpool.Pool, error) {
 	}
 
 	// Wait for database to be ready
+	connected := false
 	for i := 0; i < 30; i++ {
 		if err := pool.Ping(ctx); err == nil {
 			log.Println("✅ Connected to orders database with connection pool")
-			return pool, nil
+			connected = true
+			break
 		}
 		log.Printf("⏳ Waiting for database... (%d/30)", i+1)
 		time.Sleep(1 * time.Second)
 	}
+	if !connected {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database after 30 attempts")
+	}
+
+	// IdempotencyMiddleware depende de idempotency_keys existir antes do primeiro POST em
+	// /api/orders/{create,complete,compensate} - sem isso, toda requisição falharia com
+	// "relation idempotency_keys does not exist"
+	if _, err := pool.Exec(ctx, ensureIdempotencyKeysTableSQL); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ensure idempotency_keys table: %w", err)
+	}
 
-	pool.Close()
-	return nil, fmt.Errorf("failed to connect to database after 30 attempts")
+	return pool, nil
 }
 
 func initTracer() (*sdktrace.TracerProvider, error) {
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// dtxMetrics agrupa os instrumentos RED (rate/errors/duration) emitidos ao longo das fases da
+// SAGA, expostos via o mesmo pipeline OTLP já usado pelo tracer (initMetrics). Os nomes dos
+// instrumentos são compartilhados entre SAGA/XA/TCC (prefixo "dtx_") para permitir comparar os
+// três modos no mesmo dashboard, com "mode" como atributo discriminador.
+type dtxMetrics struct {
+	transactionDuration  metric.Float64Histogram
+	branchDuration       metric.Float64Histogram
+	branchRetries        metric.Int64Counter
+	inflightTransactions metric.Int64UpDownCounter
+}
+
+// newDTXMetrics registra os instrumentos RED no meter informado
+func newDTXMetrics(meter metric.Meter) (*dtxMetrics, error) {
+	transactionDuration, err := meter.Float64Histogram(
+		"dtx_transaction_duration_seconds",
+		metric.WithDescription("Duration of a full distributed transaction from orchestration start to terminal outcome"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dtx_transaction_duration_seconds histogram: %w", err)
+	}
+
+	branchDuration, err := meter.Float64Histogram(
+		"dtx_branch_duration_seconds",
+		metric.WithDescription("Duration of a single branch call/phase against a participant service"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dtx_branch_duration_seconds histogram: %w", err)
+	}
+
+	branchRetries, err := meter.Int64Counter(
+		"dtx_branch_retries_total",
+		metric.WithDescription("Number of retried branch call attempts"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dtx_branch_retries_total counter: %w", err)
+	}
+
+	inflightTransactions, err := meter.Int64UpDownCounter(
+		"dtx_inflight_transactions",
+		metric.WithDescription("Number of distributed transactions currently being orchestrated"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dtx_inflight_transactions counter: %w", err)
+	}
+
+	return &dtxMetrics{
+		transactionDuration:  transactionDuration,
+		branchDuration:       branchDuration,
+		branchRetries:        branchRetries,
+		inflightTransactions: inflightTransactions,
+	}, nil
+}
+
+// RecordTransaction registra a duração de uma transação a partir do início da orquestração e seu
+// desfecho. Na SAGA, o único chamador (dtm.go) registra no aceite do Submit
+// (submitted/submit_failed), não no desfecho terminal das branches
+func (m *dtxMetrics) RecordTransaction(ctx context.Context, mode, outcome string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.transactionDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.String("mode", mode),
+		attribute.String("outcome", outcome),
+	))
+}
+
+// RecordBranch registra a duração de uma fase/branch isolada contra um serviço participante
+func (m *dtxMetrics) RecordBranch(ctx context.Context, service, phase string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.branchDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.String("service", service),
+		attribute.String("phase", phase),
+	))
+}
+
+// AddBranchRetries soma o número de tentativas retried para uma branch contra um serviço
+func (m *dtxMetrics) AddBranchRetries(ctx context.Context, service string, retries int64) {
+	if m == nil || retries <= 0 {
+		return
+	}
+	m.branchRetries.Add(ctx, retries, metric.WithAttributes(attribute.String("service", service)))
+}
+
+// AddInflight incrementa (delta positivo) ou decrementa (delta negativo) o número de transações
+// em andamento para o modo informado
+func (m *dtxMetrics) AddInflight(ctx context.Context, mode string, delta int64) {
+	if m == nil {
+		return
+	}
+	m.inflightTransactions.Add(ctx, delta, metric.WithAttributes(attribute.String("mode", mode)))
+}
+
+// registerPoolGauges expõe o tamanho do pool de conexões pgx como gauges observáveis, amostrados
+// a cada coleta pelo PeriodicReader - útil para correlacionar a latência das fases da SAGA com a
+// saturação do pool durante os cenários do benchrunner
+func registerPoolGauges(meter metric.Meter, pool *pgxpool.Pool) error {
+	_, err := meter.Int64ObservableGauge(
+		"pgx_pool_acquired",
+		metric.WithDescription("Number of connections currently acquired from the pgx pool"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(pool.Stat().AcquiredConns()))
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create pgx_pool_acquired gauge: %w", err)
+	}
+
+	_, err = meter.Int64ObservableGauge(
+		"pgx_pool_idle",
+		metric.WithDescription("Number of idle connections currently held by the pgx pool"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(pool.Stat().IdleConns()))
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create pgx_pool_idle gauge: %w", err)
+	}
+
+	return nil
+}
@@ -49,14 +49,24 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *Order) error {
 	return err
 }
 
-// UpdateOrderStatus atualiza o status de um pedido
+// UpdateOrderStatus atualiza o status de um pedido. Se o pedido já estiver no status alvo (ex:
+// reentrega do DTM após um commit anterior), nenhuma linha é afetada e ErrNoChange é retornado
+// em vez de um sucesso genérico, para que o chamador possa tratar o caso como no-op
 func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID string, status string) error {
-	_, err := r.db.Exec(ctx, `
-		UPDATE orders 
+	tag, err := r.db.Exec(ctx, `
+		UPDATE orders
 		SET status = $1, updated_at = NOW()
 		WHERE id = $2 AND status != $1
 	`, status, orderID)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoChange
+	}
+
+	return nil
 }
 
 // GetOrder busca um pedido pelo ID
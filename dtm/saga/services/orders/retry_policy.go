@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strconv"
+)
+
+// sagaRetryPolicy fica restrita aos dois campos que dtmcli.Saga aceita (WithRetryLimit/
+// RetryInterval).
+type sagaRetryPolicy struct {
+	maxAttempts     int
+	intervalSeconds int64
+}
+
+const (
+	defaultSagaRetryMaxAttempts     = 3
+	defaultSagaRetryIntervalSeconds = 1
+)
+
+// sagaRetryPolicyFromEnv lê RETRY_MAX_ATTEMPTS e RETRY_BASE_MS (convertido para segundos, que é a
+// granularidade aceita por dtmcli.Saga.RetryInterval) do ambiente, permitindo varrer a
+// configuração de retry do benchmark sem recompilar
+func sagaRetryPolicyFromEnv() sagaRetryPolicy {
+	p := sagaRetryPolicy{
+		maxAttempts:     defaultSagaRetryMaxAttempts,
+		intervalSeconds: defaultSagaRetryIntervalSeconds,
+	}
+
+	if v, err := strconv.Atoi(getEnv("RETRY_MAX_ATTEMPTS", "")); err == nil {
+		p.maxAttempts = v
+	}
+	if v, err := strconv.Atoi(getEnv("RETRY_BASE_MS", "")); err == nil && v > 0 {
+		p.intervalSeconds = int64(v) / 1000
+		if p.intervalSeconds < 1 {
+			p.intervalSeconds = 1
+		}
+	}
+
+	return p
+}
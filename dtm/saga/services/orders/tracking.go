@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TransactionTracking é o registro leve gravado no Submit da SAGA, antes de qualquer branch
+// rodar. Existe porque o DTM faz GC dos registros de transação global depois de um tempo
+// (DefaultGcIntervalSecond do dtm-labs), então sem uma cópia local o endpoint de tracking para de
+// responder exatamente quando alguém mais precisaria dele: investigando uma transação antiga que
+// travou.
+type TransactionTracking struct {
+	GID       string `db:"gid"`
+	OrderID   string `db:"order_id"`
+	TraceID   string `db:"trace_id"`
+	Protocol  string `db:"protocol"`
+	CreatedAt string `db:"created_at"`
+}
+
+// TransactionTrackingRepository persiste e consulta o mapeamento gid -> (order_id, trace_id,
+// protocol) usado pelo endpoint GET /api/transactions/:gid
+type TransactionTrackingRepository interface {
+	RecordTracking(ctx context.Context, tracking *TransactionTracking) error
+	GetTrackingByGID(ctx context.Context, gid string) (*TransactionTracking, error)
+}
+
+// PostgresTransactionTrackingRepository implementa TransactionTrackingRepository usando a tabela
+// transaction_tracking
+type PostgresTransactionTrackingRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresTransactionTrackingRepository cria uma nova instância do repositório
+func NewPostgresTransactionTrackingRepository(pool *pgxpool.Pool) *PostgresTransactionTrackingRepository {
+	return &PostgresTransactionTrackingRepository{pool: pool}
+}
+
+// RecordTracking grava o registro de rastreamento da transação (chamado no Submit, não no
+// desfecho final - o desfecho é consultado depois, ao vivo, no DTM)
+func (r *PostgresTransactionTrackingRepository) RecordTracking(ctx context.Context, tracking *TransactionTracking) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO transaction_tracking (gid, order_id, trace_id, protocol, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (gid) DO NOTHING
+	`, tracking.GID, tracking.OrderID, tracking.TraceID, tracking.Protocol)
+	if err != nil {
+		return fmt.Errorf("failed to record transaction tracking: %w", err)
+	}
+	return nil
+}
+
+// GetTrackingByGID busca o registro de rastreamento de uma transação pelo GID
+func (r *PostgresTransactionTrackingRepository) GetTrackingByGID(ctx context.Context, gid string) (*TransactionTracking, error) {
+	var tracking TransactionTracking
+	err := r.pool.QueryRow(ctx, `
+		SELECT gid, order_id, trace_id, protocol, created_at
+		FROM transaction_tracking
+		WHERE gid = $1
+	`, gid).Scan(&tracking.GID, &tracking.OrderID, &tracking.TraceID, &tracking.Protocol, &tracking.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction tracking for gid %s: %w", gid, err)
+	}
+	return &tracking, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DTMBranchStatus é a projeção de uma branch da transação global, como devolvida pelo endpoint
+// de consulta do próprio DTM (GET {DTM_SERVER}/query?gid=...)
+type DTMBranchStatus struct {
+	URL        string `json:"url"`
+	Op         string `json:"op"`
+	Status     string `json:"status"`
+	FinishTime string `json:"finish_time"`
+}
+
+// DTMTransactionStatus é a projeção do registro de transação global do DTM, usada para responder
+// o endpoint de tracking
+type DTMTransactionStatus struct {
+	GID      string
+	Protocol string
+	Status   string
+	Branches []DTMBranchStatus
+}
+
+// dtmQueryResponse espelha o shape de resposta de {DTM_SERVER}/query?gid=...: um objeto
+// "transaction" com os campos da transação global e um array "branches" com as branches
+type dtmQueryResponse struct {
+	Transaction struct {
+		Gid       string `json:"gid"`
+		TransType string `json:"trans_type"`
+		Status    string `json:"status"`
+	} `json:"transaction"`
+	Branches []DTMBranchStatus `json:"branches"`
+}
+
+// queryDTMTransaction consulta o DTM pelo estado atual de uma transação global e suas branches.
+// O registro pode já ter sido coletado pelo GC do DTM (transações antigas); nesse caso o chamador
+// deve tratar o erro como "desfecho desconhecido" e responder só com o que está gravado
+// localmente em transaction_tracking, em vez de falhar o endpoint inteiro.
+func queryDTMTransaction(ctx context.Context, dtmServer, gid string) (*DTMTransactionStatus, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	url := fmt.Sprintf("%s/query?gid=%s", dtmServer, gid)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build DTM query request: %w", err)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DTM for gid %s: %w", gid, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DTM query for gid %s returned status %d", gid, resp.StatusCode)
+	}
+
+	var parsed dtmQueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode DTM query response for gid %s: %w", gid, err)
+	}
+
+	return &DTMTransactionStatus{
+		GID:      parsed.Transaction.Gid,
+		Protocol: parsed.Transaction.TransType,
+		Status:   parsed.Transaction.Status,
+		Branches: parsed.Branches,
+	}, nil
+}
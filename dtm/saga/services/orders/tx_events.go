@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pkg/events já resolve a emissão de eventos de ciclo de vida da transação (saga.submitted,
+// saga.branch.action.ok, saga.branch.compensate.ok) de forma reutilizável, mas sem go.mod não há
+// como importar o módulo entre pastas - initTxEvents duplica localmente a parte mínima
+// necessária (conectar, garantir o stream/transporte, publicar).
+const txEventsStreamName = "TXEVENTS"
+
+// txEventsPublisher abstrai o transporte usado por emitTxEvent, selecionado por
+// TRANSPORTS=nats|http (padrão "nats"). Essas são as duas opções que este repositório consegue
+// entregar de verdade: não há cliente gRPC nem Kafka vendorizado em nenhum lugar do snapshot, e
+// sem um go.mod compartilhado não existe como montar um adaptador em dtm/*/services/* que não
+// seja duplicado por binário - daí o transporte ser selecionável, mas só entre os dois que este
+// serviço já sabe falar.
+type txEventsPublisher interface {
+	Publish(ctx context.Context, subject string, payload []byte) error
+}
+
+// natsTxEventsPublisher publica no stream JetStream TXEVENTS (subjects "tx.events.>")
+type natsTxEventsPublisher struct {
+	js jetstream.JetStream
+}
+
+func (p *natsTxEventsPublisher) Publish(ctx context.Context, subject string, payload []byte) error {
+	_, err := p.js.Publish(ctx, subject, payload)
+	return err
+}
+
+// httpTxEventsPublisher entrega cada evento via POST síncrono a um callback HTTP, no mesmo
+// estilo dos outbox relayers de dtm/tcc/services/{payment,inventory} - sem fila/retry própria,
+// já que emitTxEvent já trata falhas de publicação como não-críticas
+type httpTxEventsPublisher struct {
+	url        string
+	httpClient *http.Client
+}
+
+func (p *httpTxEventsPublisher) Publish(ctx context.Context, subject string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build tx event request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tx-Event-Subject", subject)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("tx event request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("tx event callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// initTxEvents seleciona o transporte do log de eventos de transação via TRANSPORTS ("nats",
+// padrão, ou "http") e o inicializa: para "nats", conecta e garante o stream TXEVENTS; para
+// "http", só valida que TX_EVENTS_CALLBACK_URL foi configurada
+func initTxEvents(ctx context.Context, natsURL string) (txEventsPublisher, error) {
+	switch transport := getEnv("TRANSPORTS", "nats"); transport {
+	case "http":
+		callbackURL := getEnv("TX_EVENTS_CALLBACK_URL", "")
+		if callbackURL == "" {
+			return nil, fmt.Errorf("TRANSPORTS=http requires TX_EVENTS_CALLBACK_URL to be set")
+		}
+		return &httpTxEventsPublisher{url: callbackURL, httpClient: &http.Client{Timeout: 5 * time.Second}}, nil
+	case "nats":
+		nc, err := nats.Connect(natsURL)
+		if err != nil {
+			return nil, err
+		}
+
+		js, err := jetstream.New(nc)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:     txEventsStreamName,
+			Subjects: []string{"tx.events.>"},
+			Storage:  jetstream.FileStorage,
+		}); err != nil {
+			return nil, err
+		}
+
+		return &natsTxEventsPublisher{js: js}, nil
+	default:
+		return nil, fmt.Errorf("unknown TRANSPORTS value %q, expected \"nats\" or \"http\"", transport)
+	}
+}
+
+// emitTxEvent publica um evento de transição de fase, sem interromper o fluxo de negócio em
+// caso de falha (o log de eventos é observacional, não faz parte do caminho crítico)
+func emitTxEvent(ctx context.Context, publisher txEventsPublisher, eventType, gid, orderID, userID, productID string, start time.Time) {
+	if publisher == nil {
+		return
+	}
+
+	event := TxEvent{
+		GID:        gid,
+		OrderID:    orderID,
+		UserID:     userID,
+		ProductID:  productID,
+		EventType:  eventType,
+		DurationMs: time.Since(start).Milliseconds(),
+		EmittedAt:  time.Now(),
+	}
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		event.TraceID = span.SpanContext().TraceID().String()
+		event.SpanID = span.SpanContext().SpanID().String()
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("⚠️ [TX EVENTS] failed to marshal event %s | GID=%s | Error=%v", eventType, gid, err)
+		return
+	}
+
+	subject := "tx.events." + eventType
+	if err := publisher.Publish(ctx, subject, payload); err != nil {
+		log.Printf("⚠️ [TX EVENTS] failed to publish event %s | GID=%s | Error=%v", eventType, gid, err)
+	}
+}
+
+// TxEvent é a mesma forma de pkg/events.TransactionEvent, duplicada aqui pela mesma razão de
+// initTxEvents - sem go.mod, pkg/events não pode ser importado diretamente
+type TxEvent struct {
+	GID        string    `json:"gid"`
+	OrderID    string    `json:"order_id"`
+	TraceID    string    `json:"trace_id,omitempty"`
+	SpanID     string    `json:"span_id,omitempty"`
+	UserID     string    `json:"user_id,omitempty"`
+	ProductID  string    `json:"product_id,omitempty"`
+	EventType  string    `json:"event_type"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	EmittedAt  time.Time `json:"emitted_at"`
+}
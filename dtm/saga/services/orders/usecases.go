@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
@@ -12,32 +13,86 @@ import (
 type OrderUseCase struct {
 	repository       Repository
 	sagaOrchestrator SagaOrchestrator
+	tracking         TransactionTrackingRepository
+	batchMetrics     *batchMetrics
 }
 
 // NewOrderUseCase cria uma nova instância de OrderUseCase
 func NewOrderUseCase(
 	repository Repository,
 	sagaOrchestrator SagaOrchestrator,
+	tracking TransactionTrackingRepository,
+	batchMetrics *batchMetrics,
 ) *OrderUseCase {
 	return &OrderUseCase{
 		repository:       repository,
 		sagaOrchestrator: sagaOrchestrator,
+		tracking:         tracking,
+		batchMetrics:     batchMetrics,
 	}
 }
 
+// TransactionStatusResponse agrega o estado DTM da transação global (protocolo, status geral,
+// status por branch) com o status de domínio local do pedido, para responder
+// GET /api/transactions/:gid num único payload
+type TransactionStatusResponse struct {
+	GID         string            `json:"gid"`
+	Protocol    string            `json:"protocol"`
+	Status      string            `json:"status"`
+	OrderID     string            `json:"order_id"`
+	OrderStatus string            `json:"order_status,omitempty"`
+	Branches    []DTMBranchStatus `json:"branches,omitempty"`
+}
+
+// GetTransactionTracking resolve o estado ponta-a-ponta de uma transação SAGA: localiza o
+// order_id pelo gid em transaction_tracking, consulta o DTM pelo status ao vivo da transação e
+// das branches e junta com o status de domínio atual do pedido. Se o DTM já tiver feito GC do
+// registro (transação antiga), a consulta falha mas a resposta ainda sai com o que está gravado
+// localmente, em vez de um 500 - só sem os dados de branch, que o DTM não guarda mais.
+func (uc *OrderUseCase) GetTransactionTracking(ctx context.Context, gid string) (*TransactionStatusResponse, error) {
+	tracking, err := uc.tracking.GetTrackingByGID(ctx, gid)
+	if err != nil {
+		return nil, fmt.Errorf("transaction tracking not found for gid %s: %w", gid, err)
+	}
+
+	response := &TransactionStatusResponse{
+		GID:      tracking.GID,
+		Protocol: tracking.Protocol,
+		Status:   "unknown",
+		OrderID:  tracking.OrderID,
+	}
+
+	dtmStatus, err := queryDTMTransaction(ctx, getEnv("DTM_SERVER", "http://dtm:36789/api/dtmsvr"), gid)
+	if err != nil {
+		log.Printf("⚠️ [TRACKING] DTM query failed, likely GC'd | GID: %s | Error: %v", gid, err)
+	} else {
+		response.Status = dtmStatus.Status
+		response.Branches = dtmStatus.Branches
+	}
+
+	order, err := uc.repository.GetOrder(ctx, tracking.OrderID)
+	if err != nil {
+		log.Printf("⚠️ [TRACKING] failed to load local order status | OrderID: %s | Error: %v", tracking.OrderID, err)
+	} else {
+		response.OrderStatus = order.Status
+	}
+
+	return response, nil
+}
+
 // CreateOrderSaga orquestra a transação SAGA
-func (uc *OrderUseCase) CreateOrderSaga(ctx context.Context, req CreateOrderRequest) (string, string, error) {
-	orderID, gid, err := uc.sagaOrchestrator.CreateOrderSaga(ctx, req)
+func (uc *OrderUseCase) CreateOrderSaga(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error) {
+	orderID, gid, traceID, err = uc.sagaOrchestrator.CreateOrderSaga(ctx, req)
 	if err != nil || orderID == "" {
 		if orderID == "" {
 			orderID = uuid.New().String()
 		}
 
 		_ = uc.CreateFailedOrder(ctx, req, orderID)
-		return "", "", fmt.Errorf("registering failed order to recover saga failure: %s", err.Error())
+		return orderID, gid, traceID, fmt.Errorf("registering failed order to recover saga failure: %s", err.Error())
 	}
 
-	return orderID, gid, nil
+	return orderID, gid, traceID, nil
 }
 
 func (uc *OrderUseCase) CreateFailedOrder(ctx context.Context, req CreateOrderRequest, orderID string) error {
@@ -77,11 +132,16 @@ func (uc *OrderUseCase) CompleteOrder(ctx context.Context, req SagaActionRequest
 	log.Printf("✅ [COMPLETE ORDER] OrderID: %s", req.OrderID)
 
 	err := uc.repository.UpdateOrderStatus(ctx, req.OrderID, OrderStatusCompleted)
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrNoChange) {
 		log.Printf("❌ Failed to complete order: %v", err)
 		return fmt.Errorf("failed to complete order: %w", err)
 	}
 
+	if errors.Is(err, ErrNoChange) {
+		log.Printf("ℹ️ Order already completed, skipping: %s", req.OrderID)
+		return ErrNoChange
+	}
+
 	log.Printf("✅ Order completed: %s", req.OrderID)
 	return nil
 }
@@ -91,11 +151,16 @@ func (uc *OrderUseCase) CancelOrder(ctx context.Context, req SagaActionRequest)
 	log.Printf("↩️ [COMPENSATE ORDER] OrderID: %s", req.OrderID)
 
 	err := uc.repository.UpdateOrderStatus(ctx, req.OrderID, OrderStatusRejected)
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrNoChange) {
 		log.Printf("❌ Failed to compensate order: %v", err)
 		return fmt.Errorf("failed to compensate order: %w", err)
 	}
 
+	if errors.Is(err, ErrNoChange) {
+		log.Printf("ℹ️ Order already compensated, skipping: %s", req.OrderID)
+		return ErrNoChange
+	}
+
 	log.Printf("♻️  Order compensated (rejected): %s", req.OrderID)
 	return nil
 }
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Mesmo desenho de dtm/tcc/services/payment/chaos.go, duplicado localmente aqui porque
+// dtm/*/services/* não tem um go.mod que permita compartilhar um pacote chaos. Diferença:
+// como GetWalletForUpdate já recebia o pool via NewPaymentRepository antes desta mudança, e esse
+// construtor tem chamadores em repository_integration_test.go fixados em um argumento, chaosCfg é
+// lido de um var de pacote (mesmo padrão de idempotencyMeter em idempotency.go) em vez de ser
+// injetado pelo construtor.
+type chaosConfig struct {
+	mu sync.RWMutex
+
+	enabled bool
+
+	latencyMsP50 int
+	latencyMsP99 int
+
+	httpErrorRate float64
+	connDropRate  float64
+
+	// deadlockRate é a fração (0-1) de GetWalletForUpdate que simulam um deadlock do Postgres
+	// (SQLSTATE 40P01)
+	deadlockRate float64
+
+	// phases restringe a injeção a fases específicas ("debit", "compensate"). Um mapa vazio
+	// significa "todas as fases"
+	phases map[string]bool
+}
+
+var chaosCfg = newChaosConfigFromEnv()
+
+func newChaosConfigFromEnv() *chaosConfig {
+	return &chaosConfig{
+		enabled:       os.Getenv("CHAOS_ENABLED") == "true",
+		latencyMsP50:  chaosEnvInt("CHAOS_LATENCY_MS_P50", 0),
+		latencyMsP99:  chaosEnvInt("CHAOS_LATENCY_MS_P99", 0),
+		httpErrorRate: chaosEnvFloat("CHAOS_HTTP_ERROR_RATE", 0),
+		connDropRate:  chaosEnvFloat("CHAOS_CONN_DROP_RATE", 0),
+		deadlockRate:  chaosEnvFloat("CHAOS_DEADLOCK_RATE", 0),
+		phases:        chaosEnvPhases("CHAOS_PHASES"),
+	}
+}
+
+type chaosUpdateRequest struct {
+	Enabled       *bool           `json:"enabled,omitempty"`
+	LatencyMsP50  *int            `json:"latency_ms_p50,omitempty"`
+	LatencyMsP99  *int            `json:"latency_ms_p99,omitempty"`
+	HTTPErrorRate *float64        `json:"http_error_rate,omitempty"`
+	ConnDropRate  *float64        `json:"conn_drop_rate,omitempty"`
+	DeadlockRate  *float64        `json:"deadlock_rate,omitempty"`
+	Phases        map[string]bool `json:"phases,omitempty"`
+}
+
+func (c *chaosConfig) update(req chaosUpdateRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if req.Enabled != nil {
+		c.enabled = *req.Enabled
+	}
+	if req.LatencyMsP50 != nil {
+		c.latencyMsP50 = *req.LatencyMsP50
+	}
+	if req.LatencyMsP99 != nil {
+		c.latencyMsP99 = *req.LatencyMsP99
+	}
+	if req.HTTPErrorRate != nil {
+		c.httpErrorRate = *req.HTTPErrorRate
+	}
+	if req.ConnDropRate != nil {
+		c.connDropRate = *req.ConnDropRate
+	}
+	if req.DeadlockRate != nil {
+		c.deadlockRate = *req.DeadlockRate
+	}
+	if req.Phases != nil {
+		c.phases = req.Phases
+	}
+}
+
+func (c *chaosConfig) snapshot() chaosUpdateRequest {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	enabled, p50, p99, httpRate, connRate, deadlockRate := c.enabled, c.latencyMsP50, c.latencyMsP99, c.httpErrorRate, c.connDropRate, c.deadlockRate
+	return chaosUpdateRequest{
+		Enabled:       &enabled,
+		LatencyMsP50:  &p50,
+		LatencyMsP99:  &p99,
+		HTTPErrorRate: &httpRate,
+		ConnDropRate:  &connRate,
+		DeadlockRate:  &deadlockRate,
+		Phases:        c.phases,
+	}
+}
+
+func (c *chaosConfig) phaseEnabled(phase string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.phases) == 0 {
+		return true
+	}
+	return c.phases[phase]
+}
+
+func (c *chaosConfig) snapshotRates() (enabled bool, p50, p99 int, httpRate, connRate, deadlockRate float64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.enabled, c.latencyMsP50, c.latencyMsP99, c.httpErrorRate, c.connDropRate, c.deadlockRate
+}
+
+// chaosMiddleware injeta latência, erros HTTP e quedas de conexão nas ações SAGA deste serviço,
+// respeitando o toggle por fase (debit/compensate) configurado em chaosConfig.phases
+func chaosMiddleware(cfg *chaosConfig, phase string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		enabled, p50, p99, httpRate, connRate, _ := cfg.snapshotRates()
+		if !enabled || !cfg.phaseEnabled(phase) {
+			c.Next()
+			return
+		}
+
+		if p50 > 0 || p99 > 0 {
+			time.Sleep(chaosRandomLatency(p50, p99))
+		}
+
+		if httpRate > 0 && rand.Float64() < httpRate {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "chaos: injected failure"})
+			return
+		}
+
+		if connRate > 0 && rand.Float64() < connRate {
+			hijacker, ok := c.Writer.(http.Hijacker)
+			if ok {
+				conn, _, err := hijacker.Hijack()
+				if err == nil {
+					conn.Close()
+					c.Abort()
+					return
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func chaosRandomLatency(p50, p99 int) time.Duration {
+	if p99 <= p50 {
+		return time.Duration(p50) * time.Millisecond
+	}
+	ms := p50 + rand.Intn(p99-p50+1)
+	return time.Duration(ms) * time.Millisecond
+}
+
+// maybeChaosDeadlock simula um deadlock do Postgres (SQLSTATE 40P01) em GetWalletForUpdate -
+// ignora o toggle por fase de chaosConfig.phases, já que o lock da carteira é disputado
+// igualmente em débito e compensação
+func maybeChaosDeadlock(cfg *chaosConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	_, _, _, _, _, deadlockRate := cfg.snapshotRates()
+	if deadlockRate <= 0 {
+		return nil
+	}
+	if rand.Float64() < deadlockRate {
+		return &pgconn.PgError{
+			Code:    "40P01",
+			Message: "deadlock detected (chaos-injected)",
+		}
+	}
+	return nil
+}
+
+// chaosAdminHandler expõe POST/GET /admin/chaos para ajustar a injeção de falhas em tempo real
+func chaosAdminHandler(cfg *chaosConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet {
+			c.JSON(http.StatusOK, cfg.snapshot())
+			return
+		}
+
+		var req chaosUpdateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chaos config: " + err.Error()})
+			return
+		}
+
+		cfg.update(req)
+		c.JSON(http.StatusOK, cfg.snapshot())
+	}
+}
+
+func chaosEnvInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+func chaosEnvFloat(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fallback
+	}
+	return f
+}
+
+func chaosEnvPhases(key string) map[string]bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+	phases := make(map[string]bool)
+	for _, p := range strings.Split(v, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			phases[p] = true
+		}
+	}
+	return phases
+}
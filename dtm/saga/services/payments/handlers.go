@@ -1,13 +1,13 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -33,19 +33,18 @@ func (h *PaymentHandler) DebitPayment(c *gin.Context) {
 		return
 	}
 
-	ctx, span := getOrStartSpanFromPayload(c.Request.Context(), "debit_payment", req)
+	ctx, span := startSpanFromPayload(c.Request.Context(), "debit_payment", "debit", req)
 	defer span.End()
 
 	span.SetAttributes(
-		attribute.String("order_id", req.OrderID),
-		attribute.String("user_id", req.UserID),
 		attribute.String("amount", fmt.Sprintf("%d", req.Amount)),
-		attribute.String("trace_id", req.TraceID),
 	)
 
 	err := h.useCase.DebitPayment(ctx, req)
 	if err != nil {
 		log.Printf("ℹ️ [DEBIT] FAILED for OrderID=%s : %s", req.OrderID, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "debit_payment failed")
 		// Determina o código de erro baseado na mensagem
 		if containsAny(err.Error(), []string{"wallet not found", "insufficient funds"}) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -66,18 +65,14 @@ func (h *PaymentHandler) CompensatePayment(c *gin.Context) {
 		return
 	}
 
-	ctx, span := getOrStartSpanFromPayload(c.Request.Context(), "compensate_payment", req)
+	ctx, span := startSpanFromPayload(c.Request.Context(), "compensate_payment", "compensate", req)
 	defer span.End()
 
-	span.SetAttributes(
-		attribute.String("order_id", req.OrderID),
-		attribute.String("user_id", req.UserID),
-		attribute.String("trace_id", req.TraceID),
-	)
-
 	err := h.useCase.CompensatePayment(ctx, req)
 	if err != nil {
 		log.Printf("ℹ️ [COMPENSATE DEBIT] FAILED for OrderID=%s : %s", req.OrderID, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "compensate_payment failed")
 
 		// Determina o código de erro baseado na mensagem
 		if containsAny(err.Error(), []string{"version conflict", "max retries exceeded"}) {
@@ -109,14 +104,3 @@ func containsAny(s string, substrs []string) bool {
 	}
 	return false
 }
-
-// getOrStartSpanFromPayload garante que sempre retorna um span filho do tracing atual (ou cria um novo se não houver)
-func getOrStartSpanFromPayload(ctx context.Context, operationName string, req SagaActionRequest) (context.Context, trace.Span) {
-	span := trace.SpanFromContext(ctx)
-	if span == nil || !span.SpanContext().IsValid() {
-		return startSpanFromPayload(ctx, operationName, req)
-	}
-	// Se já existe um span válido, apenas o renomeia e retorna o contexto atual
-	span.SetName(operationName)
-	return ctx, span
-}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Mesmo desenho usado em dtm/saga/services/{orders,inventory}/idempotency.go, duplicado
+// localmente em cada serviço porque não há um go.mod ligando os módulos em dtm/*/services/*.
+//
+// A chave aqui é order_id+":"+phase, já que o DTM chama as branches da SAGA só com o corpo JSON
+// da ação (SagaActionRequest), sem os parâmetros de query que ele anexa às chamadas de branch
+// XA/TCC. Montado em /api/payments/debit e /api/payments/compensate - ver main.go.
+const idempotencyKeyTTL = 48 * time.Hour
+
+const ensureIdempotencyKeysTableSQL = `
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		key             TEXT PRIMARY KEY,
+		operation       TEXT NOT NULL,
+		phase           TEXT NOT NULL,
+		status          TEXT NOT NULL DEFAULT 'done',
+		response_status INT,
+		response_body   BYTEA,
+		created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		expires_at      TIMESTAMPTZ NOT NULL
+	)
+`
+
+// idempotencyKey monta a chave de idempotência para uma ação da SAGA de pagamentos
+func idempotencyKey(orderID, phase string) string {
+	return orderID + ":" + phase
+}
+
+// idempotencyOutcome descreve o que PostgresIdempotencyStore.Begin encontrou para uma chave
+type idempotencyOutcome int
+
+const (
+	// idempotencyNew: chave vista pela primeira vez - o chamador deve executar o handler e
+	// registrar o resultado via Complete
+	idempotencyNew idempotencyOutcome = iota
+	// idempotencyLocked: outra requisição com a mesma chave ainda está em andamento
+	idempotencyLocked
+	// idempotencyCompleted: uma tentativa anterior já terminou - a resposta gravada deve ser
+	// reproduzida em vez de reexecutar o handler
+	idempotencyCompleted
+)
+
+// IdempotencyStore é a interface de armazenamento usada por IdempotencyMiddleware para reivindicar
+// uma chave, detectar uma reentrega correndo contra uma tentativa em andamento, e reproduzir a
+// resposta gravada por uma tentativa já concluída
+type IdempotencyStore interface {
+	Begin(ctx context.Context, key, operation, phase string) (idempotencyOutcome, int, []byte, error)
+	Complete(ctx context.Context, key string, status int, body []byte) error
+}
+
+// PostgresIdempotencyStore implementa IdempotencyStore sobre a tabela idempotency_keys
+type PostgresIdempotencyStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresIdempotencyStore cria uma nova instância de PostgresIdempotencyStore
+func NewPostgresIdempotencyStore(pool *pgxpool.Pool) *PostgresIdempotencyStore {
+	return &PostgresIdempotencyStore{pool: pool}
+}
+
+// Begin tenta inserir `key` com status 'locked'; ON CONFLICT DO NOTHING faz a segunda reentrega
+// concorrente cair no branch de leitura abaixo em vez de também conseguir o lock. Uma chave
+// 'locked' cujo expires_at já passou é reclamada aqui (UPDATE para 'locked' de novo, resetando o
+// TTL) em vez de devolvida como idempotencyLocked - sem isso, um handler que falha/derruba o
+// processo antes de chegar em Complete deixaria a chave travada para sempre, e não só até
+// expires_at, já que nada além desta checagem a revisita.
+func (s *PostgresIdempotencyStore) Begin(ctx context.Context, key, operation, phase string) (idempotencyOutcome, int, []byte, error) {
+	tag, err := s.pool.Exec(ctx, `
+		INSERT INTO idempotency_keys (key, operation, phase, status, created_at, expires_at)
+		VALUES ($1, $2, $3, 'locked', NOW(), NOW() + $4 * INTERVAL '1 second')
+		ON CONFLICT (key) DO NOTHING
+	`, key, operation, phase, idempotencyKeyTTL.Seconds())
+	if err != nil {
+		return idempotencyNew, 0, nil, fmt.Errorf("failed to claim idempotency key %q: %w", key, err)
+	}
+	if tag.RowsAffected() == 1 {
+		return idempotencyNew, 0, nil, nil
+	}
+
+	var status string
+	var responseStatus *int
+	var responseBody []byte
+	var expiresAt time.Time
+	err = s.pool.QueryRow(ctx, `
+		SELECT status, response_status, response_body, expires_at FROM idempotency_keys WHERE key = $1
+	`, key).Scan(&status, &responseStatus, &responseBody, &expiresAt)
+	if err != nil {
+		return idempotencyNew, 0, nil, fmt.Errorf("failed to load idempotency key %q: %w", key, err)
+	}
+
+	if status == "locked" {
+		if time.Now().Before(expiresAt) {
+			return idempotencyLocked, 0, nil, nil
+		}
+
+		tag, err := s.pool.Exec(ctx, `
+			UPDATE idempotency_keys
+			SET status = 'locked', created_at = NOW(), expires_at = NOW() + $3 * INTERVAL '1 second'
+			WHERE key = $1 AND status = 'locked' AND expires_at = $2
+		`, key, expiresAt, idempotencyKeyTTL.Seconds())
+		if err != nil {
+			return idempotencyNew, 0, nil, fmt.Errorf("failed to reclaim expired idempotency key %q: %w", key, err)
+		}
+		if tag.RowsAffected() == 0 {
+			// Outra requisição reclamou o lock (ou a concluiu) entre o SELECT e este UPDATE
+			return idempotencyLocked, 0, nil, nil
+		}
+		return idempotencyNew, 0, nil, nil
+	}
+
+	code := 0
+	if responseStatus != nil {
+		code = *responseStatus
+	}
+	return idempotencyCompleted, code, responseBody, nil
+}
+
+// Complete marca `key` como concluída, gravando o status e o corpo que o handler de fato
+// respondeu, para que reentregas futuras sejam respondidas com idempotencyCompleted
+func (s *PostgresIdempotencyStore) Complete(ctx context.Context, key string, status int, body []byte) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE idempotency_keys SET status = 'done', response_status = $1, response_body = $2 WHERE key = $3
+	`, status, body, key)
+	if err != nil {
+		return fmt.Errorf("failed to record idempotency outcome for key %q: %w", key, err)
+	}
+	return nil
+}
+
+// idempotencyMeter segue o mesmo padrão de outboxMeter em outbox_relay.go: um Meter obtido do
+// provider global, sem depender de injeção explícita de *sdkmetric.MeterProvider
+var idempotencyMeter = otel.Meter("payments-service-saga-idempotency")
+
+// idempotencyMetrics agrupa os contadores emitidos por IdempotencyMiddleware - hits (reentregas
+// curto-circuitadas com uma resposta já conhecida) e conflicts (reentregas bloqueadas por já
+// estarem em andamento) medem diretamente a amplificação de reentrega do DTM
+type idempotencyMetrics struct {
+	hits      metric.Int64Counter
+	conflicts metric.Int64Counter
+}
+
+// newIdempotencyMetrics registra idempotency_hits_total/idempotency_conflicts_total no meter
+// informado
+func newIdempotencyMetrics(meter metric.Meter) (*idempotencyMetrics, error) {
+	hits, err := meter.Int64Counter(
+		"idempotency_hits_total",
+		metric.WithDescription("Number of SAGA action requests short-circuited by replaying a previously recorded response"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create idempotency_hits_total counter: %w", err)
+	}
+
+	conflicts, err := meter.Int64Counter(
+		"idempotency_conflicts_total",
+		metric.WithDescription("Number of SAGA action requests rejected because another attempt with the same key was still in flight"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create idempotency_conflicts_total counter: %w", err)
+	}
+
+	return &idempotencyMetrics{hits: hits, conflicts: conflicts}, nil
+}
+
+// bodyRecordingWriter intercepta o que o handler escreve para que IdempotencyMiddleware possa
+// persistir exatamente o que o chamador recebeu, para reproduzir em reentregas futuras
+type bodyRecordingWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bodyRecordingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *bodyRecordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// IdempotencyMiddleware curto-circuita reentregas de uma branch SAGA identificadas por
+// order_id+phase (extraído do corpo após o bind - ver idempotencyKey). O DTM retenta branches
+// agressivamente em qualquer instabilidade de rede; sem isso, cada reentrega reexecutaria o
+// handler inteiro (reabrindo uma Tx de negócio e revalidando a carteira) mesmo quando o débito
+// original já tinha sido concluído. A primeira chamada processa normalmente e grava status+corpo
+// da resposta; uma reentrega com a mesma chave reproduz essa resposta em vez de reexecutar o
+// handler. Uma reentrega que chega enquanto a primeira ainda está em andamento (status "locked")
+// é rejeitada com 409 em vez de correr contra ela.
+func IdempotencyMiddleware(store IdempotencyStore, metrics *idempotencyMetrics, operation, phase string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := c.GetRawData()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var parsed struct {
+			OrderID string `json:"order_id"`
+		}
+		_ = json.Unmarshal(body, &parsed)
+		if parsed.OrderID == "" {
+			c.Next()
+			return
+		}
+		key := idempotencyKey(parsed.OrderID, phase)
+
+		outcome, status, cachedBody, err := store.Begin(c.Request.Context(), key, operation, phase)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		switch outcome {
+		case idempotencyCompleted:
+			metrics.hits.Add(c.Request.Context(), 1)
+			c.Data(status, "application/json", cachedBody)
+			c.Abort()
+			return
+		case idempotencyLocked:
+			metrics.conflicts.Add(c.Request.Context(), 1)
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with the same order_id and phase is already being processed"})
+			return
+		}
+
+		writer := &bodyRecordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		if writer.status == 0 {
+			writer.status = http.StatusOK
+		}
+		if err := store.Complete(c.Request.Context(), key, writer.status, writer.body.Bytes()); err != nil {
+			log.Printf("⚠️ [IDEMPOTENCY] failed to persist outcome for key=%s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+var dbPool *pgxpool.Pool
+
+func main() {
+	// Initialize OpenTelemetry
+	tp, err := initTracer()
+	if err != nil {
+		log.Fatalf("Failed to initialize tracer: %v", err)
+	}
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down tracer: %v", err)
+		}
+	}()
+
+	tracer := tp.Tracer("payments-service")
+
+	// Initialize database
+	dbPool, err = initDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer dbPool.Close()
+
+	// Setup Gin router
+	r := gin.Default()
+	r.Use(otelgin.Middleware(getEnv("SERVICE_NAME", "payments-service")))
+
+	repository, closeRepository := initPaymentRepository(dbPool)
+	defer closeRepository()
+	outboxRepository := NewPostgresOutboxRepository(dbPool)
+
+	useCase := NewPaymentUseCase(repository, outboxRepository)
+	handler := NewPaymentHandler(useCase, tracer)
+
+	idempotencyStore := NewPostgresIdempotencyStore(dbPool)
+	idemMetrics, err := newIdempotencyMetrics(idempotencyMeter)
+	if err != nil {
+		log.Fatalf("Failed to initialize idempotency metrics: %v", err)
+	}
+
+	// Outbox relayer: publishes payments_outbox events (debit/compensate) to the configured
+	// webhook, closing the dual-write gap between the wallet mutation above and downstream
+	// consumers being notified
+	publisher := NewHTTPPublisher(getEnv("PAYMENTS_OUTBOX_CALLBACK_URL", "http://event-relay:8080/webhooks/payments-events"))
+	relay := NewOutboxRelay(outboxRepository, publisher, 20)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	relay.Start(relayCtx, 2*time.Second)
+
+	// Health check
+	r.GET("/health", handler.HealthCheck)
+
+	// SAGA action endpoints - chaosMiddleware injeta falhas configuráveis (ver chaos.go) antes do
+	// IdempotencyMiddleware, que curto-circuita reentregas do DTM pela chave order_id+phase
+	r.POST("/api/payments/debit", chaosMiddleware(chaosCfg, "debit"), IdempotencyMiddleware(idempotencyStore, idemMetrics, "payments.debit_payment", "debit"), handler.DebitPayment)
+	r.POST("/api/payments/compensate", chaosMiddleware(chaosCfg, "compensate"), IdempotencyMiddleware(idempotencyStore, idemMetrics, "payments.compensate_payment", "compensate"), handler.CompensatePayment)
+
+	// Admin endpoint - ajusta a injeção de falhas em tempo real (ver chaos.go)
+	r.GET("/admin/chaos", chaosAdminHandler(chaosCfg))
+	r.POST("/admin/chaos", chaosAdminHandler(chaosCfg))
+
+	port := getEnv("PORT", "8080")
+	log.Printf("🚀 Payments Service listening on port %s", port)
+	if err := r.Run(":" + port); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
+
+func initDB() (*pgxpool.Pool, error) {
+	dsn := fmt.Sprintf(
+		"postgres://%s:%s@%s:%s/%s?sslmode=disable&pool_max_conns=25&pool_min_conns=5",
+		getEnv("DATABASE_USER", "root"),
+		getEnv("DATABASE_PASSWORD", "saga_pass"),
+		getEnv("DATABASE_HOST", "localhost"),
+		getEnv("DATABASE_PORT", "5432"),
+		getEnv("DATABASE_NAME", "payments_db"),
+	)
+
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
+	}
+
+	// Configure connection pool
+	config.MaxConns = 30
+	config.MaxConns = 10
+	config.MaxConnLifetime = time.Hour
+	config.MaxConnIdleTime = 30 * time.Minute
+	config.HealthCheckPeriod = 1 * time.Minute
+
+	// Instrumenta cada Query/Exec/BeginTx com um span "db.query", para decompor o tempo gasto no
+	// Postgres dentro do flame graph de cada ação da SAGA
+	config.ConnConfig.Tracer = newDBQueryTracer(getEnv("SERVICE_NAME", "payments-service"))
+
+	ctx := context.Background()
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+
+	// Wait for database to be ready
+	connected := false
+	for i := 0; i < 30; i++ {
+		if err := pool.Ping(ctx); err == nil {
+			log.Println("✅ Connected to payments database with connection pool")
+			connected = true
+			break
+		}
+		log.Printf("⏳ Waiting for database... (%d/30)", i+1)
+		time.Sleep(1 * time.Second)
+	}
+	if !connected {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database after 30 attempts")
+	}
+
+	// IdempotencyMiddleware depende de idempotency_keys existir antes do primeiro POST em
+	// /api/payments/{debit,compensate} - sem isso, toda requisição falharia com
+	// "relation idempotency_keys does not exist"
+	if _, err := pool.Exec(ctx, ensureIdempotencyKeysTableSQL); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ensure idempotency_keys table: %w", err)
+	}
+
+	return pool, nil
+}
+
+func initTracer() (*sdktrace.TracerProvider, error) {
+	ctx := context.Background()
+
+	otlpEndpoint := getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318")
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpoint(otlpEndpoint),
+		otlptracehttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(getEnv("SERVICE_NAME", "payments-service")),
+			semconv.ServiceVersion("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	otel.SetTracerProvider(tp)
+
+	return tp, nil
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OutboxEvent representa uma entrada do outbox transacional de pagamentos: é gravada na MESMA
+// transação de negócio que debita/credita a carteira (ver EnqueueOutboxEvent), diferente de um
+// evento publicado diretamente no bus, que poderia se perder se o processo caísse entre o commit
+// da carteira e a publicação. FailedAttempts alimenta o backoff exponencial do relay (ver
+// outbox_relay.go) - cresce a cada tentativa malsucedida de entrega e zera a prioridade da linha
+// até next_attempt_at (coluna interna, não exposta aqui) liberá-la de novo.
+type OutboxEvent struct {
+	ID             int64
+	OrderID        string
+	EventType      string
+	Payload        []byte
+	FailedAttempts int
+	OccurredAt     time.Time
+}
+
+// Publisher abstrai o destino para onde os eventos do outbox são entregues. A implementação
+// hoje disponível é HTTPPublisher (webhook configurável via PAYMENTS_OUTBOX_CALLBACK_URL); a
+// interface existe para que um backend Kafka/NATS possa ser adicionado sem mudar o relay.
+type Publisher interface {
+	Publish(ctx context.Context, event OutboxEvent) error
+}
+
+// OutboxRepository define as operações de persistência do outbox transacional de pagamentos
+type OutboxRepository interface {
+	// EnqueueOutboxEvent grava o evento dentro da transação de negócio em andamento - não é
+	// chamado quando a escrita de negócio foi um no-op (idempotência detectada, ou
+	// RowsAffected() == 0 no modo otimista), para que retentativas da mesma branch SAGA não
+	// dupliquem o evento publicado
+	EnqueueOutboxEvent(ctx context.Context, tx Tx, event *OutboxEvent) error
+
+	// ClaimPendingEvents seleciona até `limit` eventos ainda não publicados e cujo backoff já
+	// expirou, com FOR UPDATE SKIP LOCKED. A query roda fora de uma transação explícita, então o
+	// lock só dura o próprio SELECT: duas réplicas do relay podem receber o mesmo evento
+	ClaimPendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error)
+
+	// MarkPublished marca o evento como entregue
+	MarkPublished(ctx context.Context, id int64) error
+
+	// RecordPublishFailure incrementa failed_attempts e reagenda next_attempt_at para o futuro
+	RecordPublishFailure(ctx context.Context, id int64) error
+}
+
+// PostgresOutboxRepository implementa OutboxRepository usando a tabela payment_outbox_events
+type PostgresOutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresOutboxRepository(pool *pgxpool.Pool) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{pool: pool}
+}
+
+// EnqueueOutboxEvent grava o evento dentro da transação de negócio recebida via tx
+func (r *PostgresOutboxRepository) EnqueueOutboxEvent(ctx context.Context, tx Tx, event *OutboxEvent) error {
+	pgTx := tx.(*PostgresTx).tx
+
+	query := `
+		INSERT INTO payment_outbox_events (order_id, event_type, payload, occurred_at, next_attempt_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING id
+	`
+	err := pgTx.QueryRow(ctx, query, event.OrderID, event.EventType, event.Payload).Scan(&event.ID)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue outbox event: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimPendingEvents seleciona eventos não publicados cujo next_attempt_at já passou, em ordem
+// de chegada, com FOR UPDATE SKIP LOCKED (o lock termina com o SELECT). next_attempt_at é recalculado a
+// cada falha (ver RecordPublishFailure) a partir do momento da própria falha, não da criação do
+// evento - do contrário, uma vez failed_attempts atingir o teto de LEAST(...,6), a condição
+// ficaria permanentemente satisfeita e o relay tentaria de novo a cada Sweep em vez de respeitar
+// o backoff.
+func (r *PostgresOutboxRepository) ClaimPendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, order_id, event_type, payload, failed_attempts, occurred_at
+		FROM payment_outbox_events
+		WHERE published_at IS NULL
+		  AND next_attempt_at <= NOW()
+		ORDER BY id ASC
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var event OutboxEvent
+		if err := rows.Scan(&event.ID, &event.OrderID, &event.EventType, &event.Payload, &event.FailedAttempts, &event.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// MarkPublished marca o evento como entregue
+func (r *PostgresOutboxRepository) MarkPublished(ctx context.Context, id int64) error {
+	_, err := r.pool.Exec(ctx, `UPDATE payment_outbox_events SET published_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event %d as published: %w", id, err)
+	}
+	return nil
+}
+
+// RecordPublishFailure incrementa failed_attempts e reagenda next_attempt_at a partir de agora,
+// com backoff exponencial até um teto de 64s (2^6)
+func (r *PostgresOutboxRepository) RecordPublishFailure(ctx context.Context, id int64) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE payment_outbox_events
+		SET failed_attempts = failed_attempts + 1,
+		    next_attempt_at = NOW() + (INTERVAL '1 second' * POWER(2, LEAST(failed_attempts + 1, 6)))
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to record publish failure for outbox event %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisPaymentRepository implementa PaymentRepository sobre pipelines transacionais do Redis
+// (MULTI/EXEC via Client.TxPipeline), selecionado por PAYMENT_WALLET_BACKEND=redis (ver main.go)
+// como alternativa de benchmark ao Postgres (PostgresPaymentRepository) - permite comparar
+// throughput de lock pessimista (Postgres FOR UPDATE) vs pipeline (Redis) sob o mesmo
+// orquestrador SAGA, nos mesmos pontos de chamada dos use cases. Mesmo padrão de
+// RESUME_CALLBACK_BACKEND em dtm/tcc/services/orders/resume_callback_redis.go para selecionar um
+// backend Redis opcional via env var, e duplicado localmente em vez de um pkg/tx compartilhado:
+// não há go.mod ligando dtm/*/services/*.
+//
+// Carteira: hash "wallet:{userID}" com os campos amount/version. Idempotência de pagamento: SET
+// NX em "payment:{orderID}:{type}".
+type RedisPaymentRepository struct {
+	client *redis.Client
+
+	mu     sync.Mutex
+	pipes  map[int64]redis.Pipeliner
+	nextID int64
+}
+
+// NewRedisPaymentRepository cria um RedisPaymentRepository apoiado no client informado
+func NewRedisPaymentRepository(client *redis.Client) PaymentRepository {
+	return &RedisPaymentRepository{client: client, pipes: make(map[int64]redis.Pipeliner)}
+}
+
+// redisTx implementa Tx sobre um único redis.Pipeliner
+type redisTx struct {
+	ctx  context.Context
+	repo *RedisPaymentRepository
+	id   int64
+	pipe redis.Pipeliner
+	done bool
+}
+
+// BeginTx abre um novo pipeline transacional e o registra no índice interno, para que Commit/
+// Rollback o localizem pelo id sem precisar carregá-lo por fora
+func (r *RedisPaymentRepository) BeginTx(ctx context.Context) (Tx, error) {
+	id := atomic.AddInt64(&r.nextID, 1)
+	pipe := r.client.TxPipeline()
+
+	r.mu.Lock()
+	r.pipes[id] = pipe
+	r.mu.Unlock()
+
+	return &redisTx{ctx: ctx, repo: r, id: id, pipe: pipe}, nil
+}
+
+func (r *RedisPaymentRepository) release(id int64) {
+	r.mu.Lock()
+	delete(r.pipes, id)
+	r.mu.Unlock()
+}
+
+// Commit executa o pipeline (EXEC) e libera o índice interno
+func (t *redisTx) Commit() error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+	defer t.repo.release(t.id)
+
+	if _, err := t.pipe.Exec(t.ctx); err != nil {
+		return fmt.Errorf("failed to exec redis pipeline: %w", err)
+	}
+	return nil
+}
+
+// Rollback descarta os comandos enfileirados sem executá-los - seguro chamar depois de um Commit
+// bem-sucedido (no-op), para manter o padrão `defer tx.Rollback()` usado logo após BeginTx
+func (t *redisTx) Rollback() error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+	defer t.repo.release(t.id)
+
+	return t.pipe.Discard()
+}
+
+func walletKey(userID string) string { return "wallet:" + userID }
+
+func paymentKey(orderID, paymentType string) string { return "payment:" + orderID + ":" + paymentType }
+
+// GetWalletByUserID busca a carteira do usuário
+func (r *RedisPaymentRepository) GetWalletByUserID(ctx context.Context, userID string) (*Wallet, error) {
+	vals, err := r.client.HMGet(ctx, walletKey(userID), "amount", "version").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+	if vals[0] == nil {
+		return nil, fmt.Errorf("wallet not found for user %s", userID)
+	}
+
+	amount, _ := strconv.Atoi(fmt.Sprint(vals[0]))
+	version := 0
+	if vals[1] != nil {
+		version, _ = strconv.Atoi(fmt.Sprint(vals[1]))
+	}
+
+	return &Wallet{UserID: userID, CurrentAmount: amount, Version: version}, nil
+}
+
+// GetWalletForUpdate não trava nada de fato - ao contrário do FOR UPDATE do Postgres, o ponto de
+// comparação deste backend é justamente não pagar o custo de um lock de linha e, em vez disso,
+// aplicar a mutação dentro do mesmo EXEC do pipeline (ver DebitWallet/CreditWallet) como a
+// garantia de atomicidade
+func (r *RedisPaymentRepository) GetWalletForUpdate(ctx context.Context, tx Tx, userID string) (*Wallet, error) {
+	return r.GetWalletByUserID(ctx, userID)
+}
+
+// GetWalletVersion é equivalente a GetWalletByUserID neste backend - a versão já está sempre
+// disponível no hash da carteira, não há uma leitura "sem lock" separada como no Postgres
+func (r *RedisPaymentRepository) GetWalletVersion(ctx context.Context, tx Tx, userID string) (*Wallet, error) {
+	return r.GetWalletByUserID(ctx, userID)
+}
+
+// GetPaymentByOrderIDAndType lê diretamente contra o client (fora do pipeline) para devolver uma
+// resposta imediata ao chamador antes de enfileirar a mutação, o mesmo papel que GetWalletForUpdate
+// cumpre aqui
+func (r *RedisPaymentRepository) GetPaymentByOrderIDAndType(ctx context.Context, tx Tx, orderID string, paymentType string) (bool, error) {
+	exists, err := r.client.Exists(ctx, paymentKey(orderID, paymentType)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check payment record: %w", err)
+	}
+	return exists == 1, nil
+}
+
+// DebitWallet enfileira o débito da carteira e o registro de idempotência no pipeline da tx -
+// os dois só são aplicados atomicamente quando Commit executa o EXEC
+func (r *RedisPaymentRepository) DebitWallet(ctx context.Context, tx Tx, userID string, orderID string, amount int) error {
+	pipe := tx.(*redisTx).pipe
+	pipe.HIncrBy(ctx, walletKey(userID), "amount", -int64(amount))
+	pipe.Set(ctx, paymentKey(orderID, "debit"), uuid.New().String(), 0)
+	return nil
+}
+
+// CreditWallet é o equivalente de DebitWallet para o crédito (compensação)
+func (r *RedisPaymentRepository) CreditWallet(ctx context.Context, tx Tx, userID string, orderID string, amount int) error {
+	pipe := tx.(*redisTx).pipe
+	pipe.HIncrBy(ctx, walletKey(userID), "amount", int64(amount))
+	pipe.Set(ctx, paymentKey(orderID, "credit"), uuid.New().String(), 0)
+	return nil
+}
+
+// debitCreditVersioned é o script Lua que substitui a condição WHERE version = $expectedVersion
+// do Postgres: HGET+comparação+HINCRBY+registro de pagamento precisam ser atômicos entre si, e um
+// pipeline comum não garante isso (os comandos enfileirados não enxergam o resultado uns dos
+// outros antes do EXEC) - só um script Lua roda como uma única operação atômica no Redis
+const debitCreditVersionedScript = `
+local wallet_key = KEYS[1]
+local payment_key = KEYS[2]
+local delta = tonumber(ARGV[1])
+local expected_version = tonumber(ARGV[2])
+local payment_id = ARGV[3]
+
+local current_version = tonumber(redis.call("HGET", wallet_key, "version") or "-1")
+if current_version ~= expected_version then
+	return 0
+end
+
+redis.call("HINCRBY", wallet_key, "amount", delta)
+redis.call("HINCRBY", wallet_key, "version", 1)
+redis.call("SET", payment_key, payment_id)
+return 1
+`
+
+// DebitWalletVersioned aplica o débito condicionado à versão esperada via script Lua (ver
+// debitCreditVersionedScript) - roda direto contra o client, fora da tx/pipeline, porque a
+// verificação condicional precisa ser uma única operação atômica
+func (r *RedisPaymentRepository) DebitWalletVersioned(ctx context.Context, tx Tx, userID, orderID string, amount, expectedVersion int) (bool, error) {
+	return r.applyVersioned(ctx, userID, orderID, "debit", -amount, expectedVersion)
+}
+
+// CreditWalletVersioned é o equivalente de DebitWalletVersioned para o crédito (compensação)
+func (r *RedisPaymentRepository) CreditWalletVersioned(ctx context.Context, tx Tx, userID, orderID string, amount, expectedVersion int) (bool, error) {
+	return r.applyVersioned(ctx, userID, orderID, "credit", amount, expectedVersion)
+}
+
+func (r *RedisPaymentRepository) applyVersioned(ctx context.Context, userID, orderID, paymentType string, delta, expectedVersion int) (bool, error) {
+	result, err := r.client.Eval(ctx, debitCreditVersionedScript,
+		[]string{walletKey(userID), paymentKey(orderID, paymentType)},
+		delta, expectedVersion, uuid.New().String(),
+	).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to apply versioned wallet update: %w", err)
+	}
+
+	applied, ok := result.(int64)
+	return ok && applied == 1, nil
+}
+
+// initPaymentRepository monta o PaymentRepository configurado via PAYMENT_WALLET_BACKEND
+// ("postgres", o padrão, ou "redis"), mesmo padrão de initResumeCallback em
+// dtm/tcc/services/orders/resume_callback_redis.go - permite comparar o throughput do lock
+// pessimista do Postgres (FOR UPDATE) contra o pipeline do Redis sob o mesmo orquestrador SAGA,
+// sem recompilar o serviço. Devolve uma função de shutdown a ser chamada via defer em main();
+// no Postgres, é um no-op (o dbPool já é fechado separadamente em main()).
+func initPaymentRepository(dbPool *pgxpool.Pool) (PaymentRepository, func()) {
+	if getEnv("PAYMENT_WALLET_BACKEND", "postgres") != "redis" {
+		return NewPaymentRepository(dbPool), func() {}
+	}
+
+	opts, err := redis.ParseURL(getEnv("REDIS_URL", "redis://redis:6379/0"))
+	if err != nil {
+		log.Fatalf("Failed to parse REDIS_URL for payment repository: %v", err)
+	}
+
+	client := redis.NewClient(opts)
+	return NewRedisPaymentRepository(client), func() {
+		if err := client.Close(); err != nil {
+			log.Printf("Error closing payment repository redis client: %v", err)
+		}
+	}
+}
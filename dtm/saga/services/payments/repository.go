@@ -17,9 +17,22 @@ type PaymentRepository interface {
 	DebitWallet(ctx context.Context, tx Tx, userID string, orderID string, amount int) error
 	CreditWallet(ctx context.Context, tx Tx, userID string, orderID string, amount int) error
 	BeginTx(ctx context.Context) (Tx, error)
+
+	// GetWalletVersion lê a carteira sem lock de linha, para o modo de concorrência otimista
+	// (PAYMENT_LOCK_MODE=optimistic) - ver DebitWalletVersioned/CreditWalletVersioned
+	GetWalletVersion(ctx context.Context, tx Tx, userID string) (*Wallet, error)
+
+	// DebitWalletVersioned/CreditWalletVersioned aplicam a atualização condicionada à versão lida
+	// por GetWalletVersion (UPDATE ... WHERE version = $expectedVersion). Retornam ok=false, sem
+	// erro, quando nenhuma linha foi afetada porque outra transação já avançou a versão (conflito
+	// de concorrência) - cabe ao chamador decidir se tenta novamente
+	DebitWalletVersioned(ctx context.Context, tx Tx, userID, orderID string, amount, expectedVersion int) (bool, error)
+	CreditWalletVersioned(ctx context.Context, tx Tx, userID, orderID string, amount, expectedVersion int) (bool, error)
 }
 
-// Tx interface para transações
+// Tx abstrai o que o repositório precisa de uma transação de negócio (PostgresTx, que envolve um
+// pgx.Tx, e redisTx a implementam),
+// para que as operações de carteira não dependam do driver concreto.
 type Tx interface {
 	Commit() error
 	Rollback() error
@@ -76,6 +89,10 @@ func (r *PostgresPaymentRepository) BeginTx(ctx context.Context) (Tx, error) {
 
 // GetWalletForUpdate obtém a carteira com lock pessimista (FOR UPDATE)
 func (r *PostgresPaymentRepository) GetWalletForUpdate(ctx context.Context, tx Tx, userID string) (*Wallet, error) {
+	if err := maybeChaosDeadlock(chaosCfg); err != nil {
+		return nil, err
+	}
+
 	pgTx := tx.(*PostgresTx).tx
 
 	query := `
@@ -179,6 +196,95 @@ func (r *PostgresPaymentRepository) CreditWallet(ctx context.Context, tx Tx, use
 	return nil
 }
 
+// GetWalletVersion busca a carteira sem lock de linha, incluindo a versão atual - usada pelo modo
+// de concorrência otimista no lugar de GetWalletForUpdate
+func (r *PostgresPaymentRepository) GetWalletVersion(ctx context.Context, tx Tx, userID string) (*Wallet, error) {
+	pgTx := tx.(*PostgresTx).tx
+
+	var wallet Wallet
+	err := pgTx.QueryRow(ctx, `
+		SELECT id, user_id, current_amount, version, created_at, updated_at
+		FROM wallets
+		WHERE user_id = $1
+	`, userID).Scan(&wallet.ID, &wallet.UserID, &wallet.CurrentAmount, &wallet.Version, &wallet.CreatedAt, &wallet.UpdatedAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet version: %w", err)
+	}
+
+	return &wallet, nil
+}
+
+// DebitWalletVersioned debita o valor e incrementa a versão, condicionado à versão esperada lida
+// anteriormente por GetWalletVersion. ok=false (sem erro) indica conflito de versão: outra
+// transação alterou a carteira entre a leitura e esta escrita
+func (r *PostgresPaymentRepository) DebitWalletVersioned(ctx context.Context, tx Tx, userID, orderID string, amount, expectedVersion int) (bool, error) {
+	pgTx := tx.(*PostgresTx).tx
+
+	tag, err := pgTx.Exec(ctx, `
+		UPDATE wallets
+		SET current_amount = current_amount - $1,
+		    version = version + 1,
+		    updated_at = NOW()
+		WHERE user_id = $2 AND version = $3
+	`, amount, userID, expectedVersion)
+	if err != nil {
+		return false, fmt.Errorf("failed to debit wallet (optimistic): %w", err)
+	}
+	if tag.RowsAffected() != 1 {
+		return false, nil
+	}
+
+	var walletID string
+	if err := pgTx.QueryRow(ctx, `SELECT id FROM wallets WHERE user_id = $1`, userID).Scan(&walletID); err != nil {
+		return false, fmt.Errorf("failed to get wallet id: %w", err)
+	}
+
+	paymentID := uuid.New().String()
+	if _, err := pgTx.Exec(ctx, `
+		INSERT INTO user_payments (id, wallet_id, order_id, amount, type)
+		VALUES ($1, $2, $3, $4, $5)
+	`, paymentID, walletID, orderID, amount, "debit"); err != nil {
+		return false, fmt.Errorf("failed to insert payment record: %w", err)
+	}
+
+	return true, nil
+}
+
+// CreditWalletVersioned é o equivalente de CreditWallet para o modo de concorrência otimista
+func (r *PostgresPaymentRepository) CreditWalletVersioned(ctx context.Context, tx Tx, userID, orderID string, amount, expectedVersion int) (bool, error) {
+	pgTx := tx.(*PostgresTx).tx
+
+	tag, err := pgTx.Exec(ctx, `
+		UPDATE wallets
+		SET current_amount = current_amount + $1,
+		    version = version + 1,
+		    updated_at = NOW()
+		WHERE user_id = $2 AND version = $3
+	`, amount, userID, expectedVersion)
+	if err != nil {
+		return false, fmt.Errorf("failed to credit wallet (optimistic): %w", err)
+	}
+	if tag.RowsAffected() != 1 {
+		return false, nil
+	}
+
+	var walletID string
+	if err := pgTx.QueryRow(ctx, `SELECT id FROM wallets WHERE user_id = $1`, userID).Scan(&walletID); err != nil {
+		return false, fmt.Errorf("failed to get wallet id: %w", err)
+	}
+
+	paymentID := uuid.New().String()
+	if _, err := pgTx.Exec(ctx, `
+		INSERT INTO user_payments (id, wallet_id, order_id, amount, type)
+		VALUES ($1, $2, $3, $4, $5)
+	`, paymentID, walletID, orderID, amount, "credit"); err != nil {
+		return false, fmt.Errorf("failed to insert payment record: %w", err)
+	}
+
+	return true, nil
+}
+
 // GetPaymentByOrderIDAndType verifica se já existe um pagamento para o order_id e tipo especificados
 func (r *PostgresPaymentRepository) GetPaymentByOrderIDAndType(ctx context.Context, tx Tx, orderID string, paymentType string) (bool, error) {
 	pgTx := tx.(*PostgresTx).tx
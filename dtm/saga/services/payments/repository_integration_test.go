@@ -0,0 +1,202 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Nenhum fixture compartilhado: este arquivo sobe seu próprio testcontainers.ContainerRequest,
+// igual a inventory/repository_integration_test.go - não há um go.mod comum que permitisse os
+// dois importarem um helper de um pacote testutil.
+//
+// paymentsTestSchema cobre apenas o que PostgresPaymentRepository de fato lê/escreve; não há
+// migrações versionadas neste repositório (nenhum serviço tem uma), então o schema de teste é a
+// fonte da verdade local, copiado das colunas referenciadas nas queries de repository.go.
+const paymentsTestSchema = `
+CREATE TABLE IF NOT EXISTS wallets (
+	id             TEXT PRIMARY KEY,
+	user_id        TEXT UNIQUE NOT NULL,
+	current_amount INTEGER NOT NULL,
+	version        INTEGER NOT NULL DEFAULT 1,
+	created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_payments (
+	id         TEXT PRIMARY KEY,
+	wallet_id  TEXT NOT NULL REFERENCES wallets(id),
+	order_id   TEXT NOT NULL,
+	amount     INTEGER NOT NULL,
+	type       TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+`
+
+// newPaymentsTestPool sobe um PostgreSQL 16 efêmero e aplica paymentsTestSchema, sem depender
+// de um helper compartilhado: este serviço, como todo dtm/*/services/*, é um binário Go
+// independente sem go.mod compartilhado - não há hoje um import path válido de volta para um
+// pacote de fixtures em outro diretório.
+func newPaymentsTestPool(ctx context.Context, t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	pgContainer, err := postgres.Run(ctx, "postgres:16-alpine",
+		postgres.WithDatabase("payments_test"),
+		postgres.WithUsername("payments_test"),
+		postgres.WithPassword("payments_test"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(30*time.Second),
+		),
+	)
+	require.NoError(t, err, "failed to start postgres container")
+
+	dsn, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err, "failed to obtain postgres connection string")
+
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err, "failed to connect to test postgres container")
+
+	_, err = pool.Exec(ctx, paymentsTestSchema)
+	require.NoError(t, err, "failed to apply test schema")
+
+	t.Cleanup(func() {
+		pool.Close()
+		_ = pgContainer.Terminate(context.Background())
+	})
+
+	return pool
+}
+
+func seedWallet(ctx context.Context, t *testing.T, pool *pgxpool.Pool, userID string, amount int) string {
+	t.Helper()
+
+	walletID := uuid.New().String()
+	_, err := pool.Exec(ctx, `
+		INSERT INTO wallets (id, user_id, current_amount, version)
+		VALUES ($1, $2, $3, 1)
+	`, walletID, userID, amount)
+	require.NoError(t, err, "failed to seed wallet")
+
+	return walletID
+}
+
+// TestPostgresPaymentRepository_GetWalletForUpdate_Serializes comprova que FOR UPDATE de fato
+// serializa duas goroutines disputando a mesma carteira: a segunda só obtém a linha depois que a
+// primeira comita, nunca concorrentemente.
+func TestPostgresPaymentRepository_GetWalletForUpdate_Serializes(t *testing.T) {
+	ctx := context.Background()
+	pool := newPaymentsTestPool(ctx, t)
+	repo := NewPaymentRepository(pool)
+
+	userID := "user-" + uuid.New().String()
+	seedWallet(ctx, t, pool, userID, 1000)
+
+	firstHolds := make(chan struct{})
+	releaseFirst := make(chan struct{})
+	var secondAcquiredAt, firstReleasedAt time.Time
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		tx, err := repo.BeginTx(ctx)
+		require.NoError(t, err)
+		_, err = repo.GetWalletForUpdate(ctx, tx, userID)
+		require.NoError(t, err)
+
+		close(firstHolds)
+		<-releaseFirst
+		firstReleasedAt = time.Now()
+		require.NoError(t, tx.Commit())
+	}()
+
+	go func() {
+		defer wg.Done()
+		<-firstHolds
+
+		tx, err := repo.BeginTx(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		close(releaseFirst)
+		_, err = repo.GetWalletForUpdate(ctx, tx, userID)
+		require.NoError(t, err)
+		secondAcquiredAt = time.Now()
+	}()
+
+	wg.Wait()
+
+	assert.False(t, secondAcquiredAt.Before(firstReleasedAt),
+		"second GetWalletForUpdate must not acquire the row before the first transaction commits")
+}
+
+// TestPostgresPaymentRepository_DebitWallet_DuplicateCaughtByExistenceCheck comprova que uma
+// segunda tentativa de débito para o mesmo order_id, em outra transação, é detectada por
+// GetPaymentByOrderIDAndType antes de debitar de novo - o mesmo precheck que
+// PaymentUseCase.debitPaymentPessimistic já faz em produção.
+func TestPostgresPaymentRepository_DebitWallet_DuplicateCaughtByExistenceCheck(t *testing.T) {
+	ctx := context.Background()
+	pool := newPaymentsTestPool(ctx, t)
+	repo := NewPaymentRepository(pool)
+
+	userID := "user-" + uuid.New().String()
+	orderID := "order-" + uuid.New().String()
+	seedWallet(ctx, t, pool, userID, 1000)
+
+	tx1, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.DebitWallet(ctx, tx1, userID, orderID, 100))
+	require.NoError(t, tx1.Commit())
+
+	tx2, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	defer tx2.Rollback()
+
+	exists, err := repo.GetPaymentByOrderIDAndType(ctx, tx2, orderID, PaymentTypeDebit)
+	require.NoError(t, err)
+	assert.True(t, exists, "duplicate debit attempt for the same order_id must be caught by GetPaymentByOrderIDAndType")
+
+	wallet, err := repo.GetWalletByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 900, wallet.CurrentAmount, "wallet must only be debited once across both attempts")
+}
+
+// TestPostgresPaymentRepository_Rollback_LeavesUserPaymentsEmpty comprova que um Rollback
+// explícito não deixa nenhum rastro em user_payments, nem a atualização de saldo em wallets -
+// garantindo que o `defer tx.Rollback()` usado em todo use case é seguro mesmo depois de
+// DebitWallet já ter sido chamado dentro da transação.
+func TestPostgresPaymentRepository_Rollback_LeavesUserPaymentsEmpty(t *testing.T) {
+	ctx := context.Background()
+	pool := newPaymentsTestPool(ctx, t)
+	repo := NewPaymentRepository(pool)
+
+	userID := "user-" + uuid.New().String()
+	orderID := "order-" + uuid.New().String()
+	seedWallet(ctx, t, pool, userID, 1000)
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.DebitWallet(ctx, tx, userID, orderID, 100))
+	require.NoError(t, tx.Rollback())
+
+	var count int
+	require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM user_payments WHERE order_id = $1`, orderID).Scan(&count))
+	assert.Equal(t, 0, count, "user_payments must be empty after a rollback")
+
+	wallet, err := repo.GetWalletByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, wallet.CurrentAmount, "wallet balance must be unchanged after a rollback")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SagaActionRequest representa a requisição para ações da SAGA. Carrega o trace context como o
+// par traceparent/tracestate do W3C Trace Context (em vez de TraceID/SpanID em hex cru), já que
+// o DTM chama as branches diretamente e não repassa os headers HTTP da requisição original - ver
+// o mesmo tipo em dtm/saga/services/orders/main.go e dtm/saga/services/inventory/main.go.
+// BranchID é o índice (0-based) da branch dentro do saga.NewSaga().Add(...) montado em
+// dtm/saga/services/orders/dtm.go, usado para correlacionar os spans de cada serviço com a
+// branch DTM que os disparou
+type SagaActionRequest struct {
+	OrderID     string `json:"order_id" binding:"required"`
+	UserID      string `json:"user_id"`
+	ProductID   string `json:"product_id"`
+	Amount      int    `json:"amount"`
+	BranchID    int    `json:"branch_id"`
+	Traceparent string `json:"traceparent,omitempty"`
+	Tracestate  string `json:"tracestate,omitempty"`
+}
+
+// startSpanFromPayload extrai o trace context propagado pelo orquestrador SAGA (via o par
+// traceparent/tracestate no payload) usando o TextMapPropagator configurado, e enriquece o span
+// com os atributos semânticos da ação, incluindo o branch_id atribuído pelo orquestrador e o op
+// (action|compensate) derivado de phase - op identifica a direção que o DTM chamou (forward x
+// rollback), phase identifica a ação específica dentro dessa direção (ex.: "debit" vs
+// "compensate")
+func startSpanFromPayload(ctx context.Context, operationName, phase string, req SagaActionRequest) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier{
+		"traceparent": req.Traceparent,
+		"tracestate":  req.Tracestate,
+	})
+
+	tracer := otel.Tracer("payments-service")
+	ctx, span := tracer.Start(ctx, operationName)
+	span.SetAttributes(
+		attribute.String("saga.phase", phase),
+		attribute.String("saga.op", sagaOpFromPhase(phase)),
+		attribute.Int("saga.branch_id", req.BranchID),
+		attribute.String("saga.order_id", req.OrderID),
+		attribute.String("saga.user_id", req.UserID),
+	)
+	return ctx, span
+}
+
+// sagaOpFromPhase reduz phase (específico de cada branch, ex.: "debit"/"compensate") ao op
+// genérico que o DTM de fato usa para decidir qual URL chamar: "compensate" nas branches de
+// rollback, "action" em toda branch forward
+func sagaOpFromPhase(phase string) string {
+	if phase == "compensate" {
+		return "compensate"
+	}
+	return "action"
+}
@@ -2,9 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"math/rand"
+	"os"
+	"time"
 
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/metric"
 )
 
@@ -12,27 +17,112 @@ var (
 	ErrUnprocessableEntity = fmt.Errorf("unprocessable entity")
 )
 
+// Modos de lock suportados por PaymentUseCase, selecionados via PAYMENT_LOCK_MODE
+const (
+	lockModePessimistic = "pessimistic"
+	lockModeOptimistic  = "optimistic"
+
+	maxOptimisticLockRetries  = 5
+	optimisticLockBackoffBase = 20 * time.Millisecond
+)
+
 // PaymentUseCase contém a lógica de negócio de pagamentos
 type PaymentUseCase struct {
 	repository                   PaymentRepository
+	outbox                       OutboxRepository
+	lockMode                     string
 	paymentDebitCounter          metric.Int64Counter
 	paymentCompensationCounter   metric.Int64Counter
 	paymentOptimisticLockRetries metric.Int64Counter
 }
 
-// NewPaymentUseCase cria uma nova instância de PaymentUseCase
+// NewPaymentUseCase cria uma nova instância de PaymentUseCase. O modo de lock usado para debitar/
+// creditar a carteira é lido de PAYMENT_LOCK_MODE ("pessimistic", default, ou "optimistic") - isso
+// permite rodar o mesmo benchmark SAGA sob as duas estratégias de concorrência sem recompilar.
+// outbox pode ser nil (ex: em testes) - nesse caso a gravação do evento é pulada silenciosamente
 func NewPaymentUseCase(
 	repository PaymentRepository,
+	outbox OutboxRepository,
 ) *PaymentUseCase {
-	return &PaymentUseCase{
+	lockMode := os.Getenv("PAYMENT_LOCK_MODE")
+	if lockMode != lockModeOptimistic {
+		lockMode = lockModePessimistic
+	}
+
+	uc := &PaymentUseCase{
 		repository: repository,
+		outbox:     outbox,
+		lockMode:   lockMode,
+	}
+
+	if lockMode == lockModeOptimistic {
+		retries, err := otel.Meter("payments-service-saga").Int64Counter(
+			"paymentOptimisticLockRetries",
+			metric.WithDescription("Number of optimistic lock collisions retried while debiting/crediting a wallet"),
+		)
+		if err != nil {
+			log.Printf("⚠️ [PAYMENT] failed to create paymentOptimisticLockRetries counter: %v", err)
+		}
+		uc.paymentOptimisticLockRetries = retries
+	}
+
+	return uc
+}
+
+// recordOptimisticLockRetry incrementa paymentOptimisticLockRetries; no-op quando o contador não
+// foi inicializado (modo pessimista)
+func (uc *PaymentUseCase) recordOptimisticLockRetry(ctx context.Context) {
+	if uc.paymentOptimisticLockRetries == nil {
+		return
+	}
+	uc.paymentOptimisticLockRetries.Add(ctx, 1)
+}
+
+// jitteredOptimisticBackoff aplica o mesmo esquema de backoff exponencial + jitter usado para
+// retries de branch (ver monitored_branch.go em dtm/tcc), com uma base menor porque aqui o retry
+// é por contenção de linha no mesmo banco, não por uma chamada de rede
+func jitteredOptimisticBackoff(attempt int) time.Duration {
+	backoff := optimisticLockBackoffBase * time.Duration(1<<uint(attempt-1))
+	jitter := time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+	return backoff + jitter
+}
+
+// enqueueOutboxEvent grava, dentro da mesma transação de negócio tx, um evento descrevendo a
+// mutação de carteira que acabou de ser aplicada. É chamado só depois que a escrita de negócio é
+// confirmada como tendo realmente ocorrido (não um no-op de idempotência, nem RowsAffected() == 0
+// no modo otimista) - caso contrário uma retentativa da mesma branch SAGA publicaria o mesmo
+// evento mais de uma vez
+func (uc *PaymentUseCase) enqueueOutboxEvent(ctx context.Context, tx Tx, req SagaActionRequest, eventType string) error {
+	if uc.outbox == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox payload: %w", err)
 	}
+
+	event := &OutboxEvent{
+		OrderID:   req.OrderID,
+		EventType: eventType,
+		Payload:   payload,
+	}
+	return uc.outbox.EnqueueOutboxEvent(ctx, tx, event)
 }
 
-// DebitPayment debita um valor da carteira do usuário usando Lock Pessimista
+// DebitPayment debita um valor da carteira do usuário, usando lock pessimista ou otimista
+// conforme uc.lockMode (ver NewPaymentUseCase)
 func (uc *PaymentUseCase) DebitPayment(ctx context.Context, req SagaActionRequest) error {
-	log.Printf("➡️ [DEBIT PAYMENT] TraceID: %s | OrderID: %s | UserID: %s | Amount: %d",
-		req.TraceID, req.OrderID, req.UserID, req.Amount)
+	if uc.lockMode == lockModeOptimistic {
+		return uc.debitPaymentOptimistic(ctx, req)
+	}
+	return uc.debitPaymentPessimistic(ctx, req)
+}
+
+// debitPaymentPessimistic debita um valor da carteira do usuário usando Lock Pessimista
+func (uc *PaymentUseCase) debitPaymentPessimistic(ctx context.Context, req SagaActionRequest) error {
+	log.Printf("➡️ [DEBIT PAYMENT] OrderID: %s | UserID: %s | Amount: %d",
+		req.OrderID, req.UserID, req.Amount)
 
 	// 1. Inicia a transação
 	tx, err := uc.repository.BeginTx(ctx)
@@ -74,6 +164,11 @@ func (uc *PaymentUseCase) DebitPayment(ctx context.Context, req SagaActionReques
 		return err
 	}
 
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, "payment.debited"); err != nil {
+		log.Printf("❌ [DEBIT] | OrderID=%s Failed to enqueue outbox event: %v", req.OrderID, err)
+		return err
+	}
+
 	// 6. Commit da transação
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("erro ao comitar débito: %w", err)
@@ -83,10 +178,19 @@ func (uc *PaymentUseCase) DebitPayment(ctx context.Context, req SagaActionReques
 	return nil
 }
 
-// CompensatePayment credita um valor de volta na carteira do usuário (compensação) com idempotência e lock pessimista
+// CompensatePayment credita um valor de volta na carteira do usuário (compensação), usando lock
+// pessimista ou otimista conforme uc.lockMode (ver NewPaymentUseCase)
 func (uc *PaymentUseCase) CompensatePayment(ctx context.Context, req SagaActionRequest) error {
-	log.Printf("↩️ [COMPENSATE PAYMENT] TraceID: %s | OrderID: %s | UserID: %s | Amount: %d",
-		req.TraceID, req.OrderID, req.UserID, req.Amount)
+	if uc.lockMode == lockModeOptimistic {
+		return uc.compensatePaymentOptimistic(ctx, req)
+	}
+	return uc.compensatePaymentPessimistic(ctx, req)
+}
+
+// compensatePaymentPessimistic credita um valor de volta na carteira do usuário (compensação) com idempotência e lock pessimista
+func (uc *PaymentUseCase) compensatePaymentPessimistic(ctx context.Context, req SagaActionRequest) error {
+	log.Printf("↩️ [COMPENSATE PAYMENT] OrderID: %s | UserID: %s | Amount: %d",
+		req.OrderID, req.UserID, req.Amount)
 
 	// 1. Inicia a transação
 	tx, err := uc.repository.BeginTx(ctx)
@@ -119,6 +223,11 @@ func (uc *PaymentUseCase) CompensatePayment(ctx context.Context, req SagaActionR
 		return err
 	}
 
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, "payment.compensated"); err != nil {
+		log.Printf("❌ [COMPENSATE] | OrderID=%s Failed to enqueue outbox event: %v", req.OrderID, err)
+		return err
+	}
+
 	// 5. Commit da transação
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("erro ao comitar compensação: %w", err)
@@ -127,3 +236,152 @@ func (uc *PaymentUseCase) CompensatePayment(ctx context.Context, req SagaActionR
 	log.Printf("✅ [COMPENSATE] Success: OrderID=%s", req.OrderID)
 	return nil
 }
+
+// debitPaymentOptimistic é o equivalente de debitPaymentPessimistic sob concorrência otimista: a
+// carteira é lida sem lock de linha (GetWalletVersion) e a escrita é condicionada à versão lida
+// (DebitWalletVersioned). Um conflito de versão não é um erro de negócio - é reexecutado desde a
+// leitura, até maxOptimisticLockRetries vezes, com backoff + jitter entre tentativas
+func (uc *PaymentUseCase) debitPaymentOptimistic(ctx context.Context, req SagaActionRequest) error {
+	log.Printf("➡️ [DEBIT PAYMENT/OPTIMISTIC] OrderID: %s | UserID: %s | Amount: %d",
+		req.OrderID, req.UserID, req.Amount)
+
+	for attempt := 1; attempt <= maxOptimisticLockRetries; attempt++ {
+		ok, err := uc.tryDebitPaymentOnce(ctx, req)
+		if err != nil {
+			return err
+		}
+		if ok {
+			log.Printf("✅ [DEBIT/OPTIMISTIC] Success: OrderID=%s | Attempt=%d", req.OrderID, attempt)
+			return nil
+		}
+
+		uc.recordOptimisticLockRetry(ctx)
+		log.Printf("♻️ [DEBIT/OPTIMISTIC] Version conflict, retrying | OrderID=%s | Attempt=%d/%d",
+			req.OrderID, attempt, maxOptimisticLockRetries)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(jitteredOptimisticBackoff(attempt)):
+		}
+	}
+
+	return fmt.Errorf("optimistic lock version conflict: max retries exceeded after %d attempts for order %s", maxOptimisticLockRetries, req.OrderID)
+}
+
+// tryDebitPaymentOnce executa uma única tentativa de débito otimista. Retorna ok=false (sem erro)
+// quando a escrita colidiu com outra versão - cabe ao chamador decidir se tenta novamente
+func (uc *PaymentUseCase) tryDebitPaymentOnce(ctx context.Context, req SagaActionRequest) (bool, error) {
+	tx, err := uc.repository.BeginTx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := uc.repository.GetWalletVersion(ctx, tx, req.UserID)
+	if err != nil {
+		log.Printf("❌ DEBIT/OPTIMISTIC FAILED: GetWalletVersion | OrderID=%s | Error=%v", req.OrderID, err)
+		return false, err
+	}
+
+	exists, err := uc.repository.GetPaymentByOrderIDAndType(ctx, tx, req.OrderID, "debit")
+	if err != nil {
+		return false, fmt.Errorf("erro ao verificar idempotência: %w", err)
+	}
+	if exists {
+		log.Printf("ℹ️ [IDEMPOTENCY] Débito já realizado para OrderID=%s", req.OrderID)
+		return true, nil
+	}
+
+	if wallet.CurrentAmount < req.Amount {
+		log.Printf("❌ DEBIT/OPTIMISTIC FAILED: Insufficient funds | UserID=%s", req.UserID)
+		return false, fmt.Errorf("insufficient funds")
+	}
+
+	ok, err := uc.repository.DebitWalletVersioned(ctx, tx, req.UserID, req.OrderID, req.Amount, wallet.Version)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, nil
+	}
+
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, "payment.debited"); err != nil {
+		return false, fmt.Errorf("failed to enqueue outbox event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("erro ao comitar débito: %w", err)
+	}
+	return true, nil
+}
+
+// compensatePaymentOptimistic é o equivalente otimista de compensatePaymentPessimistic
+func (uc *PaymentUseCase) compensatePaymentOptimistic(ctx context.Context, req SagaActionRequest) error {
+	log.Printf("↩️ [COMPENSATE PAYMENT/OPTIMISTIC] OrderID: %s | UserID: %s | Amount: %d",
+		req.OrderID, req.UserID, req.Amount)
+
+	for attempt := 1; attempt <= maxOptimisticLockRetries; attempt++ {
+		ok, err := uc.tryCompensatePaymentOnce(ctx, req)
+		if err != nil {
+			return err
+		}
+		if ok {
+			log.Printf("✅ [COMPENSATE/OPTIMISTIC] Success: OrderID=%s | Attempt=%d", req.OrderID, attempt)
+			return nil
+		}
+
+		uc.recordOptimisticLockRetry(ctx)
+		log.Printf("♻️ [COMPENSATE/OPTIMISTIC] Version conflict, retrying | OrderID=%s | Attempt=%d/%d",
+			req.OrderID, attempt, maxOptimisticLockRetries)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(jitteredOptimisticBackoff(attempt)):
+		}
+	}
+
+	return fmt.Errorf("optimistic lock version conflict: max retries exceeded after %d attempts for order %s", maxOptimisticLockRetries, req.OrderID)
+}
+
+// tryCompensatePaymentOnce executa uma única tentativa de compensação otimista
+func (uc *PaymentUseCase) tryCompensatePaymentOnce(ctx context.Context, req SagaActionRequest) (bool, error) {
+	tx, err := uc.repository.BeginTx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := uc.repository.GetWalletVersion(ctx, tx, req.UserID)
+	if err != nil {
+		log.Printf("❌ COMPENSATE/OPTIMISTIC FAILED: GetWalletVersion | OrderID=%s | Error=%v", req.OrderID, err)
+		return false, err
+	}
+
+	exists, err := uc.repository.GetPaymentByOrderIDAndType(ctx, tx, req.OrderID, "credit")
+	if err != nil {
+		return false, fmt.Errorf("erro ao verificar idempotência: %w", err)
+	}
+	if exists {
+		log.Printf("ℹ️  [IDEMPOTENCY] Pagamento de compensação já processado para OrderID=%s", req.OrderID)
+		return true, nil
+	}
+
+	ok, err := uc.repository.CreditWalletVersioned(ctx, tx, req.UserID, req.OrderID, req.Amount, wallet.Version)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, nil
+	}
+
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, "payment.compensated"); err != nil {
+		return false, fmt.Errorf("failed to enqueue outbox event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("erro ao comitar compensação: %w", err)
+	}
+	return true, nil
+}
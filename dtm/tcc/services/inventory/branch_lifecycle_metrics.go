@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// branchLifecycleMetrics instrumenta o desfecho de negócio de cada fase TCC (try/confirm/cancel)
+// tal como observado por este participante. Duplicado em relação a
+// dtm/tcc/services/orders/branch_lifecycle_metrics.go pelo mesmo motivo de sempre: sem go.mod
+// não há como compartilhar o tipo entre os binários.
+type branchLifecycleMetrics struct {
+	service string
+	branch  string
+
+	tryDuration     metric.Float64Histogram
+	confirmDuration metric.Float64Histogram
+	cancelCount     metric.Int64Counter
+}
+
+// newBranchLifecycleMetrics registra tcc.branch.try.duration, tcc.branch.confirm.duration e
+// tcc.branch.cancel.count no meter informado
+func newBranchLifecycleMetrics(meter metric.Meter, service, branch string) (*branchLifecycleMetrics, error) {
+	tryDuration, err := meter.Float64Histogram(
+		"tcc.branch.try.duration",
+		metric.WithDescription("Duration of the TCC TRY phase as observed by the participant"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tcc.branch.try.duration histogram: %w", err)
+	}
+
+	confirmDuration, err := meter.Float64Histogram(
+		"tcc.branch.confirm.duration",
+		metric.WithDescription("Duration of the TCC CONFIRM phase as observed by the participant"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tcc.branch.confirm.duration histogram: %w", err)
+	}
+
+	cancelCount, err := meter.Int64Counter(
+		"tcc.branch.cancel.count",
+		metric.WithDescription("Number of TCC CANCEL phases executed by the participant"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tcc.branch.cancel.count counter: %w", err)
+	}
+
+	return &branchLifecycleMetrics{
+		service:         service,
+		branch:          branch,
+		tryDuration:     tryDuration,
+		confirmDuration: confirmDuration,
+		cancelCount:     cancelCount,
+	}, nil
+}
+
+func (m *branchLifecycleMetrics) attrs(outcome string) metric.MeasurementOption {
+	return metric.WithAttributes(
+		attribute.String("service", m.service),
+		attribute.String("branch", m.branch),
+		attribute.String("outcome", outcome),
+	)
+}
+
+// RecordTry registra a duração da fase TRY e seu desfecho ("ok", "unchanged" ou "error")
+func (m *branchLifecycleMetrics) RecordTry(ctx context.Context, outcome string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.tryDuration.Record(ctx, duration.Seconds(), m.attrs(outcome))
+}
+
+// RecordConfirm registra a duração da fase CONFIRM e seu desfecho
+func (m *branchLifecycleMetrics) RecordConfirm(ctx context.Context, outcome string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.confirmDuration.Record(ctx, duration.Seconds(), m.attrs(outcome))
+}
+
+// AddCancel incrementa a contagem de fases CANCEL executadas
+func (m *branchLifecycleMetrics) AddCancel(ctx context.Context, outcome string) {
+	if m == nil {
+		return
+	}
+	m.cancelCount.Add(ctx, 1, m.attrs(outcome))
+}
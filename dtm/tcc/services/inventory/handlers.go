@@ -2,32 +2,46 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// startSpanFromPayload creates a child span linked to the propagated trace context
-func startSpanFromPayload(c *gin.Context, operationName string, req TCCActionRequest) (context.Context, trace.Span) {
-	ctx := c.Request.Context()
-
-	if req.TraceID != "" && req.SpanID != "" {
-		parsedTraceID, _ := trace.TraceIDFromHex(req.TraceID)
-		parsedSpanID, _ := trace.SpanIDFromHex(req.SpanID)
-
-		spanContext := trace.NewSpanContext(trace.SpanContextConfig{
-			TraceID:    parsedTraceID,
-			SpanID:     parsedSpanID,
-			TraceFlags: trace.FlagsSampled,
-			Remote:     true,
-		})
-
-		ctx = trace.ContextWithSpanContext(ctx, spanContext)
+// startSpanFromPayload extrai o trace context propagado pelo DTM orchestrator (via o par
+// traceparent/tracestate no payload, já que o DTM não repassa headers HTTP arbitrários) usando o
+// TextMapPropagator configurado, e enriquece o span com os atributos semânticos da fase TCC. Na
+// fase TRY, também linka (trace.Link) o span ao span "tcc.branch.inventory" criado pelo
+// orchestrator ao registrar esta branch, recuperado via branchSpans (ver branch_span_lookup.go)
+func startSpanFromPayload(c *gin.Context, operationName, phase string, req TCCActionRequest) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.MapCarrier{
+		"traceparent": req.Traceparent,
+		"tracestate":  req.Tracestate,
+		"baggage":     req.Baggage,
+	})
+
+	var opts []trace.SpanStartOption
+	if phase == SagaPhaseTry && branchSpans != nil && req.GID != "" {
+		if sc, ok := branchSpans.Lookup(ctx, req.GID, "inventory"); ok {
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+		}
 	}
 
-	return tracer.Start(ctx, operationName)
+	ctx, span := tracer.Start(ctx, operationName, opts...)
+	span.SetAttributes(
+		attribute.String("saga.phase", phase),
+		attribute.String("saga.order_id", req.OrderID),
+		attribute.String("saga.user_id", req.UserID),
+		attribute.String("db.system", "postgresql"),
+	)
+	return ctx, span
 }
 
 // HandleTryDecreaseStock handler para fase TRY do TCC
@@ -40,16 +54,29 @@ func HandleTryDecreaseStock(uc *InventoryUseCase) gin.HandlerFunc {
 			return
 		}
 
-		ctx, span := startSpanFromPayload(c, "inventory.TryDecreaseStock", req)
+		ctx, span := startSpanFromPayload(c, "inventory.TryDecreaseStock", SagaPhaseTry, req)
 		defer span.End()
 
+		start := time.Now()
 		err := uc.TryDecreaseStock(ctx, req)
-		if err != nil {
+		elapsed := time.Since(start)
+		if err != nil && !errors.Is(err, ErrNoChange) {
+			lifecycleM.RecordTry(ctx, "error", elapsed)
 			log.Printf("❌ [TRY]  ORDER_ID %s | Failed: %v", req.OrderID, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "TRY phase failed")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		if errors.Is(err, ErrNoChange) {
+			lifecycleM.RecordTry(ctx, "unchanged", elapsed)
+			span.SetAttributes(attribute.Bool("saga.noop", true))
+			c.JSON(http.StatusOK, gin.H{"status": "unchanged", "order_id": req.OrderID})
+			return
+		}
+
+		lifecycleM.RecordTry(ctx, "ok", elapsed)
 		c.JSON(http.StatusOK, gin.H{"status": "try_success", "order_id": req.OrderID})
 	}
 }
@@ -64,16 +91,29 @@ func HandleConfirmDecreaseStock(uc *InventoryUseCase) gin.HandlerFunc {
 			return
 		}
 
-		ctx, span := startSpanFromPayload(c, "inventory.ConfirmDecreaseStock", req)
+		ctx, span := startSpanFromPayload(c, "inventory.ConfirmDecreaseStock", SagaPhaseConfirm, req)
 		defer span.End()
 
+		start := time.Now()
 		err := uc.ConfirmDecreaseStock(ctx, req)
-		if err != nil {
+		elapsed := time.Since(start)
+		if err != nil && !errors.Is(err, ErrNoChange) {
+			lifecycleM.RecordConfirm(ctx, "error", elapsed)
 			log.Printf("❌ [CONFIRM] ORDER_ID %s | Failed: %v", req.OrderID, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "CONFIRM phase failed")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		if errors.Is(err, ErrNoChange) {
+			lifecycleM.RecordConfirm(ctx, "unchanged", elapsed)
+			span.SetAttributes(attribute.Bool("saga.noop", true))
+			c.JSON(http.StatusOK, gin.H{"status": "unchanged", "order_id": req.OrderID})
+			return
+		}
+
+		lifecycleM.RecordConfirm(ctx, "ok", elapsed)
 		c.JSON(http.StatusOK, gin.H{"status": "confirm_success", "order_id": req.OrderID})
 	}
 }
@@ -88,16 +128,27 @@ func HandleCancelDecreaseStock(uc *InventoryUseCase) gin.HandlerFunc {
 			return
 		}
 
-		ctx, span := startSpanFromPayload(c, "inventory.CancelDecreaseStock", req)
+		ctx, span := startSpanFromPayload(c, "inventory.CancelDecreaseStock", SagaPhaseCancel, req)
 		defer span.End()
 
 		err := uc.CancelDecreaseStock(ctx, req)
-		if err != nil {
+		if err != nil && !errors.Is(err, ErrNoChange) {
+			lifecycleM.AddCancel(ctx, "error")
 			log.Printf("❌ ORDER_ID %s | [CANCEL] Failed: %v", req.OrderID, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "CANCEL phase failed")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		if errors.Is(err, ErrNoChange) {
+			lifecycleM.AddCancel(ctx, "unchanged")
+			span.SetAttributes(attribute.Bool("saga.noop", true))
+			c.JSON(http.StatusOK, gin.H{"status": "unchanged", "order_id": req.OrderID})
+			return
+		}
+
+		lifecycleM.AddCancel(ctx, "ok")
 		c.JSON(http.StatusOK, gin.H{"status": "cancel_success", "order_id": req.OrderID})
 	}
 }
@@ -15,14 +15,26 @@ type ProductInventory struct {
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
-// TCCActionRequest representa o payload das requisições TCC (sempre 1 unidade)
+// TCCActionRequest representa o payload das requisições TCC (sempre 1 unidade). Carrega o trace
+// context como o par traceparent/tracestate do W3C Trace Context, já que o DTM não repassa
+// headers HTTP arbitrários entre o registro da branch e a chamada que ele faz mais tarde
 type TCCActionRequest struct {
-	OrderID    string `json:"order_id"`
-	UserID     string `json:"user_id"`
-	ProductID  string `json:"product_id"`
-	TotalPrice int    `json:"total_price"`
-	TraceID    string `json:"trace_id"`
-	SpanID     string `json:"span_id"`
+	OrderID     string `json:"order_id"`
+	UserID      string `json:"user_id"`
+	ProductID   string `json:"product_id"`
+	TotalPrice  int    `json:"total_price"`
+	Traceparent string `json:"traceparent,omitempty"`
+	Tracestate  string `json:"tracestate,omitempty"`
+
+	// Baggage carrega o header W3C Baggage (ex: "benchmark_run_id=...") propagado junto com
+	// Traceparent/Tracestate pelo orchestrator - ver o campo equivalente em
+	// dtm/tcc/services/orders/models.go
+	Baggage string `json:"baggage,omitempty"`
+
+	// GID identifica a transação TCC que originou esta ação, usado para recuperar o SpanContext
+	// do span de registro desta branch no orchestrator (ver branch_span_lookup.go) e linkar o
+	// span da fase TRY a ele
+	GID string `json:"gid,omitempty"`
 }
 
 // InventoryMovement representa um movimento de estoque (entrada/saída)
@@ -51,8 +63,8 @@ type InventoryRepository interface {
 	// CANCEL: Cancela a reserva de 1 unidade (incrementa stock_available)
 	CancelReserveStock(ctx context.Context, tx Tx, productID, orderID string) error
 
-	// Verificações de idempotência (dentro da transação)
-	GetInventoryMovementByOrderIDAndStatus(ctx context.Context, tx Tx, orderID, status string) (bool, error)
+	// Verificação de idempotência (dentro da transação): um único método de status usado por
+	// TRY/CONFIRM/CANCEL evita a checagem divergente entre fases
 	GetInventoryMovementStatusByOrderID(ctx context.Context, tx Tx, orderID string) (string, error)
 }
 
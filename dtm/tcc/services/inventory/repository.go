@@ -125,28 +125,6 @@ func (r *PostgresInventoryRepository) GetInventoryMovementStatusByOrderID(ctx co
 	return status, nil
 }
 
-// GetInventoryMovementByOrderIDAndStatus verifica se existe movimentação com orderID e status específicos
-func (r *PostgresInventoryRepository) GetInventoryMovementByOrderIDAndStatus(ctx context.Context, tx Tx, orderID, status string) (bool, error) {
-	pgTx := tx.(*PostgresTx).tx
-
-	query := `
-		SELECT movement_id
-		FROM inventory_movements
-		WHERE order_id = $1 AND status = $2
-	`
-
-	var id int64
-	err := pgTx.QueryRow(ctx, query, orderID, status).Scan(&id)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return false, nil
-		}
-		return false, fmt.Errorf("failed to query inventory movements by status: %w", err)
-	}
-
-	return true, nil
-}
-
 // ConfirmReserveStock confirma a venda de 1 unidade (TCC CONFIRM) com lock pessimista
 func (r *PostgresInventoryRepository) ConfirmReserveStock(ctx context.Context, tx Tx, productID, orderID string) error {
 	pgTx := tx.(*PostgresTx).tx
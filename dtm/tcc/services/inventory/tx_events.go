@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Mesma duplicação local de pkg/events já usada pelo orders-service SAGA e pelo inventory-service
+// 2PC/XA (sem go.mod não há como importar o pacote entre pastas) - mesmo stream/subject, para que
+// os três protocolos apareçam no mesmo log de eventos. Este pacote (dtm/tcc/services/inventory)
+// não possui main.go nesta snapshot do repositório (o mesmo gap já registrado para TCC
+// inventory/payment e 2PC payment ao conectar as métricas RED), então initTxEvents existe pronto
+// para ser chamado assim que um entrypoint for adicionado; até lá, InventoryUseCase recebe
+// events=nil e emitTxEvent vira um no-op.
+const txEventsStreamName = "TXEVENTS"
+
+// initTxEvents conecta ao NATS e garante o stream TXEVENTS (armazenamento em arquivo, subjects
+// "tx.events.>") usado pelo emissor de eventos de ciclo de vida da transação
+func initTxEvents(ctx context.Context, natsURL string) (jetstream.JetStream, error) {
+	nc, err := nats.Connect(natsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     txEventsStreamName,
+		Subjects: []string{"tx.events.>"},
+		Storage:  jetstream.FileStorage,
+	}); err != nil {
+		return nil, err
+	}
+
+	return js, nil
+}
+
+// emitTxEvent publica um evento de transição de fase TCC (tcc.try, tcc.confirm, tcc.cancel), sem
+// interromper a fase em caso de falha (o log de eventos é observacional, não faz parte do
+// caminho crítico)
+func emitTxEvent(ctx context.Context, js jetstream.JetStream, eventType string, req TCCActionRequest) {
+	if js == nil {
+		return
+	}
+
+	event := TxEvent{
+		OrderID:   req.OrderID,
+		UserID:    req.UserID,
+		ProductID: req.ProductID,
+		EventType: eventType,
+		EmittedAt: time.Now(),
+	}
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		event.TraceID = span.SpanContext().TraceID().String()
+		event.SpanID = span.SpanContext().SpanID().String()
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("⚠️ [TX EVENTS] failed to marshal event %s | OrderID=%s | Error=%v", eventType, req.OrderID, err)
+		return
+	}
+
+	subject := "tx.events." + eventType
+	if _, err := js.Publish(ctx, subject, payload); err != nil {
+		log.Printf("⚠️ [TX EVENTS] failed to publish event %s | OrderID=%s | Error=%v", eventType, req.OrderID, err)
+	}
+}
+
+// TxEvent é a mesma forma de pkg/events.TransactionEvent, duplicada aqui pela mesma razão de
+// initTxEvents - sem go.mod, pkg/events não pode ser importado diretamente
+type TxEvent struct {
+	GID        string    `json:"gid,omitempty"`
+	OrderID    string    `json:"order_id"`
+	TraceID    string    `json:"trace_id,omitempty"`
+	SpanID     string    `json:"span_id,omitempty"`
+	UserID     string    `json:"user_id,omitempty"`
+	ProductID  string    `json:"product_id,omitempty"`
+	EventType  string    `json:"event_type"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	EmittedAt  time.Time `json:"emitted_at"`
+}
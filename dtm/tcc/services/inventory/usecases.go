@@ -2,57 +2,128 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"strings"
+
+	"github.com/nats-io/nats.go/jetstream"
 )
 
 var ErrNotFound = errors.New("not found")
 
+// ErrNoChange sinaliza que a movimentação de estoque já estava no estado alvo (reentrega do DTM
+// após um commit anterior) e a escrita foi pulada
+var ErrNoChange = errors.New("no change: inventory movement already applied")
+
 // InventoryUseCase encapsula a lógica de negócio de inventário
 type InventoryUseCase struct {
 	repository InventoryRepository
+	sagaLog    SagaLogRepository
+	outbox     OutboxRepository
+	txEvents   jetstream.JetStream
 }
 
 // NewInventoryUseCase cria uma nova instância do caso de uso
-func NewInventoryUseCase(repository InventoryRepository) *InventoryUseCase {
+func NewInventoryUseCase(repository InventoryRepository, sagaLog SagaLogRepository, outbox OutboxRepository, txEvents jetstream.JetStream) *InventoryUseCase {
 	return &InventoryUseCase{
 		repository: repository,
+		sagaLog:    sagaLog,
+		outbox:     outbox,
+		txEvents:   txEvents,
+	}
+}
+
+// appendSagaEvent grava o evento no log de auditoria sem interromper a fase TCC em caso de falha
+func (uc *InventoryUseCase) appendSagaEvent(ctx context.Context, req TCCActionRequest, phase, status string) {
+	if uc.sagaLog == nil {
+		return
+	}
+
+	event := &SagaEvent{
+		OrderID:     req.OrderID,
+		Service:     "inventory",
+		Phase:       phase,
+		Status:      status,
+		PayloadHash: hashPayload(req),
+		TraceID:     traceIDFromTraceparent(req.Traceparent),
+	}
+	if err := uc.sagaLog.AppendEvent(ctx, event); err != nil {
+		log.Printf("⚠️ [SAGA LOG] failed to append event | OrderID=%s Phase=%s | Error=%v", req.OrderID, phase, err)
 	}
 }
 
+// enqueueOutboxEvent grava, dentro da mesma transação de negócio, um evento descrevendo a
+// mudança de fase TCC que acabou de ser aplicada. Ao contrário do saga_events (gravado após o
+// commit, apenas para auditoria), esta entrada é o que o relay assíncrono entrega ao callback
+// configurado - por isso precisa existir atomicamente junto com a mudança de estado.
+func (uc *InventoryUseCase) enqueueOutboxEvent(ctx context.Context, tx Tx, req TCCActionRequest, phase string) error {
+	if uc.outbox == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	event := &OutboxEvent{
+		OrderID: req.OrderID,
+		Phase:   phase,
+		Status:  OutboxStatusPending,
+		Payload: payload,
+	}
+	return uc.outbox.EnqueueOutboxEvent(ctx, tx, event)
+}
+
+// beginGuardedPhase reúne o preâmbulo idêntico nas três fases TCC abaixo: abre a transação de
+// negócio, trava o produto (FOR UPDATE) e lê o status de idempotência de
+// GetInventoryMovementStatusByOrderID, devolvendo a tx já aberta para o chamador continuar
+// (ou tx.Rollback()/defer cuida de fechar, se o chamador não chegar a tx.Commit()).
+func (uc *InventoryUseCase) beginGuardedPhase(ctx context.Context, productID, orderID string) (Tx, *ProductInventory, string, error) {
+	tx, err := uc.repository.BeginTx(ctx)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	product, err := uc.repository.GetProductForUpdate(ctx, tx, productID)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, "", err
+	}
+
+	status, err := uc.repository.GetInventoryMovementStatusByOrderID(ctx, tx, orderID)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, "", err
+	}
+
+	return tx, product, status, nil
+}
+
 // TryDecreaseStock fase TRY do TCC - reserva 1 unidade do estoque
 func (uc *InventoryUseCase) TryDecreaseStock(ctx context.Context, req TCCActionRequest) error {
 	log.Printf("📦 [TRY] Reserve stock: ProductID=%s, Quantity=1, OrderID=%s",
 		req.ProductID, req.OrderID)
 
-	// Inicia transação
-	tx, err := uc.repository.BeginTx(ctx)
+	tx, product, status, err := uc.beginGuardedPhase(ctx, req.ProductID, req.OrderID)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	// Obtém o produto com lock pessimista
-	product, err := uc.repository.GetProductForUpdate(ctx, tx, req.ProductID)
-	if err != nil {
-		return err
-	}
-
 	// Valida estoque disponível
 	if product.StockAvailable < 1 {
 		log.Printf("❌ [TRY] Insufficient stock for product %s: available=%d", req.ProductID, product.StockAvailable)
 		return ErrInsufficientStock
 	}
 
-	// Verifica idempotência - se já existe movimentação com status pending
-	exists, err := uc.repository.GetInventoryMovementByOrderIDAndStatus(ctx, tx, req.OrderID, "pending")
-	if err != nil {
-		return err
-	}
-
-	if exists {
-		log.Printf("ℹ️ [TRY] Inventory movement already pending for OrderID=%s", req.OrderID)
-		return nil
+	// Verifica idempotência - se já existe movimentação para este pedido, a fase TRY já foi
+	// aplicada (reentrega do DTM); usa o mesmo status lido por beginGuardedPhase para CONFIRM/CANCEL
+	if status != "" {
+		log.Printf("ℹ️ [TRY] Inventory movement already exists (status=%s) for OrderID=%s", status, req.OrderID)
+		return ErrNoChange
 	}
 
 	// Executa a reserva
@@ -61,11 +132,18 @@ func (uc *InventoryUseCase) TryDecreaseStock(ctx context.Context, req TCCActionR
 		return err
 	}
 
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, SagaPhaseTry); err != nil {
+		log.Printf("❌ [TRY] Failed to enqueue outbox event: %v", err)
+		return err
+	}
+
 	// Commit da transação
 	if err := tx.Commit(); err != nil {
 		return err
 	}
 
+	uc.appendSagaEvent(ctx, req, SagaPhaseTry, "ok")
+	emitTxEvent(ctx, uc.txEvents, "tcc.try", req)
 	return nil
 }
 
@@ -74,28 +152,17 @@ func (uc *InventoryUseCase) ConfirmDecreaseStock(ctx context.Context, req TCCAct
 	log.Printf("✅ [CONFIRM] Confirm stock decrease: ProductID=%s, Quantity=1, OrderID=%s",
 		req.ProductID, req.OrderID)
 
-	// Inicia transação
-	tx, err := uc.repository.BeginTx(ctx)
+	tx, _, status, err := uc.beginGuardedPhase(ctx, req.ProductID, req.OrderID)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	// Obtém o produto com lock pessimista
-	_, err = uc.repository.GetProductForUpdate(ctx, tx, req.ProductID)
-	if err != nil {
-		return err
-	}
-
-	// Verifica idempotência - se já existe movimentação com status completed
-	exists, err := uc.repository.GetInventoryMovementByOrderIDAndStatus(ctx, tx, req.OrderID, "completed")
-	if err != nil {
-		return err
-	}
-
-	if exists {
+	// Verifica idempotência - mesmo status lido por beginGuardedPhase para TRY/CANCEL, eliminando
+	// a divergência que antes existia entre esta checagem e a de CancelDecreaseStock
+	if status == "completed" {
 		log.Printf("ℹ️ [CONFIRM] Inventory movement already completed for OrderID=%s", req.OrderID)
-		return nil
+		return ErrNoChange
 	}
 
 	// Executa a confirmação
@@ -104,11 +171,18 @@ func (uc *InventoryUseCase) ConfirmDecreaseStock(ctx context.Context, req TCCAct
 		return err
 	}
 
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, SagaPhaseConfirm); err != nil {
+		log.Printf("❌ [CONFIRM] Failed to enqueue outbox event: %v", err)
+		return err
+	}
+
 	// Commit da transação
 	if err := tx.Commit(); err != nil {
 		return err
 	}
 
+	uc.appendSagaEvent(ctx, req, SagaPhaseConfirm, "ok")
+	emitTxEvent(ctx, uc.txEvents, "tcc.confirm", req)
 	return nil
 }
 
@@ -117,34 +191,21 @@ func (uc *InventoryUseCase) CancelDecreaseStock(ctx context.Context, req TCCActi
 	log.Printf("🔄 [CANCEL] Cancel stock reservation: ProductID=%s, Quantity=1, OrderID=%s",
 		req.ProductID, req.OrderID)
 
-	// Inicia transação
-	tx, err := uc.repository.BeginTx(ctx)
+	tx, _, status, err := uc.beginGuardedPhase(ctx, req.ProductID, req.OrderID)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	// Obtém o produto com lock pessimista
-	_, err = uc.repository.GetProductForUpdate(ctx, tx, req.ProductID)
-	if err != nil {
-		return err
-	}
-
-	// Verifica idempotência - se já existe movimentação com status rejected
-	status, err := uc.repository.GetInventoryMovementStatusByOrderID(ctx, tx, req.OrderID)
-	if err != nil {
-		return err
-	}
-
 	// there is nothing to cancel
 	if status == "" {
 		log.Printf("ℹ️ [CANCEL] there is nothing to cancel for OrderID=%s", req.OrderID)
-		return nil
+		return ErrNoChange
 	}
 
 	// idempotency
 	if status == "rejected" {
-		return nil
+		return ErrNoChange
 	}
 
 	if status == "completed" {
@@ -157,14 +218,31 @@ func (uc *InventoryUseCase) CancelDecreaseStock(ctx context.Context, req TCCActi
 		return err
 	}
 
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, SagaPhaseCancel); err != nil {
+		log.Printf("❌ [CANCEL] Failed to enqueue outbox event: %v", err)
+		return err
+	}
+
 	// Commit da transação
 	if err := tx.Commit(); err != nil {
 		return err
 	}
 
+	uc.appendSagaEvent(ctx, req, SagaPhaseCancel, "ok")
+	emitTxEvent(ctx, uc.txEvents, "tcc.cancel", req)
 	return nil
 }
 
+// traceIDFromTraceparent extrai o trace-id de um header traceparent W3C (formato
+// 00-{trace-id}-{parent-id}-{flags}), usado apenas para preencher o log de auditoria
+func traceIDFromTraceparent(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 {
+		return ""
+	}
+	return parts[1]
+}
+
 // Erros customizados
 var (
 	ErrInsufficientStock = &InventoryError{Message: "insufficient stock"}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// branchSpanStoreKeyPrefix namespaces as chaves deste registro no Redis compartilhado - mesmo
+// Redis usado por RESUME_CALLBACK_BACKEND=redis (ver resume_callback_redis.go), mas com um
+// prefixo próprio já que guarda um tipo de dado diferente (um SpanContext, não um CallbackResult)
+const branchSpanStoreKeyPrefix = "tcc:branchspan:"
+
+// branchSpanStoreTTL cobre o tempo entre o registro de uma branch e o DTM efetivamente chamar o
+// try/confirm/cancel do participante - generoso o bastante para picos de fila do DTM sem deixar
+// chaves orfãs se acumulando indefinidamente no Redis
+const branchSpanStoreTTL = 5 * time.Minute
+
+// branchSpanStore persiste, por (gid, branch), o SpanContext do span "tcc.branch.<branch>"
+// criado durante o registro da branch no orchestrator. Diferente do ResumeCallback (que pode
+// rodar só em memória para uma única réplica), vincular o span do participante ao span de
+// registro exige um armazenamento genuinamente compartilhado entre processos - orders,
+// inventory e payment rodam em binários separados mesmo numa única réplica - então este store
+// não tem um modo "memory" equivalente e sempre depende de Redis.
+type branchSpanStore struct {
+	client *redis.Client
+}
+
+// newBranchSpanStore cria um store apoiado no cliente Redis informado
+func newBranchSpanStore(client *redis.Client) *branchSpanStore {
+	return &branchSpanStore{client: client}
+}
+
+func branchSpanStoreKey(gid, branch string) string {
+	return branchSpanStoreKeyPrefix + gid + ":" + branch
+}
+
+// Store grava o SpanContext do span de registro da branch para que o participante correspondente
+// possa recuperá-lo depois e linká-lo ao seu próprio span via trace.Link. Falhas aqui são
+// logadas mas não interrompem o registro da branch - perder o link de tracing não deve derrubar
+// a transação distribuída.
+func (s *branchSpanStore) Store(ctx context.Context, gid, branch string, sc trace.SpanContext) {
+	encoded := encodeSpanContext(sc)
+	if err := s.client.Set(ctx, branchSpanStoreKey(gid, branch), encoded, branchSpanStoreTTL).Err(); err != nil {
+		log.Printf("⚠️ [BRANCH SPAN STORE] failed to persist span context for gid=%s branch=%s: %v", gid, branch, err)
+	}
+}
+
+// Lookup recupera o SpanContext gravado por Store, caso ainda exista (TTL expirado ou nunca
+// gravado contam como "não encontrado", não como erro)
+func (s *branchSpanStore) Lookup(ctx context.Context, gid, branch string) (trace.SpanContext, bool) {
+	raw, err := s.client.Get(ctx, branchSpanStoreKey(gid, branch)).Result()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("⚠️ [BRANCH SPAN STORE] failed to look up span context for gid=%s branch=%s: %v", gid, branch, err)
+		}
+		return trace.SpanContext{}, false
+	}
+
+	sc, ok := decodeSpanContext(raw)
+	return sc, ok
+}
+
+// encodeSpanContext serializa um SpanContext como "traceID-spanID-traceFlags", formato
+// suficiente para reconstruir um Link remoto (não precisamos do TraceState aqui)
+func encodeSpanContext(sc trace.SpanContext) string {
+	return fmt.Sprintf("%s-%s-%02x", sc.TraceID(), sc.SpanID(), byte(sc.TraceFlags()))
+}
+
+func decodeSpanContext(raw string) (trace.SpanContext, bool) {
+	parts := strings.Split(raw, "-")
+	if len(parts) != 3 {
+		return trace.SpanContext{}, false
+	}
+
+	traceID, err := trace.TraceIDFromHex(parts[0])
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	spanID, err := trace.SpanIDFromHex(parts[1])
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	var flags trace.TraceFlags
+	if _, err := fmt.Sscanf(parts[2], "%02x", &flags); err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	})
+	return sc, true
+}
+
+// initBranchSpanStore conecta ao Redis usado para compartilhar SpanContexts de branch entre os
+// três processos TCC, devolvendo uma função de shutdown a ser chamada via defer em main()
+func initBranchSpanStore() (*branchSpanStore, func()) {
+	opts, err := redis.ParseURL(getEnv("REDIS_URL", "redis://redis:6379/0"))
+	if err != nil {
+		log.Fatalf("Failed to parse REDIS_URL for branch span store: %v", err)
+	}
+
+	client := redis.NewClient(opts)
+	return newBranchSpanStore(client), func() {
+		if err := client.Close(); err != nil {
+			log.Printf("Error closing branch span store redis client: %v", err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// dbSpanCtxKey/dbQueryTracer instrumentam pgx.QueryTracer localmente: cada dtm/*/services/* é
+// um binário Go independente (sem go.mod compartilhado neste repositório), então cada serviço
+// que precisa desse hook mantém sua própria cópia em vez de depender de um pacote compartilhado.
+const maxStatementLength = 2000
+
+type dbSpanCtxKey struct{}
+
+// dbQueryTracer implementa pgx.QueryTracer, abrindo um span "db.query" para cada
+// Query/Exec/BeginTx executado através do pool instrumentado
+type dbQueryTracer struct {
+	tracer trace.Tracer
+}
+
+func newDBQueryTracer(name string) *dbQueryTracer {
+	return &dbQueryTracer{tracer: otel.Tracer(name)}
+}
+
+func (t *dbQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, span := t.tracer.Start(ctx, "db.query")
+	span.SetAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.statement", truncateStatement(data.SQL)),
+	)
+	return context.WithValue(ctx, dbSpanCtxKey{}, span)
+}
+
+func (t *dbQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span, ok := ctx.Value(dbSpanCtxKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, "query failed")
+	}
+}
+
+func truncateStatement(sql string) string {
+	runes := []rune(sql)
+	if len(runes) <= maxStatementLength {
+		return sql
+	}
+	return string(runes[:maxStatementLength]) + "…"
+}
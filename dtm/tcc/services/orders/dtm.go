@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/dtm-labs/client/dtmcli"
 	"github.com/go-resty/resty/v2"
@@ -11,12 +12,13 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
 // TCCOrchestrator abstrai as operações TCC do DTM
 type TCCOrchestrator interface {
-	CreateOrderTCC(ctx context.Context, req CreateOrderRequest) (string, string, error)
+	CreateOrderTCC(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error)
 }
 
 // DTMTCCOrchestrator implementa TCCOrchestrator usando DTM
@@ -28,22 +30,33 @@ func NewDTMTCCOrchestrator() *DTMTCCOrchestrator {
 }
 
 // CreateOrderTCC registra as branches TCC e retorna imediatamente (assíncrono)
-func (to *DTMTCCOrchestrator) CreateOrderTCC(ctx context.Context, req CreateOrderRequest) (string, string, error) {
+func (to *DTMTCCOrchestrator) CreateOrderTCC(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error) {
 	tracer := otel.Tracer("dtm-tcc-orchestrator")
 
 	// Criar span apenas para o REGISTRO das branches (rápido!)
 	ctx, registrationSpan := tracer.Start(ctx, "TCC-Registration")
 	defer registrationSpan.End()
 
+	start := time.Now()
+	dtxM.AddInflight(ctx, "tcc", 1)
+	defer dtxM.AddInflight(ctx, "tcc", -1)
+
 	// Gerar OrderID ANTES de registrar as branches
-	orderID := uuid.New().String()
-	var gid string
+	orderID = uuid.New().String()
+
+	// Injeta o trace context atual (traceparent/tracestate/baggage) para propagar até os
+	// participantes via o payload TCC - o DTM não repassa headers HTTP arbitrários nas chamadas
+	// que faz depois. O Baggage (ex: benchmark_run_id=... anexado pelo handler de entrada) viaja
+	// pelo mesmo carrier que traceparent/tracestate; esquecer de repassá-lo faria os atributos de
+	// negócio anexados via baggage.ContextWithBaggage não sobreviverem ao salto até inventory/payment
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	traceparent := carrier.Get("traceparent")
+	tracestate := carrier.Get("tracestate")
+	baggage := carrier.Get("baggage")
 
-	// Extract trace context from the incoming context
-	var traceID, spanID string
 	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
 		traceID = span.SpanContext().TraceID().String()
-		spanID = span.SpanContext().SpanID().String()
 	}
 
 	registrationSpan.SetAttributes(
@@ -58,7 +71,7 @@ func (to *DTMTCCOrchestrator) CreateOrderTCC(ctx context.Context, req CreateOrde
 	}()
 	gid = dtmcli.MustGenGid(getEnv("DTM_SERVER", "http://dtm:36789/api/dtmsvr"))
 	if gid == "" {
-		return orderID, "", fmt.Errorf("internal error: failed to generate GID")
+		return orderID, "", traceID, fmt.Errorf("internal error: failed to generate GID")
 	}
 
 	// Adicionar atributos ao span de registro
@@ -74,12 +87,14 @@ func (to *DTMTCCOrchestrator) CreateOrderTCC(ctx context.Context, req CreateOrde
 
 	// Preparar payload com trace context (sempre 1 unidade por pedido)
 	payload := TCCActionRequest{
-		OrderID:    orderID,
-		UserID:     req.UserID,
-		ProductID:  req.ProductID,
-		TotalPrice: req.TotalPrice,
-		TraceID:    traceID,
-		SpanID:     spanID,
+		OrderID:     orderID,
+		UserID:      req.UserID,
+		ProductID:   req.ProductID,
+		TotalPrice:  req.TotalPrice,
+		Traceparent: traceparent,
+		Tracestate:  tracestate,
+		Baggage:     baggage,
+		GID:         gid,
 	}
 
 	// Criar transação TCC usando TccGlobalTransaction
@@ -88,14 +103,16 @@ func (to *DTMTCCOrchestrator) CreateOrderTCC(ctx context.Context, req CreateOrde
 	paymentServiceURL := getEnv("PAYMENT_SERVICE_URL", "http://payment-service:8082")
 
 	// Registrar as 3 branches no DTM (retorna rápido, apenas registro!)
-	err := dtmcli.TccGlobalTransaction(getEnv("DTM_SERVER", "http://dtm:36789/api/dtmsvr"), gid, func(tcc *dtmcli.Tcc) (*resty.Response, error) {
+	err = dtmcli.TccGlobalTransaction(getEnv("DTM_SERVER", "http://dtm:36789/api/dtmsvr"), gid, func(tcc *dtmcli.Tcc) (*resty.Response, error) {
 		// Branch 1: Orders - cria a ordem
-		resp, err := tcc.CallBranch(
-			&payload,
-			ordersServiceURL+"/api/orders/try",
-			ordersServiceURL+"/api/orders/confirm",
-			ordersServiceURL+"/api/orders/cancel",
-		)
+		resp, err := MonitoredBranchCall(ctx, tracer, gid, "orders", ordersServiceURL+"/api/orders/try", func() (*resty.Response, error) {
+			return tcc.CallBranch(
+				&payload,
+				ordersServiceURL+"/api/orders/try",
+				ordersServiceURL+"/api/orders/confirm",
+				ordersServiceURL+"/api/orders/cancel",
+			)
+		})
 		if err != nil {
 			registrationSpan.AddEvent("Orders branch registration failed")
 			return resp, fmt.Errorf("failed to register orders TCC branch: %w", err)
@@ -103,12 +120,14 @@ func (to *DTMTCCOrchestrator) CreateOrderTCC(ctx context.Context, req CreateOrde
 		registrationSpan.AddEvent("Orders branch registered")
 
 		// Branch 2: Inventory - reserva estoque
-		resp, err = tcc.CallBranch(
-			&payload,
-			inventoryServiceURL+"/api/inventory/try",
-			inventoryServiceURL+"/api/inventory/confirm",
-			inventoryServiceURL+"/api/inventory/cancel",
-		)
+		resp, err = MonitoredBranchCall(ctx, tracer, gid, "inventory", inventoryServiceURL+"/api/inventory/try", func() (*resty.Response, error) {
+			return tcc.CallBranch(
+				&payload,
+				inventoryServiceURL+"/api/inventory/try",
+				inventoryServiceURL+"/api/inventory/confirm",
+				inventoryServiceURL+"/api/inventory/cancel",
+			)
+		})
 		if err != nil {
 			registrationSpan.AddEvent("Inventory branch registration failed")
 			return resp, fmt.Errorf("failed to register inventory TCC branch: %w", err)
@@ -116,12 +135,14 @@ func (to *DTMTCCOrchestrator) CreateOrderTCC(ctx context.Context, req CreateOrde
 		registrationSpan.AddEvent("Inventory branch registered")
 
 		// Branch 3: Payment - processa pagamento
-		resp, err = tcc.CallBranch(
-			&payload,
-			paymentServiceURL+"/api/payment/try",
-			paymentServiceURL+"/api/payment/confirm",
-			paymentServiceURL+"/api/payment/cancel",
-		)
+		resp, err = MonitoredBranchCall(ctx, tracer, gid, "payment", paymentServiceURL+"/api/payment/try", func() (*resty.Response, error) {
+			return tcc.CallBranch(
+				&payload,
+				paymentServiceURL+"/api/payment/try",
+				paymentServiceURL+"/api/payment/confirm",
+				paymentServiceURL+"/api/payment/cancel",
+			)
+		})
 		if err != nil {
 			registrationSpan.AddEvent("Payment branch registration failed")
 			return resp, fmt.Errorf("failed to register payment TCC branch: %w", err)
@@ -132,14 +153,18 @@ func (to *DTMTCCOrchestrator) CreateOrderTCC(ctx context.Context, req CreateOrde
 		return resp, nil
 	})
 
+	// outcome aqui reflete o aceite do REGISTRO das 3 branches, não o desfecho final do TCC
+	// (confirm/cancel acontecem depois, de forma assíncrona, nos handlers de cada participante)
 	if err != nil {
+		dtxM.RecordTransaction(ctx, "tcc", "registration_failed", time.Since(start))
 		registrationSpan.RecordError(err)
 		registrationSpan.SetStatus(codes.Error, "TCC branch registration failed")
 		log.Printf("❌ TCC REGISTRATION FAILED | TraceID: %s | GID: %s | Error: %v", traceID, gid, err)
-		return orderID, traceID, fmt.Errorf("TCC branch registration failed: %w", err)
+		return orderID, gid, traceID, fmt.Errorf("TCC branch registration failed: %w", err)
 	}
 
+	dtxM.RecordTransaction(ctx, "tcc", "registered", time.Since(start))
 	registrationSpan.SetStatus(codes.Ok, "TCC branches registered successfully")
 	log.Printf("✅ TCC REGISTERED | TraceID: %s | GID: %s | OrderID: %s (DTM executing asynchronously)", traceID, gid, orderID)
-	return orderID, traceID, nil
+	return orderID, gid, traceID, nil
 }
@@ -2,13 +2,40 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// writeTCCResponse traduz o erro de uma fase TCC para a resposta HTTP que o DTM espera: erros de
+// negócio (BusinessError) viram 409 com {"dtm_result":"FAILURE"}, a convenção do DTM para "a
+// branch falhou de forma definitiva, não adianta retentar" - dispara o rollback/cancelamento
+// global imediatamente em vez de fazer o DTM bater na branch repetidamente até o timeout. Qualquer
+// outro erro (infraestrutura: banco fora do ar, timeout, ...) continua 500, para que o DTM
+// retente - esse é o comportamento que já existia antes desta distinção
+func writeTCCResponse(c *gin.Context, span trace.Span, err error, phaseFailedMsg string) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, phaseFailedMsg)
+
+	var be BusinessError
+	if errors.As(err, &be) && be.BusinessError() {
+		log.Printf("⛔ %s (business error, DTM should not retry): %v", phaseFailedMsg, err)
+		c.JSON(http.StatusConflict, gin.H{"dtm_result": "FAILURE", "error": err.Error()})
+		return
+	}
+
+	log.Printf("❌ %s: %v", phaseFailedMsg, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": phaseFailedMsg})
+}
+
 // HandleCreateOrder handler para criação de pedidos - apenas registra branches TCC
 func HandleCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -22,7 +49,7 @@ func HandleCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
 		ctx, span := startSpanFromPayload(c, "orders.CreateOrder", req)
 		defer span.End()
 
-		orderID, traceID, err := uc.CreateOrder(ctx, req)
+		orderID, gid, traceID, err := uc.CreateOrder(ctx, req)
 		if err != nil {
 			log.Printf("❌ Failed to register TCC branches: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register TCC branches", "details": err.Error()})
@@ -32,6 +59,7 @@ func HandleCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
 		// Retorna 202 Accepted - processamento assíncrono via DTM
 		c.JSON(http.StatusAccepted, gin.H{
 			"order_id": orderID,
+			"gid":      gid,
 			"trace_id": traceID,
 			"status":   "processing",
 			"message":  "Order is being processed asynchronously via TCC",
@@ -39,6 +67,31 @@ func HandleCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
 	}
 }
 
+// HandleBatchCreateOrder handler para criação de pedidos em lote - registra as branches TCC de
+// cada item em paralelo e retorna um array de resultados preservando a ordem de entrada
+func HandleBatchCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqs []CreateOrderRequest
+		if err := c.ShouldBindJSON(&reqs); err != nil {
+			log.Printf("❌ Invalid batch request body: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+			return
+		}
+
+		if len(reqs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "batch must contain at least one order"})
+			return
+		}
+
+		ctx, span := tracer.Start(c.Request.Context(), "orders.BatchCreateOrder")
+		defer span.End()
+
+		results, _ := uc.BatchPlaceOrders(ctx, reqs)
+
+		c.JSON(http.StatusAccepted, results)
+	}
+}
+
 // HandleTryCreateOrder handler para fase TRY do TCC
 func HandleTryCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -50,15 +103,26 @@ func HandleTryCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
 			return
 		}
 
-		ctx, span := startSpanFromActionPayload(c, "orders.TryCreateOrder", req)
+		ctx, span := startSpanFromActionPayload(c, "orders.TryCreateOrder", SagaPhaseTry, req)
 		defer span.End()
 
-		if err := uc.TryCreateOrder(ctx, req); err != nil {
-			log.Printf("❌ TRY ORDER_ID %s | FAILED : %v", req.OrderID, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "TRY phase failed"})
+		start := time.Now()
+		err := uc.TryCreateOrder(ctx, req)
+		elapsed := time.Since(start)
+		dtxM.RecordBranch(ctx, "orders", "try", elapsed)
+		if err != nil && !errors.Is(err, ErrNoChange) {
+			lifecycleM.RecordTry(ctx, "error", elapsed)
+			writeTCCResponse(c, span, err, "TRY phase failed")
+			return
+		}
+
+		if errors.Is(err, ErrNoChange) {
+			lifecycleM.RecordTry(ctx, "unchanged", elapsed)
+			c.JSON(http.StatusOK, gin.H{"status": "unchanged"})
 			return
 		}
 
+		lifecycleM.RecordTry(ctx, "ok", elapsed)
 		c.JSON(http.StatusOK, gin.H{"status": "TRY success"})
 	}
 }
@@ -73,15 +137,26 @@ func HandleConfirmCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
 			return
 		}
 
-		ctx, span := startSpanFromActionPayload(c, "orders.ConfirmCreateOrder", req)
+		ctx, span := startSpanFromActionPayload(c, "orders.ConfirmCreateOrder", SagaPhaseConfirm, req)
 		defer span.End()
 
-		if err := uc.ConfirmCreateOrder(ctx, req); err != nil {
-			log.Printf("❌ CONFIRM ORDER_ID %s | FAILED: %v", req.OrderID, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "CONFIRM phase failed"})
+		start := time.Now()
+		err := uc.ConfirmCreateOrder(ctx, req)
+		elapsed := time.Since(start)
+		dtxM.RecordBranch(ctx, "orders", "confirm", elapsed)
+		if err != nil && !errors.Is(err, ErrNoChange) {
+			lifecycleM.RecordConfirm(ctx, "error", elapsed)
+			writeTCCResponse(c, span, err, "CONFIRM phase failed")
 			return
 		}
 
+		if errors.Is(err, ErrNoChange) {
+			lifecycleM.RecordConfirm(ctx, "unchanged", elapsed)
+			c.JSON(http.StatusOK, gin.H{"status": "unchanged"})
+			return
+		}
+
+		lifecycleM.RecordConfirm(ctx, "ok", elapsed)
 		c.JSON(http.StatusOK, gin.H{"status": "CONFIRM success"})
 	}
 }
@@ -96,62 +171,127 @@ func HandleCancelCreateOrder(uc *OrderUseCase) gin.HandlerFunc {
 			return
 		}
 
-		ctx, span := startSpanFromActionPayload(c, "orders.CancelCreateOrder", req)
+		ctx, span := startSpanFromActionPayload(c, "orders.CancelCreateOrder", SagaPhaseCancel, req)
 		defer span.End()
 
-		if err := uc.CancelCreateOrder(ctx, req); err != nil {
-			log.Printf("❌ CANCEL FAILED: ORDER_ID %s | %v", req.OrderID, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "CANCEL phase failed"})
+		start := time.Now()
+		err := uc.CancelCreateOrder(ctx, req)
+		dtxM.RecordBranch(ctx, "orders", "cancel", time.Since(start))
+		if err != nil && !errors.Is(err, ErrNoChange) {
+			lifecycleM.AddCancel(ctx, "error")
+			writeTCCResponse(c, span, err, "CANCEL phase failed")
 			return
 		}
 
+		if errors.Is(err, ErrNoChange) {
+			lifecycleM.AddCancel(ctx, "unchanged")
+			c.JSON(http.StatusOK, gin.H{"status": "unchanged"})
+			return
+		}
+
+		lifecycleM.AddCancel(ctx, "ok")
 		c.JSON(http.StatusOK, gin.H{"status": "CANCEL success"})
 	}
 }
 
-// HandleHealth handler para health check
-func HandleHealth() gin.HandlerFunc {
+// HandleGetSagaChain handler que retorna a cadeia de eventos registrada no log de auditoria da saga
+func HandleGetSagaChain(sagaLog SagaLogRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "healthy", "service": "orders-service-tcc"})
+		orderID := c.Param("order_id")
+
+		chain, err := sagaLog.GetChain(c.Request.Context(), orderID)
+		if err != nil {
+			log.Printf("❌ Failed to load saga chain | OrderID=%s | Error=%v", orderID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load saga chain", "details": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"order_id": orderID, "events": chain})
 	}
 }
 
-func startSpanFromPayload(c *gin.Context, operationName string, req CreateOrderRequest) (context.Context, trace.Span) {
-	ctx := c.Request.Context()
+// defaultAwaitTimeout é usado quando ?timeout= não é informado ou não é um duration válido
+const defaultAwaitTimeout = 30 * time.Second
 
-	if req.TraceID != "" && req.SpanID != "" {
-		parsedTraceID, _ := trace.TraceIDFromHex(req.TraceID)
-		parsedSpanID, _ := trace.SpanIDFromHex(req.SpanID)
+// maxAwaitTimeout limita quanto tempo um long-poll pode segurar a conexão aberta, para não
+// esgotar o pool de goroutines/handlers do Gin sob carga
+const maxAwaitTimeout = 60 * time.Second
 
-		spanContext := trace.NewSpanContext(trace.SpanContextConfig{
-			TraceID:    parsedTraceID,
-			SpanID:     parsedSpanID,
-			TraceFlags: trace.FlagsSampled,
-			Remote:     true,
-		})
+// HandleAwaitOrder implementa o long-poll GET /api/orders/:gid/await?timeout=30s: bloqueia até
+// o DTM disparar o confirm ou o cancel deste gid (ver ResumeCallback/dispatchResume) ou o
+// timeout expirar, o que vier primeiro. Transforma o caminho hoje fire-and-forget do TCC
+// (POST /api/orders retorna 202 assim que as branches são registradas) num request/response que
+// o benchmark consegue medir ponta a ponta.
+func HandleAwaitOrder(uc *OrderUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gid := c.Param("gid")
 
-		ctx = trace.ContextWithSpanContext(ctx, spanContext)
-	}
+		timeout := defaultAwaitTimeout
+		if raw := c.Query("timeout"); raw != "" {
+			if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 && parsed <= maxAwaitTimeout {
+				timeout = parsed
+			}
+		}
 
-	return tracer.Start(ctx, operationName)
+		ctx, span := tracer.Start(c.Request.Context(), "orders.AwaitOrder")
+		defer span.End()
+		span.SetAttributes(attribute.String("tcc.gid", gid), attribute.String("tcc.await.timeout", timeout.String()))
+
+		result, err := uc.Await(ctx, gid, timeout)
+		if err != nil {
+			if err == ErrResumeCallbackTimeout {
+				c.JSON(http.StatusRequestTimeout, gin.H{"gid": gid, "error": "timed out waiting for terminal outcome"})
+				return
+			}
+			span.RecordError(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
 }
 
-func startSpanFromActionPayload(c *gin.Context, operationName string, req TCCActionRequest) (context.Context, trace.Span) {
-	ctx := c.Request.Context()
+// HandleHealth handler para health check
+func HandleHealth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "healthy", "service": "orders-service-tcc"})
+	}
+}
 
-	if req.TraceID != "" && req.SpanID != "" {
-		parsedTraceID, _ := trace.TraceIDFromHex(req.TraceID)
-		parsedSpanID, _ := trace.SpanIDFromHex(req.SpanID)
+// startSpanFromPayload inicia o span do handler de entrada (chamado diretamente pelo cliente,
+// não por uma branch do DTM). otelgin.Middleware já extraiu o trace context dos headers HTTP
+// reais da requisição e o deixou em c.Request.Context(), então não há nada para reconstruir aqui
+func startSpanFromPayload(c *gin.Context, operationName string, req CreateOrderRequest) (context.Context, trace.Span) {
+	return tracer.Start(c.Request.Context(), operationName)
+}
 
-		spanContext := trace.NewSpanContext(trace.SpanContextConfig{
-			TraceID:    parsedTraceID,
-			SpanID:     parsedSpanID,
-			TraceFlags: trace.FlagsSampled,
-			Remote:     true,
-		})
+// startSpanFromActionPayload extrai o trace context propagado pelo DTM orchestrator (via o par
+// traceparent/tracestate no payload, já que o DTM não repassa headers HTTP arbitrários) usando o
+// TextMapPropagator configurado, e enriquece o span com os atributos semânticos da fase TCC.
+// Na fase TRY, o span também é linkado (trace.Link) de volta ao span "tcc.branch.orders" criado
+// pelo orchestrator ao registrar esta branch (ver branch_span_store.go/MonitoredBranchCall) -
+// CONFIRM/CANCEL não têm um registro equivalente no orchestrator para linkar.
+func startSpanFromActionPayload(c *gin.Context, operationName, phase string, req TCCActionRequest) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.MapCarrier{
+		"traceparent": req.Traceparent,
+		"tracestate":  req.Tracestate,
+		"baggage":     req.Baggage,
+	})
 
-		ctx = trace.ContextWithSpanContext(ctx, spanContext)
+	var opts []trace.SpanStartOption
+	if phase == SagaPhaseTry && branchSpans != nil && req.GID != "" {
+		if sc, ok := branchSpans.Lookup(ctx, req.GID, "orders"); ok {
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+		}
 	}
 
-	return tracer.Start(ctx, operationName)
+	ctx, span := tracer.Start(ctx, operationName, opts...)
+	span.SetAttributes(
+		attribute.String("saga.phase", phase),
+		attribute.String("saga.order_id", req.OrderID),
+		attribute.String("saga.user_id", req.UserID),
+		attribute.String("db.system", "postgresql"),
+	)
+	return ctx, span
 }
@@ -10,9 +10,13 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
@@ -20,12 +24,16 @@ import (
 )
 
 var (
-	dbPool *pgxpool.Pool
-	tracer trace.Tracer
+	dbPool      *pgxpool.Pool
+	tracer      trace.Tracer
+	dtxM        *dtxMetrics
+	batchM      *batchMetrics
+	lifecycleM  *branchLifecycleMetrics
+	branchSpans *branchSpanStore
 )
 
 func main() {
-	// Initialize OpenTelemetry Tracer (sem metrics)
+	// Initialize OpenTelemetry Tracer
 	tp, err := initTracer()
 	if err != nil {
 		log.Fatalf("Failed to initialize tracer: %v", err)
@@ -38,6 +46,16 @@ func main() {
 
 	tracer = tp.Tracer("orders-service-tcc")
 
+	mp, err := initMetrics()
+	if err != nil {
+		log.Fatalf("Failed to initialize metrics: %v", err)
+	}
+	defer func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down meter: %v", err)
+		}
+	}()
+
 	// Initialize database
 	dbPool, err = initDB()
 	if err != nil {
@@ -45,10 +63,68 @@ func main() {
 	}
 	defer dbPool.Close()
 
+	// RED metrics for the TCC phases (dtx_transaction_duration_seconds, dtx_branch_duration_seconds,
+	// dtx_branch_retries_total, dtx_inflight_transactions) plus pgx pool occupancy gauges
+	meter := mp.Meter("orders-service-tcc")
+	dtxM, err = newDTXMetrics(meter)
+	if err != nil {
+		log.Fatalf("Failed to initialize DTM metrics: %v", err)
+	}
+	if err := registerPoolGauges(meter, dbPool); err != nil {
+		log.Fatalf("Failed to register pgx pool gauges: %v", err)
+	}
+
+	// Batch metrics (orders.batch.size, orders.batch.failures, orders.batch.latency) - separadas
+	// de dtxM por descreverem o lote /api/orders/batch como um todo, não uma fase/branch isolada
+	batchM, err = newBatchMetrics(meter)
+	if err != nil {
+		log.Fatalf("Failed to initialize batch metrics: %v", err)
+	}
+
+	// Per-phase business metrics (tcc.branch.try.duration, tcc.branch.confirm.duration,
+	// tcc.branch.cancel.count) observed from this participant's own try/confirm/cancel handlers
+	serviceName := getEnv("SERVICE_NAME", "orders-service-tcc")
+	lifecycleM, err = newBranchLifecycleMetrics(meter, serviceName, "orders")
+	if err != nil {
+		log.Fatalf("Failed to initialize branch lifecycle metrics: %v", err)
+	}
+
+	// Branch span-context store: compartilha via Redis o SpanContext do span de registro de
+	// cada branch TCC com o participante correspondente, para que try/confirm/cancel possam
+	// linkar seus próprios spans de volta ao registro (ver branch_span_store.go)
+	var closeBranchSpans func()
+	branchSpans, closeBranchSpans = initBranchSpanStore()
+	defer closeBranchSpans()
+
+	// Resume callback registry: resolve GET /api/orders/:gid/await quando CompleteOrder/
+	// CancelOrder produzirem o desfecho terminal de um gid. RESUME_CALLBACK_BACKEND=redis usa
+	// o registro compartilhado (múltiplas réplicas); o padrão é em memória, válido apenas para
+	// uma única réplica.
+	resumeCallback, closeResumeCallback := initResumeCallback()
+	defer closeResumeCallback()
+
 	// Setup repositories and use cases
 	orderRepository := NewPostgresOrderRepository(dbPool)
+	sagaLogRepository := NewPostgresSagaLogRepository(dbPool)
+	outboxRepository := NewPostgresOutboxRepository(dbPool)
 	tccOrchestrator := NewDTMTCCOrchestrator()
-	orderUseCase := NewOrderUseCase(orderRepository, tccOrchestrator)
+	orderUseCase := NewOrderUseCase(orderRepository, tccOrchestrator, sagaLogRepository, outboxRepository, resumeCallback)
+
+	// Reconciliation job: re-drive/alert on sagas stuck in TRY
+	reconcilerCtx, stopReconciler := context.WithCancel(context.Background())
+	defer stopReconciler()
+	StartSagaReconciler(reconcilerCtx, sagaLogRepository, 30*time.Second, 5*time.Minute)
+
+	// Outbox relayer: publishes orders_outbox events to NATS, closing the dual-write gap between
+	// the TCC phase handlers above and any event notification for downstream consumers
+	natsConn, err := nats.Connect(getEnv("NATS_URL", "nats://nats:4222"))
+	if err != nil {
+		log.Fatalf("Failed to connect to NATS for outbox relay: %v", err)
+	}
+	defer natsConn.Close()
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	StartOutboxRelay(relayCtx, outboxRepository, natsConn, 2*time.Second, 20)
 
 	// Setup Gin router
 	r := gin.New()
@@ -59,14 +135,23 @@ func main() {
 		log.Printf("🚨 PANIC RECOVERED: %v", recovered)
 		c.AbortWithStatus(http.StatusInternalServerError)
 	}))
-	// r.Use(otelgin.Middleware(getEnv("SERVICE_NAME", "orders-service-tcc")))
+	r.Use(otelgin.Middleware(getEnv("SERVICE_NAME", "orders-service-tcc")))
 
 	// Health check
 	r.GET("/health", HandleHealth())
 
+	// Saga audit trail endpoint
+	r.GET("/api/sagas/:order_id", HandleGetSagaChain(sagaLogRepository))
+
 	// TCC orchestrator endpoint - initiates TCC transaction (retorna 202 Accepted)
 	r.POST("/api/orders", HandleCreateOrder(orderUseCase))
 
+	// Batch endpoint - per-item partial-failure semantics with bounded concurrency
+	r.POST("/api/orders/batch", HandleBatchCreateOrder(orderUseCase))
+
+	// Resume callback endpoint - long-poll that resolves once the DTM confirm/cancel for gid lands
+	r.GET("/api/orders/:gid/await", HandleAwaitOrder(orderUseCase))
+
 	// TCC participant endpoints - chamados pelo DTM
 	r.POST("/api/orders/try", HandleTryCreateOrder(orderUseCase))
 	r.POST("/api/orders/confirm", HandleConfirmCreateOrder(orderUseCase))
@@ -108,6 +193,10 @@ func initDB() (*pgxpool.Pool, error) {
 	config.MaxConnIdleTime = 30 * time.Minute
 	config.HealthCheckPeriod = 1 * time.Minute
 
+	// Instrumenta cada Query/Exec/BeginTx com um span "db.query", para decompor o tempo gasto no
+	// Postgres dentro do flame graph de cada fase do TCC
+	config.ConnConfig.Tracer = newDBQueryTracer(getEnv("SERVICE_NAME", "orders-service-tcc"))
+
 	ctx := context.Background()
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
@@ -158,11 +247,49 @@ func initTracer() (*sdktrace.TracerProvider, error) {
 	)
 
 	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
+	// Composto com Baggage (além de TraceContext) para que atributos de negócio propagados pelo
+	// orquestrador sobrevivam ao salto entre branches TCC
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
 
 	return tp, nil
 }
 
+func initMetrics() (*sdkmetric.MeterProvider, error) {
+	ctx := context.Background()
+
+	otlpEndpoint := getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "otel-collector:4318")
+
+	exporter, err := otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpoint(otlpEndpoint),
+		otlpmetrichttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(getEnv("SERVICE_NAME", "orders-service-tcc")),
+			semconv.ServiceVersion("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(res),
+	)
+
+	otel.SetMeterProvider(mp)
+
+	return mp, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -16,23 +16,37 @@ type Order struct {
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
-// TCCActionRequest representa o payload das requisições TCC (sempre 1 unidade)
+// TCCActionRequest representa o payload das requisições TCC (sempre 1 unidade). Carrega o trace
+// context como o par traceparent/tracestate do W3C Trace Context, já que o DTM não repassa
+// headers HTTP arbitrários entre o registro da branch e a chamada que ele faz mais tarde
 type TCCActionRequest struct {
-	OrderID    string `json:"order_id"`
-	UserID     string `json:"user_id"`
-	ProductID  string `json:"product_id"`
-	TotalPrice int    `json:"total_price"`
-	TraceID    string `json:"trace_id"`
-	SpanID     string `json:"span_id"`
+	OrderID     string `json:"order_id"`
+	UserID      string `json:"user_id"`
+	ProductID   string `json:"product_id"`
+	TotalPrice  int    `json:"total_price"`
+	Traceparent string `json:"traceparent,omitempty"`
+	Tracestate  string `json:"tracestate,omitempty"`
+
+	// Baggage carrega o header W3C Baggage (ex: "benchmark_run_id=...") serializado pelo mesmo
+	// TextMapPropagator composto que grava Traceparent/Tracestate - sem este campo, atributos de
+	// negócio anexados via baggage.ContextWithBaggage no handler de entrada se perderiam ao
+	// cruzar para as branches inventory/payment, que só recebem este payload, não os headers
+	// HTTP originais
+	Baggage string `json:"baggage,omitempty"`
+
+	// GID identifica a transação TCC que originou esta ação, usado por
+	// CompleteOrder/CancelOrder para resolver o ResumeCallback registrado em
+	// GET /api/orders/:gid/await
+	GID string `json:"gid,omitempty"`
 }
 
-// CreateOrderRequest representa a requisição de criação de pedido (sempre 1 unidade)
+// CreateOrderRequest representa a requisição de criação de pedido (sempre 1 unidade). O trace
+// context não viaja mais neste payload - vem do header traceparent da requisição HTTP, extraído
+// por otelgin.Middleware
 type CreateOrderRequest struct {
 	UserID     string `json:"user_id"`
 	ProductID  string `json:"product_id"`
 	TotalPrice int    `json:"amount"`
-	TraceID    string `json:"trace_id"`
-	SpanID     string `json:"span_id"`
 }
 
 // OrderRepository define as operações de persistência de pedidos
@@ -40,4 +54,41 @@ type OrderRepository interface {
 	CreateOrder(ctx context.Context, order *Order) error
 	GetOrderByID(ctx context.Context, orderID string) (*Order, error)
 	UpdateOrderStatus(ctx context.Context, orderID string, status string) error
+
+	// BeginTx inicia uma transação para compor a mudança de status do pedido com a gravação
+	// atômica do outbox (ver CreateOrderTx/UpdateOrderStatusTx e OutboxRepository)
+	BeginTx(ctx context.Context) (Tx, error)
+
+	// CreateOrderTx grava o pedido apenas se order_id ainda não existir (ON CONFLICT DO NOTHING)
+	// e informa via created se a linha foi de fato inserida - uma reentrega do DTM na fase TRY
+	// encontra created=false e pula a gravação em vez de estourar violação de chave primária
+	CreateOrderTx(ctx context.Context, tx Tx, order *Order) (created bool, err error)
+
+	// GetOrderStatusTx lê o status atual do pedido dentro da transação em andamento, usado por
+	// CONFIRM/CANCEL para decidir, antes de escrever, se a transição é a esperada (ver
+	// UpdateOrderStatusTx) - equivalente ao GetInventoryMovementStatusByOrderID do serviço de
+	// inventory
+	GetOrderStatusTx(ctx context.Context, tx Tx, orderID string) (string, error)
+
+	// UpdateOrderStatusTx só aplica a mudança se o status atual for from, retornando
+	// applied=false sem erro quando não for (reentrega do DTM após o pedido já ter avançado, ou
+	// CONFIRM chegando depois de um CANCEL fora de ordem)
+	UpdateOrderStatusTx(ctx context.Context, tx Tx, orderID, from, to string) (applied bool, err error)
+}
+
+// Tx abstrai uma transação de banco em andamento, permitindo compor múltiplas escritas de
+// repositórios diferentes (pedido + outbox) atomicamente sem acoplar o caso de uso ao driver
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// OrderResult representa o resultado individual de um item processado via /api/orders/batch
+type OrderResult struct {
+	Index   int    `json:"index"`
+	OrderID string `json:"order_id,omitempty"`
+	GID     string `json:"gid,omitempty"`
+	TraceID string `json:"trace_id,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
 }
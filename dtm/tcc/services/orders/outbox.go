@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Status de publicação de uma entrada do outbox
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusPublished = "published"
+)
+
+// OutboxEvent representa uma entrada do outbox transacional: é gravada na MESMA transação de
+// negócio que aplica a mudança de status do pedido, diferente do saga_events (log de auditoria),
+// que é gravado numa conexão separada após o commit. Isso fecha o gap de dual-write entre a
+// mudança de estado e a notificação de eventos para consumidores downstream.
+//
+// Traceparent é persistido junto com o evento e reinjetado como header da mensagem NATS na
+// publicação, para que consumidores downstream continuem o mesmo trace mesmo que o relay
+// publique bem depois do commit original.
+type OutboxEvent struct {
+	ID          int64   `json:"id"`
+	OrderID     string  `json:"order_id"`
+	EventType   string  `json:"event_type"`
+	Payload     []byte  `json:"payload"`
+	Traceparent string  `json:"traceparent,omitempty"`
+	Status      string  `json:"status"`
+	PublishedAt *string `json:"published_at,omitempty"`
+}
+
+// OutboxRepository define as operações de persistência do outbox transacional de pedidos
+type OutboxRepository interface {
+	// EnqueueOutboxEvent grava o evento dentro da transação de negócio em andamento
+	EnqueueOutboxEvent(ctx context.Context, tx Tx, event *OutboxEvent) error
+
+	// ClaimPendingEvents seleciona um lote de eventos pendentes com FOR UPDATE SKIP LOCKED. A query
+	// roda fora de uma transação explícita, então o lock só dura o próprio SELECT: duas instâncias
+	// do relay podem receber o mesmo evento
+	ClaimPendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error)
+
+	// MarkPublished marca o evento como publicado no transporte de mensageria
+	MarkPublished(ctx context.Context, id int64) error
+}
+
+// PostgresOutboxRepository implementa OutboxRepository usando a tabela orders_outbox
+type PostgresOutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresOutboxRepository(pool *pgxpool.Pool) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{pool: pool}
+}
+
+// EnqueueOutboxEvent grava o evento dentro da transação de negócio recebida via tx, garantindo
+// atomicidade entre a mudança de status do pedido e a entrada no outbox
+func (r *PostgresOutboxRepository) EnqueueOutboxEvent(ctx context.Context, tx Tx, event *OutboxEvent) error {
+	pgTx := tx.(*PostgresTx).tx
+
+	query := `
+		INSERT INTO orders_outbox (order_id, event_type, payload, traceparent, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id
+	`
+	err := pgTx.QueryRow(ctx, query, event.OrderID, event.EventType, event.Payload, event.Traceparent, OutboxStatusPending).Scan(&event.ID)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue outbox event: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimPendingEvents seleciona até `limit` eventos pendentes com FOR UPDATE SKIP LOCKED, usado
+// pelo relay para varrer o outbox; o lock termina com o SELECT, não com a publicação
+func (r *PostgresOutboxRepository) ClaimPendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, order_id, event_type, payload, traceparent
+		FROM orders_outbox
+		WHERE status = $1
+		ORDER BY id ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, OutboxStatusPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var event OutboxEvent
+		if err := rows.Scan(&event.ID, &event.OrderID, &event.EventType, &event.Payload, &event.Traceparent); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// MarkPublished marca o evento como publicado no transporte de mensageria
+func (r *PostgresOutboxRepository) MarkPublished(ctx context.Context, id int64) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE orders_outbox
+		SET status = $1, published_at = NOW()
+		WHERE id = $2
+	`, OutboxStatusPublished, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event as published: %w", err)
+	}
+	return nil
+}
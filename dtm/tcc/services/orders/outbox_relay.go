@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var outboxMeter = otel.Meter("orders-outbox-relay")
+
+// StartOutboxRelay inicia uma goroutine que varre periodicamente o outbox em busca de eventos
+// pendentes e os publica em `orders.events.<event_type>` via NATS, marcando-os como published
+// assim que nc.PublishMsg retorna sem erro (publish do NATS core, sem ack do servidor). Várias
+// instâncias podem rodar concorrentemente, mas sem lotes disjuntos: o lock de ClaimPendingEvents
+// termina com o SELECT, então um evento ainda pendente pode ser publicado por mais de uma réplica.
+//
+// O traceparent persistido junto ao evento (gravado atomicamente com ele na mesma transação de
+// negócio) é reinjetado como header da mensagem NATS, para que o consumidor downstream continue
+// o mesmo trace da requisição original mesmo publicando bem depois do commit.
+func StartOutboxRelay(ctx context.Context, outbox OutboxRepository, nc *nats.Conn, interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+
+	published, err := outboxMeter.Int64Counter(
+		"outbox.events.published",
+		metric.WithDescription("Number of orders outbox events successfully published to NATS"),
+	)
+	if err != nil {
+		log.Printf("⚠️ [OUTBOX RELAY] failed to create published counter: %v", err)
+	}
+
+	backlog, err := outboxMeter.Int64Histogram(
+		"outbox.backlog.size",
+		metric.WithDescription("Number of pending orders outbox events observed at each relay tick"),
+	)
+	if err != nil {
+		log.Printf("⚠️ [OUTBOX RELAY] failed to create backlog histogram: %v", err)
+	}
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				relayPendingEvents(ctx, outbox, nc, batchSize, published, backlog)
+			}
+		}
+	}()
+}
+
+func relayPendingEvents(ctx context.Context, outbox OutboxRepository, nc *nats.Conn, batchSize int, published metric.Int64Counter, backlog metric.Int64Histogram) {
+	events, err := outbox.ClaimPendingEvents(ctx, batchSize)
+	if err != nil {
+		log.Printf("❌ [OUTBOX RELAY] failed to claim pending events: %v", err)
+		return
+	}
+
+	if backlog != nil {
+		backlog.Record(ctx, int64(len(events)))
+	}
+
+	for _, event := range events {
+		if err := publishOutboxEvent(ctx, nc, event); err != nil {
+			log.Printf("❌ [OUTBOX RELAY] failed to publish event id=%d OrderID=%s: %v", event.ID, event.OrderID, err)
+			continue
+		}
+
+		if err := outbox.MarkPublished(ctx, event.ID); err != nil {
+			log.Printf("❌ [OUTBOX RELAY] failed to mark event id=%d as published: %v", event.ID, err)
+			continue
+		}
+
+		if published != nil {
+			published.Add(ctx, 1)
+		}
+		log.Printf("📤 [OUTBOX RELAY] published event id=%d OrderID=%s EventType=%s", event.ID, event.OrderID, event.EventType)
+	}
+}
+
+func publishOutboxEvent(_ context.Context, nc *nats.Conn, event OutboxEvent) error {
+	// Reinjeta o traceparent persistido com o evento (não o da goroutine do relay, que não tem
+	// relação com o trace original da requisição que gerou este evento)
+	headers := make(nats.Header)
+	if event.Traceparent != "" {
+		headers.Set("traceparent", event.Traceparent)
+	}
+
+	subject := fmt.Sprintf("orders.events.%s", event.EventType)
+	msg := &nats.Msg{
+		Subject: subject,
+		Header:  headers,
+		Data:    event.Payload,
+	}
+
+	if err := nc.PublishMsg(msg); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", subject, err)
+	}
+
+	return nil
+}
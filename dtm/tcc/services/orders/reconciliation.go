@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// StartSagaReconciler varre periodicamente o log de auditoria da saga em busca de pedidos
+// presos na fase TRY sem CONFIRM/CANCEL subsequente, sinalizando-os para re-drive/alerting.
+func StartSagaReconciler(ctx context.Context, sagaLog SagaLogRepository, interval, stuckAfter time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reconcileStuckSagas(ctx, sagaLog, stuckAfter)
+			}
+		}
+	}()
+}
+
+func reconcileStuckSagas(ctx context.Context, sagaLog SagaLogRepository, stuckAfter time.Duration) {
+	orderIDs, err := sagaLog.GetStuckInTry(ctx, stuckAfter)
+	if err != nil {
+		log.Printf("❌ [RECONCILE] failed to scan stuck sagas: %v", err)
+		return
+	}
+
+	for _, orderID := range orderIDs {
+		// DTM já re-executa branches TCC pendentes via seu próprio cron (dtm_barrier);
+		// aqui apenas sinalizamos a situação, já que o orquestrador não expõe hoje um
+		// endpoint de re-drive manual por GID/OrderID.
+		log.Printf("⚠️ [RECONCILE] OrderID=%s stuck in TRY for more than %s - flagging for operator follow-up", orderID, stuckAfter)
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -14,6 +17,91 @@ func NewPostgresOrderRepository(pool *pgxpool.Pool) *PostgresOrderRepository {
 	return &PostgresOrderRepository{pool: pool}
 }
 
+// PostgresTx implementa a interface Tx
+type PostgresTx struct {
+	tx pgx.Tx
+}
+
+func (t *PostgresTx) Commit() error {
+	return t.tx.Commit(context.Background())
+}
+
+func (t *PostgresTx) Rollback() error {
+	return t.tx.Rollback(context.Background())
+}
+
+// BeginTx inicia uma nova transação
+func (r *PostgresOrderRepository) BeginTx(ctx context.Context) (Tx, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return &PostgresTx{tx: tx}, nil
+}
+
+// CreateOrderTx é a variante de CreateOrder que participa de uma transação em andamento, usada
+// para gravar o pedido e o evento de outbox atomicamente. ON CONFLICT DO NOTHING torna a fase TRY
+// idempotente por order_id - uma reentrega do DTM não estoura violação de chave primária, apenas
+// reporta created=false
+func (r *PostgresOrderRepository) CreateOrderTx(ctx context.Context, tx Tx, order *Order) (bool, error) {
+	pgTx := tx.(*PostgresTx).tx
+
+	query := `
+		INSERT INTO orders (order_id, user_id, product_id, total_price, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (order_id) DO NOTHING
+	`
+	tag, err := pgTx.Exec(ctx, query,
+		order.OrderID,
+		order.UserID,
+		order.ProductID,
+		order.TotalPrice,
+		order.Status,
+		order.CreatedAt,
+		order.UpdatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetOrderStatusTx lê o status atual do pedido dentro da transação em andamento
+func (r *PostgresOrderRepository) GetOrderStatusTx(ctx context.Context, tx Tx, orderID string) (string, error) {
+	pgTx := tx.(*PostgresTx).tx
+
+	query := `SELECT status FROM orders WHERE order_id = $1`
+	var status string
+	err := pgTx.QueryRow(ctx, query, orderID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to query order status: %w", err)
+	}
+	return status, nil
+}
+
+// UpdateOrderStatusTx é a variante de UpdateOrderStatus que participa de uma transação em
+// andamento, usada para atualizar o status do pedido e gravar o evento de outbox atomicamente. A
+// cláusula WHERE status = from fecha a mesma race que existia em inventory antes do ajuste de
+// idempotência: sem ela, um CONFIRM reentregue após um CANCEL (fora de ordem, por retentativa do
+// DTM) sobrescreveria silenciosamente o pedido de volta para "completed"
+func (r *PostgresOrderRepository) UpdateOrderStatusTx(ctx context.Context, tx Tx, orderID, from, to string) (bool, error) {
+	pgTx := tx.(*PostgresTx).tx
+
+	query := `
+		UPDATE orders
+		SET status = $1, updated_at = NOW()
+		WHERE order_id = $2 AND status = $3
+	`
+	tag, err := pgTx.Exec(ctx, query, to, orderID, from)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
 func (r *PostgresOrderRepository) CreateOrder(ctx context.Context, order *Order) error {
 	query := `
 		INSERT INTO orders (order_id, user_id, product_id, total_price, status, created_at, updated_at)
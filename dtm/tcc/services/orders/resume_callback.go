@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// resumeCallbackTTL é o prazo máximo que um registro de callback pode ficar pendente antes do
+// reaper descartá-lo. Um pouco acima do timeout padrão de transação global do DTM (30s) para
+// tolerar o jitter entre o DTM decidir o desfecho e o confirm/cancel realmente chegar neste
+// serviço.
+const resumeCallbackTTL = 35 * time.Second
+
+// CallbackResult representa o desfecho terminal de uma transação TCC, entregue a quem estiver
+// aguardando em GET /api/orders/:gid/await
+type CallbackResult struct {
+	OrderID string `json:"order_id"`
+	GID     string `json:"gid"`
+	Status  string `json:"status"` // "completed" ou "cancelled"
+	Error   string `json:"error,omitempty"`
+}
+
+// ErrResumeCallbackTimeout é retornado por Await quando o timeout expira sem que o gid tenha
+// sido resolvido
+var ErrResumeCallbackTimeout = errors.New("resume callback: timed out waiting for terminal outcome")
+
+// ResumeCallback registra, por GID, quem está esperando pelo desfecho assíncrono de uma
+// transação TCC e entrega esse desfecho quando CompleteOrder/CancelOrder o produzem. Modelado no
+// mesmo espírito de um resumeCallback(ctx, taskRunID, value, err) de gerenciador de tarefas: o
+// "taskRunID" aqui é o GID do DTM, e o "value"/"err" viram CallbackResult.
+type ResumeCallback interface {
+	// Await bloqueia até o gid ser resolvido via Resume ou o timeout expirar, o que vier
+	// primeiro. Se o gid já tiver sido resolvido antes da chamada (confirm/cancel rápido
+	// demais), retorna o resultado imediatamente.
+	Await(ctx context.Context, gid string, timeout time.Duration) (CallbackResult, error)
+
+	// Resume entrega o desfecho terminal de gid a quem estiver aguardando (ou o deixa
+	// disponível para um Await futuro, dentro do TTL do registro)
+	Resume(ctx context.Context, gid string, result CallbackResult) error
+}
+
+// callbackWaiter representa um gid em aberto: ou já tem um resultado resolvido (delivered=true,
+// aguardando o próximo Await consumi-lo), ou ainda não (um ou mais Await bloqueados em ch)
+type callbackWaiter struct {
+	ch        chan CallbackResult
+	createdAt time.Time
+	delivered bool
+	result    CallbackResult
+}
+
+// InMemoryResumeCallback implementa ResumeCallback com um registro local em memória, adequado
+// para uma única réplica do orders-service. Para múltiplas réplicas atrás de um load balancer,
+// ver RedisResumeCallback - o confirm/cancel de um gid pode chegar em um pod diferente daquele
+// que está bloqueado em Await para o mesmo gid.
+type InMemoryResumeCallback struct {
+	mu      sync.Mutex
+	waiters map[string]*callbackWaiter
+}
+
+// NewInMemoryResumeCallback cria um registro vazio
+func NewInMemoryResumeCallback() *InMemoryResumeCallback {
+	return &InMemoryResumeCallback{
+		waiters: make(map[string]*callbackWaiter),
+	}
+}
+
+func (r *InMemoryResumeCallback) getOrCreate(gid string) *callbackWaiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.waiters[gid]
+	if !ok {
+		w = &callbackWaiter{ch: make(chan CallbackResult, 1), createdAt: time.Now()}
+		r.waiters[gid] = w
+	}
+	return w
+}
+
+// Await implementa ResumeCallback.Await
+func (r *InMemoryResumeCallback) Await(ctx context.Context, gid string, timeout time.Duration) (CallbackResult, error) {
+	w := r.getOrCreate(gid)
+
+	r.mu.Lock()
+	if w.delivered {
+		delete(r.waiters, gid)
+		r.mu.Unlock()
+		return w.result, nil
+	}
+	r.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-w.ch:
+		r.mu.Lock()
+		if r.waiters[gid] == w {
+			delete(r.waiters, gid)
+		}
+		r.mu.Unlock()
+		return result, nil
+	case <-timer.C:
+		return CallbackResult{}, ErrResumeCallbackTimeout
+	case <-ctx.Done():
+		return CallbackResult{}, ctx.Err()
+	}
+}
+
+// Resume implementa ResumeCallback.Resume
+func (r *InMemoryResumeCallback) Resume(_ context.Context, gid string, result CallbackResult) error {
+	w := r.getOrCreate(gid)
+
+	select {
+	case w.ch <- result:
+		// Fica no buffer do canal, para um Await já bloqueado ou para o próximo; o Await que o
+		// receber remove o waiter do mapa.
+	default:
+		// O buffer já guarda um resultado não consumido (Resume repetido) - deixa este
+		// disponível para o próximo Await, dentro do TTL do reaper.
+		r.mu.Lock()
+		w.delivered = true
+		w.result = result
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+// StartReaper inicia uma goroutine que descarta, a cada `interval`, os waiters mais antigos que
+// resumeCallbackTTL - evita que um gid cujo confirm/cancel nunca chegou (ex: DTM derrubado antes
+// de concluir o 2PC) vaze memória indefinidamente
+func (r *InMemoryResumeCallback) StartReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.reapExpired()
+			}
+		}
+	}()
+}
+
+func (r *InMemoryResumeCallback) reapExpired() {
+	cutoff := time.Now().Add(-resumeCallbackTTL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for gid, w := range r.waiters {
+		if w.createdAt.Before(cutoff) {
+			delete(r.waiters, gid)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// resumeCallbackKeyPrefix namespaces as chaves/canais deste registro no Redis compartilhado
+const resumeCallbackKeyPrefix = "tcc:resume:"
+
+// RedisResumeCallback implementa ResumeCallback via pub/sub do Redis, permitindo que múltiplas
+// réplicas do orders-service resolvam o mesmo gid independente de qual pod recebeu o
+// confirm/cancel do DTM. Pub/sub sozinho perderia a mensagem se Resume acontecer antes de
+// qualquer Await se inscrever (corrida comum quando o 2PC é rápido) - por isso Resume também
+// grava o resultado com SETEX, e Await primeiro tenta um GET direto antes de se inscrever no
+// canal.
+type RedisResumeCallback struct {
+	client *redis.Client
+}
+
+// NewRedisResumeCallback cria um registro apoiado no cliente Redis informado
+func NewRedisResumeCallback(client *redis.Client) *RedisResumeCallback {
+	return &RedisResumeCallback{client: client}
+}
+
+func resumeCallbackKey(gid string) string {
+	return resumeCallbackKeyPrefix + gid
+}
+
+// Await implementa ResumeCallback.Await
+func (r *RedisResumeCallback) Await(ctx context.Context, gid string, timeout time.Duration) (CallbackResult, error) {
+	if result, ok, err := r.getStored(ctx, gid); err != nil {
+		return CallbackResult{}, err
+	} else if ok {
+		return result, nil
+	}
+
+	sub := r.client.Subscribe(ctx, resumeCallbackKey(gid))
+	defer sub.Close()
+
+	// Checa de novo depois de inscrito, para fechar a janela entre o GET acima e a inscrição
+	if result, ok, err := r.getStored(ctx, gid); err != nil {
+		return CallbackResult{}, err
+	} else if ok {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	msg, err := sub.ReceiveMessage(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return CallbackResult{}, ErrResumeCallbackTimeout
+		}
+		return CallbackResult{}, fmt.Errorf("resume callback: redis subscribe failed: %w", err)
+	}
+
+	var result CallbackResult
+	if err := json.Unmarshal([]byte(msg.Payload), &result); err != nil {
+		return CallbackResult{}, fmt.Errorf("resume callback: failed to decode published result: %w", err)
+	}
+	return result, nil
+}
+
+// Resume implementa ResumeCallback.Resume
+func (r *RedisResumeCallback) Resume(ctx context.Context, gid string, result CallbackResult) error {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("resume callback: failed to encode result: %w", err)
+	}
+
+	if err := r.client.Set(ctx, resumeCallbackKey(gid), payload, resumeCallbackTTL).Err(); err != nil {
+		return fmt.Errorf("resume callback: failed to persist result: %w", err)
+	}
+
+	if err := r.client.Publish(ctx, resumeCallbackKey(gid), payload).Err(); err != nil {
+		// Não é fatal: quem ainda não se inscreveu vai encontrar o resultado via o GET/SETEX
+		// acima assim que chamar Await
+		log.Printf("⚠️ [RESUME CALLBACK] failed to publish gid=%s to redis pub/sub: %v", gid, err)
+	}
+	return nil
+}
+
+// initResumeCallback monta o backend de ResumeCallback configurado via
+// RESUME_CALLBACK_BACKEND ("memory", o padrão, ou "redis") e devolve uma função de shutdown a
+// ser chamada via defer em main(). Em memória, o shutdown apenas para o reaper; no Redis, também
+// fecha a conexão com o servidor.
+func initResumeCallback() (ResumeCallback, func()) {
+	if getEnv("RESUME_CALLBACK_BACKEND", "memory") != "redis" {
+		reaperCtx, stopReaper := context.WithCancel(context.Background())
+		memory := NewInMemoryResumeCallback()
+		memory.StartReaper(reaperCtx, 10*time.Second)
+		return memory, stopReaper
+	}
+
+	opts, err := redis.ParseURL(getEnv("REDIS_URL", "redis://redis:6379/0"))
+	if err != nil {
+		log.Fatalf("Failed to parse REDIS_URL for resume callback: %v", err)
+	}
+
+	client := redis.NewClient(opts)
+	return NewRedisResumeCallback(client), func() {
+		if err := client.Close(); err != nil {
+			log.Printf("Error closing resume callback redis client: %v", err)
+		}
+	}
+}
+
+func (r *RedisResumeCallback) getStored(ctx context.Context, gid string) (CallbackResult, bool, error) {
+	raw, err := r.client.Get(ctx, resumeCallbackKey(gid)).Bytes()
+	if err == redis.Nil {
+		return CallbackResult{}, false, nil
+	}
+	if err != nil {
+		return CallbackResult{}, false, fmt.Errorf("resume callback: redis get failed: %w", err)
+	}
+
+	var result CallbackResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return CallbackResult{}, false, fmt.Errorf("resume callback: failed to decode stored result: %w", err)
+	}
+	return result, true, nil
+}
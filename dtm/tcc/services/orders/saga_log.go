@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Fases da saga registradas no log de auditoria (TCC/XA)
+const (
+	SagaPhaseTry     = "try"
+	SagaPhaseConfirm = "confirm"
+	SagaPhaseCancel  = "cancel"
+)
+
+// SagaEvent representa um registro imutável da cadeia de eventos de uma transação distribuída
+type SagaEvent struct {
+	ID          int64     `json:"id"`
+	OrderID     string    `json:"order_id"`
+	Service     string    `json:"service"`
+	Phase       string    `json:"phase"`
+	Status      string    `json:"status"`
+	PayloadHash string    `json:"payload_hash"`
+	PrevEventID *int64    `json:"prev_event_id,omitempty"`
+	TraceID     string    `json:"trace_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SagaLogRepository define as operações do log de auditoria append-only da saga
+type SagaLogRepository interface {
+	// AppendEvent grava um novo evento encadeado ao último evento conhecido do OrderID
+	AppendEvent(ctx context.Context, event *SagaEvent) error
+
+	// GetChain retorna a cadeia de eventos de um pedido, ordenada por criação
+	GetChain(ctx context.Context, orderID string) ([]SagaEvent, error)
+
+	// GetStuckInTry retorna OrderIDs cujo último evento é um TRY mais antigo que olderThan
+	// (ou seja, sem CONFIRM/CANCEL subsequente) - candidatos a reconciliação
+	GetStuckInTry(ctx context.Context, olderThan time.Duration) ([]string, error)
+}
+
+// PostgresSagaLogRepository implementa SagaLogRepository usando a tabela saga_events
+type PostgresSagaLogRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresSagaLogRepository(pool *pgxpool.Pool) *PostgresSagaLogRepository {
+	return &PostgresSagaLogRepository{pool: pool}
+}
+
+// AppendEvent grava o evento em saga_events, encadeando-o ao último evento do mesmo OrderID
+func (r *PostgresSagaLogRepository) AppendEvent(ctx context.Context, event *SagaEvent) error {
+	var prevEventID *int64
+	err := r.pool.QueryRow(ctx, `
+		SELECT id FROM saga_events WHERE order_id = $1 ORDER BY id DESC LIMIT 1
+	`, event.OrderID).Scan(&prevEventID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to look up previous saga event: %w", err)
+	}
+	event.PrevEventID = prevEventID
+
+	query := `
+		INSERT INTO saga_events (order_id, service, phase, status, payload_hash, prev_event_id, trace_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING id, created_at
+	`
+	err = r.pool.QueryRow(ctx, query,
+		event.OrderID, event.Service, event.Phase, event.Status, event.PayloadHash, event.PrevEventID, event.TraceID,
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to append saga event: %w", err)
+	}
+
+	log.Printf("📝 [SAGA LOG] OrderID=%s Service=%s Phase=%s Status=%s", event.OrderID, event.Service, event.Phase, event.Status)
+	return nil
+}
+
+// GetChain retorna a cadeia de eventos de um pedido, ordenada por criação
+func (r *PostgresSagaLogRepository) GetChain(ctx context.Context, orderID string) ([]SagaEvent, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, order_id, service, phase, status, payload_hash, prev_event_id, trace_id, created_at
+		FROM saga_events
+		WHERE order_id = $1
+		ORDER BY id ASC
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saga chain: %w", err)
+	}
+	defer rows.Close()
+
+	var events []SagaEvent
+	for rows.Next() {
+		var event SagaEvent
+		if err := rows.Scan(
+			&event.ID, &event.OrderID, &event.Service, &event.Phase, &event.Status,
+			&event.PayloadHash, &event.PrevEventID, &event.TraceID, &event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan saga event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetStuckInTry retorna OrderIDs cujo último evento é um TRY mais antigo que olderThan
+func (r *PostgresSagaLogRepository) GetStuckInTry(ctx context.Context, olderThan time.Duration) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT DISTINCT ON (order_id) order_id
+		FROM saga_events
+		ORDER BY order_id, id DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan for stuck sagas: %w", err)
+	}
+	defer rows.Close()
+
+	cutoff := time.Now().Add(-olderThan)
+	var stuck []string
+	for rows.Next() {
+		var orderID string
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("failed to scan stuck saga row: %w", err)
+		}
+
+		chain, err := r.GetChain(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if len(chain) == 0 {
+			continue
+		}
+
+		last := chain[len(chain)-1]
+		if last.Phase == SagaPhaseTry && last.CreatedAt.Before(cutoff) {
+			stuck = append(stuck, orderID)
+		}
+	}
+
+	return stuck, rows.Err()
+}
+
+// hashPayload calcula um hash estável do payload para permitir auditoria sem expor dados sensíveis
+func hashPayload(payload any) string {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -2,38 +2,121 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"go.opentelemetry.io/otel/trace"
-) // OrderUseCase encapsula a lógica de negócio de pedidos
+)
+
+// ErrNoChange sinaliza que a ação TCC já estava aplicada (reentrega do DTM) ou que o pedido não
+// está mais no status esperado para a transição (CONFIRM/CANCEL fora de ordem) - em ambos os
+// casos a escrita é pulada e a fase é reportada como sucesso, já que não há nada a fazer
+var ErrNoChange = errors.New("no change: order action already applied")
+
+// OrderUseCase encapsula a lógica de negócio de pedidos
 type OrderUseCase struct {
 	repository      OrderRepository
 	tccOrchestrator TCCOrchestrator
+	sagaLog         SagaLogRepository
+	outbox          OutboxRepository
+	resumeCallback  ResumeCallback
 }
 
 // NewOrderUseCase cria uma nova instância do caso de uso
-func NewOrderUseCase(repository OrderRepository, tccOrchestrator TCCOrchestrator) *OrderUseCase {
+func NewOrderUseCase(repository OrderRepository, tccOrchestrator TCCOrchestrator, sagaLog SagaLogRepository, outbox OutboxRepository, resumeCallback ResumeCallback) *OrderUseCase {
 	return &OrderUseCase{
 		repository:      repository,
 		tccOrchestrator: tccOrchestrator,
+		sagaLog:         sagaLog,
+		outbox:          outbox,
+		resumeCallback:  resumeCallback,
+	}
+}
+
+// dispatchResume entrega o desfecho terminal de um gid a quem estiver bloqueado em
+// GET /api/orders/:gid/await. Não interrompe a fase CONFIRM/CANCEL em caso de falha - o reaper
+// do ResumeCallback garante que o registro não vaze mesmo que ninguém nunca chame Await.
+func (uc *OrderUseCase) dispatchResume(ctx context.Context, req TCCActionRequest, gid, status string, phaseErr error) {
+	if uc.resumeCallback == nil || gid == "" {
+		return
+	}
+
+	result := CallbackResult{OrderID: req.OrderID, GID: gid, Status: status}
+	if phaseErr != nil {
+		result.Error = phaseErr.Error()
+	}
+
+	if err := uc.resumeCallback.Resume(ctx, gid, result); err != nil {
+		log.Printf("⚠️ [RESUME CALLBACK] failed to resume gid=%s: %v", gid, err)
+	}
+}
+
+// Await bloqueia até o gid ser resolvido por CompleteOrder/CancelOrder ou o timeout expirar
+func (uc *OrderUseCase) Await(ctx context.Context, gid string, timeout time.Duration) (CallbackResult, error) {
+	if uc.resumeCallback == nil {
+		return CallbackResult{}, fmt.Errorf("resume callback not configured")
+	}
+	return uc.resumeCallback.Await(ctx, gid, timeout)
+}
+
+// enqueueOutboxEvent grava, dentro da mesma transação de negócio, um evento descrevendo a
+// mudança de status do pedido que acabou de ser aplicada. Ao contrário do saga_events (gravado
+// após o commit, apenas para auditoria), esta entrada é o que o OutboxRelayer assíncrono publica
+// no NATS - por isso precisa existir atomicamente junto com a mudança de estado.
+func (uc *OrderUseCase) enqueueOutboxEvent(ctx context.Context, tx Tx, req TCCActionRequest, eventType string) error {
+	if uc.outbox == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	event := &OutboxEvent{
+		OrderID:     req.OrderID,
+		EventType:   eventType,
+		Payload:     payload,
+		Traceparent: req.Traceparent,
+	}
+	return uc.outbox.EnqueueOutboxEvent(ctx, tx, event)
+}
+
+// appendSagaEvent grava o evento no log de auditoria sem interromper a fase TCC em caso de falha
+func (uc *OrderUseCase) appendSagaEvent(ctx context.Context, req TCCActionRequest, phase, status string) {
+	if uc.sagaLog == nil {
+		return
+	}
+
+	event := &SagaEvent{
+		OrderID:     req.OrderID,
+		Service:     "orders",
+		Phase:       phase,
+		Status:      status,
+		PayloadHash: hashPayload(req),
+		TraceID:     traceIDFromTraceparent(req.Traceparent),
+	}
+	if err := uc.sagaLog.AppendEvent(ctx, event); err != nil {
+		log.Printf("⚠️ [SAGA LOG] failed to append event | OrderID=%s Phase=%s | Error=%v", req.OrderID, phase, err)
 	}
 }
 
 // CreateOrder registra as branches TCC e retorna 202 Accepted
-func (uc *OrderUseCase) CreateOrder(ctx context.Context, req CreateOrderRequest) (string, string, error) {
+func (uc *OrderUseCase) CreateOrder(ctx context.Context, req CreateOrderRequest) (orderID, gid, traceID string, err error) {
 	log.Printf("📦 Registering order creation: UserID=%s, ProductID=%s, TotalPrice=%d (1 unit)",
 		req.UserID, req.ProductID, req.TotalPrice)
 
 	// Validações básicas
 	if req.TotalPrice <= 0 {
-		return "", "", ErrInvalidPrice
+		return "", "", "", ErrInvalidPrice
 	}
 
 	// Registra branches TCC no DTM (retorna imediatamente!)
-	orderID, traceID, err := uc.tccOrchestrator.CreateOrderTCC(ctx, req)
+	orderID, gid, traceID, err = uc.tccOrchestrator.CreateOrderTCC(ctx, req)
 	if err != nil || orderID == "" {
 		if orderID == "" {
 			orderID = uuid.New().String()
@@ -50,11 +133,11 @@ func (uc *OrderUseCase) CreateOrder(ctx context.Context, req CreateOrderRequest)
 		})
 
 		log.Printf("❌ TCC branch registration failed: %v", err)
-		return orderID, traceID, fmt.Errorf("registering failed order to recover dtm failure: %s", err.Error())
+		return orderID, gid, traceID, fmt.Errorf("registering failed order to recover dtm failure: %s", err.Error())
 	}
 
-	log.Printf("✅ TCC branches registered | OrderID=%s | TraceID=%s (processing asynchronously)", orderID, traceID)
-	return orderID, traceID, nil
+	log.Printf("✅ TCC branches registered | OrderID=%s | GID=%s | TraceID=%s (processing asynchronously)", orderID, gid, traceID)
+	return orderID, gid, traceID, nil
 }
 
 // TryCreateOrder implementa a fase TRY do TCC - cria ordem com status "pending"
@@ -69,26 +152,90 @@ func (uc *OrderUseCase) TryCreateOrder(ctx context.Context, req TCCActionRequest
 		UpdatedAt:  time.Now(),
 	}
 
-	if err := uc.repository.CreateOrder(ctx, order); err != nil {
+	tx, err := uc.repository.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	created, err := uc.repository.CreateOrderTx(ctx, tx, order)
+	if err != nil {
 		log.Printf("❌ TRY FAILED: Failed to create pending order | OrderID=%s | Error=%v", req.OrderID, err)
+		uc.appendSagaEvent(ctx, req, SagaPhaseTry, "failed")
 		return err
 	}
 
+	if !created {
+		log.Printf("ℹ️ [TRY] Order already exists for OrderID=%s", req.OrderID)
+		return ErrNoChange
+	}
+
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, "order.try"); err != nil {
+		log.Printf("❌ TRY FAILED: Failed to enqueue outbox event | OrderID=%s | Error=%v", req.OrderID, err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	uc.appendSagaEvent(ctx, req, SagaPhaseTry, "ok")
 	log.Printf("✅ TRY SUCCESS: Pending order created | OrderID=%s", req.OrderID)
 	return nil
 }
 
 // ConfirmCreateOrder implementa a fase CONFIRM do TCC - atualiza ordem para "completed"
 func (uc *OrderUseCase) ConfirmCreateOrder(ctx context.Context, req TCCActionRequest) error {
-
 	log.Printf("✅ CONFIRM: Updating order to 'completed' | OrderID=%s", req.OrderID)
 
-	if err := uc.repository.UpdateOrderStatus(ctx, req.OrderID, "completed"); err != nil {
+	tx, err := uc.repository.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := uc.repository.GetOrderStatusTx(ctx, tx, req.OrderID)
+	if err != nil {
+		return err
+	}
+
+	if status == "completed" {
+		log.Printf("ℹ️ [CONFIRM] Order already completed for OrderID=%s", req.OrderID)
+		return ErrNoChange
+	}
+
+	if status == "cancelled" {
+		err := &OrderError{Message: fmt.Sprintf("invalid status to confirm: order %s is cancelled", req.OrderID)}
+		uc.appendSagaEvent(ctx, req, SagaPhaseConfirm, "failed")
+		uc.dispatchResume(ctx, req, req.GID, "confirm_failed", err)
+		return err
+	}
+
+	applied, err := uc.repository.UpdateOrderStatusTx(ctx, tx, req.OrderID, "pending", "completed")
+	if err != nil {
 		log.Printf("❌ CONFIRM FAILED: Failed to update order status | OrderID=%s | Error=%v", req.OrderID, err)
+		uc.appendSagaEvent(ctx, req, SagaPhaseConfirm, "failed")
+		uc.dispatchResume(ctx, req, req.GID, "confirm_failed", err)
 		return err
 	}
 
+	if !applied {
+		log.Printf("ℹ️ [CONFIRM] Order no longer pending for OrderID=%s", req.OrderID)
+		return ErrNoChange
+	}
+
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, "order.confirmed"); err != nil {
+		log.Printf("❌ CONFIRM FAILED: Failed to enqueue outbox event | OrderID=%s | Error=%v", req.OrderID, err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	uc.appendSagaEvent(ctx, req, SagaPhaseConfirm, "ok")
 	log.Printf("✅ CONFIRM SUCCESS: Order completed | OrderID=%s", req.OrderID)
+	uc.dispatchResume(ctx, req, req.GID, "completed", nil)
 	return nil
 }
 
@@ -96,43 +243,74 @@ func (uc *OrderUseCase) ConfirmCreateOrder(ctx context.Context, req TCCActionReq
 func (uc *OrderUseCase) CancelCreateOrder(ctx context.Context, req TCCActionRequest) error {
 	log.Printf("CANCEL: Updating order to 'cancelled' | OrderID=%s", req.OrderID)
 
-	if err := uc.repository.UpdateOrderStatus(ctx, req.OrderID, "cancelled"); err != nil {
-		log.Printf("❌ CANCEL FAILED: Failed to update order status | OrderID=%s | Error=%v", req.OrderID, err)
+	tx, err := uc.repository.BeginTx(ctx)
+	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	log.Printf("✅ CANCEL SUCCESS: Order cancelled | OrderID=%s", req.OrderID)
-	return nil
-}
+	status, err := uc.repository.GetOrderStatusTx(ctx, tx, req.OrderID)
+	if err != nil {
+		return err
+	}
 
-// extractTraceContext recupera o trace context do payload e injeta no contexto
-func extractTraceContext(ctx context.Context, traceIDStr, spanIDStr string) context.Context {
-	if traceIDStr == "" || spanIDStr == "" {
-		return ctx
+	if status == "cancelled" {
+		log.Printf("ℹ️ [CANCEL] Order already cancelled for OrderID=%s", req.OrderID)
+		return ErrNoChange
 	}
 
-	// Parse traceID e spanID
-	traceID, err := trace.TraceIDFromHex(traceIDStr)
-	if err != nil {
-		log.Printf("⚠️  Invalid traceID: %v", err)
-		return ctx
+	if status == "completed" {
+		err := &OrderError{Message: fmt.Sprintf("invalid status to cancel: order %s is completed", req.OrderID)}
+		uc.appendSagaEvent(ctx, req, SagaPhaseCancel, "failed")
+		uc.dispatchResume(ctx, req, req.GID, "cancel_failed", err)
+		return err
 	}
 
-	spanID, err := trace.SpanIDFromHex(spanIDStr)
+	applied, err := uc.repository.UpdateOrderStatusTx(ctx, tx, req.OrderID, "pending", "cancelled")
 	if err != nil {
-		log.Printf("⚠️  Invalid spanID: %v", err)
-		return ctx
+		log.Printf("❌ CANCEL FAILED: Failed to update order status | OrderID=%s | Error=%v", req.OrderID, err)
+		uc.appendSagaEvent(ctx, req, SagaPhaseCancel, "failed")
+		uc.dispatchResume(ctx, req, req.GID, "cancel_failed", err)
+		return err
+	}
+
+	if !applied {
+		log.Printf("ℹ️ [CANCEL] Order no longer pending for OrderID=%s", req.OrderID)
+		return ErrNoChange
+	}
+
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, "order.cancelled"); err != nil {
+		log.Printf("❌ CANCEL FAILED: Failed to enqueue outbox event | OrderID=%s | Error=%v", req.OrderID, err)
+		return err
 	}
 
-	// Criar SpanContext
-	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
-		TraceID:    traceID,
-		SpanID:     spanID,
-		TraceFlags: trace.FlagsSampled,
-	})
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 
-	// Injetar no contexto
-	return trace.ContextWithSpanContext(ctx, spanContext)
+	uc.appendSagaEvent(ctx, req, SagaPhaseCancel, "ok")
+	log.Printf("✅ CANCEL SUCCESS: Order cancelled | OrderID=%s", req.OrderID)
+	uc.dispatchResume(ctx, req, req.GID, "cancelled", nil)
+	return nil
+}
+
+// traceIDFromTraceparent extrai o trace-id de um header traceparent W3C (formato
+// 00-{trace-id}-{parent-id}-{flags}), usado apenas para preencher o log de auditoria
+func traceIDFromTraceparent(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 {
+		return ""
+	}
+	return parts[1]
+}
+
+// BusinessError é implementado por erros que representam um desfecho de negócio determinístico
+// (preço inválido, pedido inexistente, ...), para os quais reexecutar a branch TCC não muda o
+// resultado. writeTCCResponse (handlers.go) usa esse contrato para decidir entre a convenção
+// FAILURE do DTM (409 com dtm_result, não retentar) e um 500 genérico (retentar)
+type BusinessError interface {
+	error
+	BusinessError() bool
 }
 
 // Erros customizados
@@ -147,3 +325,9 @@ type OrderError struct {
 func (e *OrderError) Error() string {
 	return e.Message
 }
+
+// BusinessError marca OrderError como um erro de negócio, não uma falha de infraestrutura (ver
+// BusinessError acima)
+func (e *OrderError) BusinessError() bool {
+	return true
+}
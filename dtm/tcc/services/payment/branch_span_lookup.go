@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// branchSpanStoreKeyPrefix espelha exatamente o valor usado por
+// dtm/tcc/services/orders/branch_span_store.go - como não há go.mod para compartilhar o tipo
+// entre os dois binários, a codificação da chave/valor precisa ser duplicada "na mão" aqui para
+// que este serviço consiga ler o que o orchestrator gravou.
+const branchSpanStoreKeyPrefix = "tcc:branchspan:"
+
+// branchSpanLookup é a contraparte somente-leitura de branchSpanStore: o orchestrator (orders)
+// é quem grava o SpanContext do registro da branch; este serviço só precisa recuperá-lo para
+// linkar seu próprio span da fase TRY a ele.
+type branchSpanLookup struct {
+	client *redis.Client
+}
+
+func newBranchSpanLookup(client *redis.Client) *branchSpanLookup {
+	return &branchSpanLookup{client: client}
+}
+
+func branchSpanStoreKey(gid, branch string) string {
+	return branchSpanStoreKeyPrefix + gid + ":" + branch
+}
+
+// Lookup recupera o SpanContext gravado pelo orchestrator para (gid, branch), caso ainda exista
+func (s *branchSpanLookup) Lookup(ctx context.Context, gid, branch string) (trace.SpanContext, bool) {
+	raw, err := s.client.Get(ctx, branchSpanStoreKey(gid, branch)).Result()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("⚠️ [BRANCH SPAN LOOKUP] failed to look up span context for gid=%s branch=%s: %v", gid, branch, err)
+		}
+		return trace.SpanContext{}, false
+	}
+
+	parts := strings.Split(raw, "-")
+	if len(parts) != 3 {
+		return trace.SpanContext{}, false
+	}
+
+	traceID, err := trace.TraceIDFromHex(parts[0])
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	spanID, err := trace.SpanIDFromHex(parts[1])
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	var flags trace.TraceFlags
+	if _, err := fmt.Sscanf(parts[2], "%02x", &flags); err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	})
+	return sc, true
+}
+
+// initBranchSpanLookup conecta ao mesmo Redis usado por initBranchSpanStore no orders-service
+func initBranchSpanLookup() (*branchSpanLookup, func()) {
+	opts, err := redis.ParseURL(getEnv("REDIS_URL", "redis://redis:6379/0"))
+	if err != nil {
+		log.Fatalf("Failed to parse REDIS_URL for branch span lookup: %v", err)
+	}
+
+	client := redis.NewClient(opts)
+	return newBranchSpanLookup(client), func() {
+		if err := client.Close(); err != nil {
+			log.Printf("Error closing branch span lookup redis client: %v", err)
+		}
+	}
+}
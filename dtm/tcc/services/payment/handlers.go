@@ -2,32 +2,67 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// startSpanFromPayload creates a child span linked to the propagated trace context
-func startSpanFromPayload(c *gin.Context, operationName string, req TCCActionRequest) (context.Context, trace.Span) {
-	ctx := c.Request.Context()
-
-	if req.TraceID != "" && req.SpanID != "" {
-		parsedTraceID, _ := trace.TraceIDFromHex(req.TraceID)
-		parsedSpanID, _ := trace.SpanIDFromHex(req.SpanID)
+// writeTCCResponse traduz o erro de uma fase TCC para a resposta HTTP que o DTM espera: erros de
+// negócio (BusinessError) viram 409 com {"dtm_result":"FAILURE"}, a convenção do DTM para "a
+// branch falhou de forma definitiva, não adianta retentar" - dispara o rollback/cancelamento
+// global imediatamente em vez de fazer o DTM bater na branch repetidamente até o timeout. Qualquer
+// outro erro (infraestrutura: banco fora do ar, timeout, ...) continua 500, para que o DTM
+// retente - esse é o comportamento que já existia antes desta distinção
+func writeTCCResponse(c *gin.Context, span trace.Span, err error, phaseFailedMsg string) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, phaseFailedMsg)
+
+	var be BusinessError
+	if errors.As(err, &be) && be.BusinessError() {
+		log.Printf("⛔ %s (business error, DTM should not retry): %v", phaseFailedMsg, err)
+		c.JSON(http.StatusConflict, gin.H{"dtm_result": "FAILURE", "error": err.Error()})
+		return
+	}
 
-		spanContext := trace.NewSpanContext(trace.SpanContextConfig{
-			TraceID:    parsedTraceID,
-			SpanID:     parsedSpanID,
-			TraceFlags: trace.FlagsSampled,
-			Remote:     true,
-		})
+	log.Printf("❌ %s: %v", phaseFailedMsg, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
 
-		ctx = trace.ContextWithSpanContext(ctx, spanContext)
+// startSpanFromPayload extrai o trace context propagado pelo DTM orchestrator (via o par
+// traceparent/tracestate no payload, já que o DTM não repassa headers HTTP arbitrários) usando o
+// TextMapPropagator configurado, e enriquece o span com os atributos semânticos da fase TCC. Na
+// fase TRY, também linka (trace.Link) o span ao span "tcc.branch.payment" criado pelo
+// orchestrator ao registrar esta branch, recuperado via branchSpans (ver branch_span_lookup.go)
+func startSpanFromPayload(c *gin.Context, operationName, phase string, req TCCActionRequest) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.MapCarrier{
+		"traceparent": req.Traceparent,
+		"tracestate":  req.Tracestate,
+		"baggage":     req.Baggage,
+	})
+
+	var opts []trace.SpanStartOption
+	if phase == SagaPhaseTry && branchSpans != nil && req.GID != "" {
+		if sc, ok := branchSpans.Lookup(ctx, req.GID, "payment"); ok {
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+		}
 	}
 
-	return tracer.Start(ctx, operationName)
+	ctx, span := tracer.Start(ctx, operationName, opts...)
+	span.SetAttributes(
+		attribute.String("saga.phase", phase),
+		attribute.String("saga.order_id", req.OrderID),
+		attribute.String("saga.user_id", req.UserID),
+		attribute.String("db.system", "postgresql"),
+	)
+	return ctx, span
 }
 
 // HandleTryDebitWallet handler para fase TRY do TCC
@@ -40,16 +75,19 @@ func HandleTryDebitWallet(uc *PaymentUseCase) gin.HandlerFunc {
 			return
 		}
 
-		ctx, span := startSpanFromPayload(c, "payment.TryDebitWallet", req)
+		ctx, span := startSpanFromPayload(c, "payment.TryDebitWallet", SagaPhaseTry, req)
 		defer span.End()
 
+		start := time.Now()
 		err := uc.TryDebitWallet(ctx, req)
+		elapsed := time.Since(start)
 		if err != nil {
-			log.Printf("❌ [TRY] ORDER_ID %s | Failed: %v", req.OrderID, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			lifecycleM.RecordTry(ctx, "error", elapsed)
+			writeTCCResponse(c, span, err, "TRY phase failed")
 			return
 		}
 
+		lifecycleM.RecordTry(ctx, "ok", elapsed)
 		c.JSON(http.StatusOK, gin.H{"status": "try_success", "order_id": req.OrderID})
 	}
 }
@@ -64,16 +102,19 @@ func HandleConfirmDebitWallet(uc *PaymentUseCase) gin.HandlerFunc {
 			return
 		}
 
-		ctx, span := startSpanFromPayload(c, "payment.ConfirmDebitWallet", req)
+		ctx, span := startSpanFromPayload(c, "payment.ConfirmDebitWallet", SagaPhaseConfirm, req)
 		defer span.End()
 
+		start := time.Now()
 		err := uc.ConfirmDebitWallet(ctx, req)
+		elapsed := time.Since(start)
 		if err != nil {
-			log.Printf("❌ [CONFIRM] ORDER_ID %s | Failed: %v", req.OrderID, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			lifecycleM.RecordConfirm(ctx, "error", elapsed)
+			writeTCCResponse(c, span, err, "CONFIRM phase failed")
 			return
 		}
 
+		lifecycleM.RecordConfirm(ctx, "ok", elapsed)
 		c.JSON(http.StatusOK, gin.H{"status": "confirm_success", "order_id": req.OrderID})
 	}
 }
@@ -88,16 +129,17 @@ func HandleCancelDebitWallet(uc *PaymentUseCase) gin.HandlerFunc {
 			return
 		}
 
-		ctx, span := startSpanFromPayload(c, "payment.CancelDebitWallet", req)
+		ctx, span := startSpanFromPayload(c, "payment.CancelDebitWallet", SagaPhaseCancel, req)
 		defer span.End()
 
 		err := uc.CancelDebitWallet(ctx, req)
 		if err != nil {
-			log.Printf("❌ [CANCEL] ORDER_ID %s | Failed: %v", req.OrderID, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			lifecycleM.AddCancel(ctx, "error")
+			writeTCCResponse(c, span, err, "CANCEL phase failed")
 			return
 		}
 
+		lifecycleM.AddCancel(ctx, "ok")
 		c.JSON(http.StatusOK, gin.H{"status": "cancel_success", "order_id": req.OrderID})
 	}
 }
@@ -108,3 +150,14 @@ func HandleHealth() gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"status": "healthy", "service": "payment-service-tcc"})
 	}
 }
+
+// HandleAdminReconcile dispara uma varredura imediata do Reconciler (ver reconciler.go), fora do
+// intervalo periódico de Start - útil para forçar a reconciliação em testes de benchmark sem
+// esperar o próximo tick. Continua encontrando só as linhas paradas há mais de
+// reconcileStuckAfter()
+func HandleAdminReconcile(reconciler *Reconciler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reconciled, stuck := reconciler.Sweep(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"stuck_found": stuck, "reconciled": reconciled})
+	}
+}
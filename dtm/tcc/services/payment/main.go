@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	dbPool      *pgxpool.Pool
+	tracer      trace.Tracer
+	lifecycleM  *branchLifecycleMetrics
+	branchSpans *branchSpanLookup
+)
+
+func main() {
+	// Initialize OpenTelemetry Tracer
+	tp, err := initTracer()
+	if err != nil {
+		log.Fatalf("Failed to initialize tracer: %v", err)
+	}
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down tracer: %v", err)
+		}
+	}()
+
+	tracer = tp.Tracer("payment-service-tcc")
+
+	mp, err := initMetrics()
+	if err != nil {
+		log.Fatalf("Failed to initialize metrics: %v", err)
+	}
+	defer func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down meter: %v", err)
+		}
+	}()
+
+	// Initialize database
+	dbPool, err = initDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer dbPool.Close()
+
+	meter := mp.Meter("payment-service-tcc")
+	if err := registerPoolGauges(meter, dbPool); err != nil {
+		log.Fatalf("Failed to register pgx pool gauges: %v", err)
+	}
+
+	// Per-phase business metrics (tcc.branch.try.duration, tcc.branch.confirm.duration,
+	// tcc.branch.cancel.count) observed from this participant's own try/confirm/cancel handlers
+	serviceName := getEnv("SERVICE_NAME", "payment-service-tcc")
+	lifecycleM, err = newBranchLifecycleMetrics(meter, serviceName, "payment")
+	if err != nil {
+		log.Fatalf("Failed to initialize branch lifecycle metrics: %v", err)
+	}
+
+	// Branch span-context lookup: recupera via Redis o SpanContext gravado pelo orchestrator
+	// (orders) ao registrar a branch "payment", para linkar o span da fase TRY a ele
+	var closeBranchSpans func()
+	branchSpans, closeBranchSpans = initBranchSpanLookup()
+	defer closeBranchSpans()
+
+	// Chaos config: só injeta falhas quando CHAOS_ENABLED=true, ajustável em tempo real via
+	// POST /admin/chaos - é o alvo de cmd/benchrunner/chaos_hook.go, que dobra
+	// payment_fail_rate na metade do cenário para observar a recuperação de cada protocolo
+	chaosCfg := newChaosConfigFromEnv()
+
+	// Setup repositories and use cases
+	paymentRepository := NewPostgresPaymentRepository(dbPool, chaosCfg)
+	sagaLogRepository := NewPostgresSagaLogRepository(dbPool)
+	outboxRepository := NewPostgresOutboxRepository(dbPool)
+	paymentUseCase := NewPaymentUseCase(paymentRepository, sagaLogRepository, outboxRepository)
+
+	// Reconciliation job: re-drive payment_transactions presas consultando o desfecho global no
+	// DTM, e expõe a mesma varredura sob demanda via /admin/reconcile (ver HandleAdminReconcile)
+	dtmServer := getEnv("DTM_SERVER", "http://dtm:36789/api/dtmsvr")
+	reconciler := NewReconciler(paymentRepository, paymentUseCase, dtmServer)
+	reconcilerCtx, stopReconciler := context.WithCancel(context.Background())
+	defer stopReconciler()
+	reconciler.Start(reconcilerCtx, 30*time.Second)
+
+	// Outbox relayer: entrega payment_outbox via POST ao callback configurado, fechando o
+	// dual-write gap entre os handlers de fase acima e qualquer notificação para consumidores
+	// downstream (ver outbox_relay.go - diferente de orders, este serviço usa um callback HTTP
+	// em vez de publicar direto no NATS)
+	callbackURL := getEnv("PAYMENT_OUTBOX_CALLBACK_URL", "http://event-relay:8080/webhooks/payment-events")
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	StartOutboxRelay(relayCtx, outboxRepository, callbackURL, 2*time.Second, 20)
+
+	// Setup Gin router
+	r := gin.New()
+
+	// Add middleware
+	r.Use(gin.Logger())
+	r.Use(gin.RecoveryWithWriter(gin.DefaultWriter, func(c *gin.Context, recovered interface{}) {
+		log.Printf("🚨 PANIC RECOVERED: %v", recovered)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	r.Use(otelgin.Middleware(serviceName))
+	r.Use(chaosMiddleware(chaosCfg))
+
+	// Health check
+	r.GET("/health", HandleHealth())
+
+	// TCC participant endpoints - chamados pelo DTM
+	r.POST("/api/payment/try", HandleTryDebitWallet(paymentUseCase))
+	r.POST("/api/payment/confirm", HandleConfirmDebitWallet(paymentUseCase))
+	r.POST("/api/payment/cancel", HandleCancelDebitWallet(paymentUseCase))
+
+	// Admin endpoint - força uma varredura do Reconciler fora do intervalo periódico de Start
+	r.POST("/admin/reconcile", HandleAdminReconcile(reconciler))
+
+	// Admin endpoint - ajusta a injeção de falhas em tempo real (ver chaos.go)
+	r.GET("/admin/chaos", chaosAdminHandler(chaosCfg))
+	r.POST("/admin/chaos", chaosAdminHandler(chaosCfg))
+
+	port := getEnv("PORT", "8082")
+	log.Printf("🚀 Payment Service (TCC) listening on port %s", port)
+	srv := &http.Server{
+		Addr:         ":" + port,
+		Handler:      r,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  30 * time.Second,
+	}
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
+
+func initDB() (*pgxpool.Pool, error) {
+	dsn := fmt.Sprintf(
+		"postgres://%s:%s@%s:%s/%s?sslmode=disable&pool_max_conns=25&pool_min_conns=5",
+		getEnv("DATABASE_USER", "root"),
+		getEnv("DATABASE_PASSWORD", "pass"),
+		getEnv("DATABASE_HOST", "postgres"),
+		getEnv("DATABASE_PORT", "5432"),
+		getEnv("DATABASE_NAME", "payment_db"),
+	)
+
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
+	}
+
+	config.MaxConns = 10
+	config.MaxConnLifetime = time.Hour
+	config.MaxConnIdleTime = 30 * time.Minute
+	config.HealthCheckPeriod = 1 * time.Minute
+
+	// Instrumenta cada Query/Exec/BeginTx com um span "db.query", para decompor o tempo gasto no
+	// Postgres dentro do flame graph de cada fase do TCC
+	config.ConnConfig.Tracer = newDBQueryTracer(getEnv("SERVICE_NAME", "payment-service-tcc"))
+
+	ctx := context.Background()
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+
+	// Wait for database to be ready
+	for i := 0; i < 30; i++ {
+		if err := pool.Ping(ctx); err == nil {
+			log.Println("✅ Connected to payment database with connection pool")
+			return pool, nil
+		}
+		log.Printf("⏳ Waiting for database... (%d/30)", i+1)
+		time.Sleep(1 * time.Second)
+	}
+
+	pool.Close()
+	return nil, fmt.Errorf("failed to connect to database after 30 attempts")
+}
+
+func initTracer() (*sdktrace.TracerProvider, error) {
+	ctx := context.Background()
+
+	otlpEndpoint := getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "otel-collector:4318")
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpoint(otlpEndpoint),
+		otlptracehttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(getEnv("SERVICE_NAME", "payment-service-tcc")),
+			semconv.ServiceVersion("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+
+	otel.SetTracerProvider(tp)
+	// Composto com Baggage (além de TraceContext) para que atributos de negócio propagados pelo
+	// orquestrador sobrevivam ao salto entre branches TCC
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return tp, nil
+}
+
+func initMetrics() (*sdkmetric.MeterProvider, error) {
+	ctx := context.Background()
+
+	otlpEndpoint := getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "otel-collector:4318")
+
+	exporter, err := otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpoint(otlpEndpoint),
+		otlpmetrichttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(getEnv("SERVICE_NAME", "payment-service-tcc")),
+			semconv.ServiceVersion("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(res),
+	)
+
+	otel.SetMeterProvider(mp)
+
+	return mp, nil
+}
+
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
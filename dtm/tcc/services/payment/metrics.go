@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// registerPoolGauges expõe a ocupação do pgx pool (pgx_pool_acquired/pgx_pool_idle), nos mesmos
+// moldes de dtm/tcc/services/orders/metrics.go
+func registerPoolGauges(meter metric.Meter, pool *pgxpool.Pool) error {
+	_, err := meter.Int64ObservableGauge(
+		"pgx_pool_acquired",
+		metric.WithDescription("Number of connections currently acquired from the pgx pool"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(pool.Stat().AcquiredConns()))
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create pgx_pool_acquired gauge: %w", err)
+	}
+
+	_, err = meter.Int64ObservableGauge(
+		"pgx_pool_idle",
+		metric.WithDescription("Number of idle connections currently held by the pgx pool"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(pool.Stat().IdleConns()))
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create pgx_pool_idle gauge: %w", err)
+	}
+
+	return nil
+}
@@ -14,15 +14,27 @@ type Wallet struct {
 	UpdatedAt       time.Time `json:"updated_at"`
 }
 
-// TCCActionRequest representa o payload das requisições TCC
+// TCCActionRequest representa o payload das requisições TCC. Carrega o trace context como o par
+// traceparent/tracestate do W3C Trace Context, já que o DTM não repassa headers HTTP arbitrários
+// entre o registro da branch e a chamada que ele faz mais tarde
 type TCCActionRequest struct {
-	OrderID    string `json:"order_id"`
-	UserID     string `json:"user_id"`
-	ProductID  string `json:"product_id"`
-	Quantity   int    `json:"quantity"`
-	TotalPrice int    `json:"total_price"`
-	TraceID    string `json:"trace_id"`
-	SpanID     string `json:"span_id"`
+	OrderID     string `json:"order_id"`
+	UserID      string `json:"user_id"`
+	ProductID   string `json:"product_id"`
+	Quantity    int    `json:"quantity"`
+	TotalPrice  int    `json:"total_price"`
+	Traceparent string `json:"traceparent,omitempty"`
+	Tracestate  string `json:"tracestate,omitempty"`
+
+	// Baggage carrega o header W3C Baggage (ex: "benchmark_run_id=...") propagado junto com
+	// Traceparent/Tracestate pelo orchestrator - ver o campo equivalente em
+	// dtm/tcc/services/orders/models.go
+	Baggage string `json:"baggage,omitempty"`
+
+	// GID identifica a transação TCC que originou esta ação, usado para recuperar o SpanContext
+	// do span de registro desta branch no orchestrator (ver branch_span_lookup.go) e linkar o
+	// span da fase TRY a ele
+	GID string `json:"gid,omitempty"`
 }
 
 // PaymentRepository define as operações de persistência de pagamentos
@@ -33,18 +45,35 @@ type PaymentRepository interface {
 	// Lock pessimista
 	GetWalletForUpdate(ctx context.Context, tx Tx, userID string) (*Wallet, error)
 
-	// TRY: Reserva o saldo (decrementa available_amount)
-	TryReserveBalance(ctx context.Context, tx Tx, userID, orderID string, amount int) error
+	// TRY: Reserva o saldo (decrementa available_amount) e cria a linha de payment_transactions
+	// já em TxStateReserved (ver transaction_state.go)
+	TryReserveBalance(ctx context.Context, tx Tx, userID, orderID, gid string, amount int) error
 
-	// CONFIRM: Confirma o débito (decrementa current_amount, cria transação)
+	// CONFIRM/CANCEL aplicam apenas a mutação de saldo - a transição de estado da
+	// payment_transaction é responsabilidade do use case, via TransitionPaymentTransaction abaixo
 	ConfirmDebit(ctx context.Context, tx Tx, userID, orderID string, amount int) error
-
-	// CANCEL: Cancela a reserva (incrementa available_amount)
 	CancelReserveBalance(ctx context.Context, tx Tx, userID, orderID string, amount int) error
 
-	// Métodos para verificação de status das transações (dentro da transação)
-	GetPaymentTransactionByOrderIDAndStatus(ctx context.Context, tx Tx, orderID, status string) (bool, error)
+	// Verificação de idempotência (dentro da transação): um único método de status usado por
+	// TRY/CONFIRM/CANCEL evita a checagem divergente entre fases
 	GetPaymentTransactionStatusByOrderID(ctx context.Context, tx Tx, orderID string) (string, error)
+
+	// GetPaymentTransactionStatus é o equivalente de GetPaymentTransactionStatusByOrderID sem uma
+	// transação de negócio já aberta, usado por CONFIRM/CANCEL para decidir, antes de abrir sua
+	// própria transação, se ainda há trabalho a fazer (idempotência) ou se a linha já avançou
+	GetPaymentTransactionStatus(ctx context.Context, orderID string) (string, error)
+
+	// TransitionPaymentTransaction aplica uma transição validada pela máquina de estados de
+	// transaction_state.go em sua própria transação curta, comitada independentemente da
+	// operação de negócio que ela precede/sucede - é esse commit isolado que deixa a linha
+	// observavelmente presa num estado intermediário se o processo cair logo em seguida, o que o
+	// Reconciler (reconciler.go) depende para encontrar trabalho a fazer. ok=false sem erro
+	// indica que o estado atual não é mais `from` - outra chamada já moveu a linha adiante.
+	TransitionPaymentTransaction(ctx context.Context, orderID, from, to string, lastErr error) (bool, error)
+
+	// ListStuckTransactions lista as linhas em qualquer um dos estados informados cujo
+	// updated_at é anterior a olderThan atrás de agora - usado pelo Reconciler
+	ListStuckTransactions(ctx context.Context, states []string, olderThan time.Duration) ([]StuckTransaction, error)
 }
 
 // Tx representa uma transação de banco de dados
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Status de entrega de uma entrada do outbox
+const (
+	OutboxStatusPending    = "pending"
+	OutboxStatusDispatched = "dispatched"
+)
+
+// OutboxEvent representa uma entrada do outbox transacional: é gravada na MESMA transação de
+// negócio que aplica a mudança na carteira, diferente do saga_events (log de auditoria), que é
+// gravado numa conexão separada após o commit. Isso garante que o evento só existe se a
+// mudança de estado que ele descreve também existir.
+type OutboxEvent struct {
+	ID           int64   `json:"id"`
+	OrderID      string  `json:"order_id"`
+	Phase        string  `json:"phase"`
+	Status       string  `json:"status"`
+	Payload      []byte  `json:"payload"`
+	DispatchedAt *string `json:"dispatched_at,omitempty"`
+}
+
+// OutboxRepository define as operações de persistência do outbox transacional de pagamentos
+type OutboxRepository interface {
+	// EnqueueOutboxEvent grava o evento dentro da transação de negócio em andamento
+	EnqueueOutboxEvent(ctx context.Context, tx Tx, event *OutboxEvent) error
+
+	// ClaimPendingEvents seleciona um lote de eventos pendentes com FOR UPDATE SKIP LOCKED. A query
+	// roda fora de uma transação explícita, então o lock só dura o próprio SELECT: duas instâncias
+	// do relay podem receber o mesmo evento
+	ClaimPendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error)
+
+	// MarkDispatched marca o evento como entregue ao callback configurado
+	MarkDispatched(ctx context.Context, id int64) error
+}
+
+// PostgresOutboxRepository implementa OutboxRepository usando a tabela payment_outbox
+type PostgresOutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresOutboxRepository(pool *pgxpool.Pool) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{pool: pool}
+}
+
+// EnqueueOutboxEvent grava o evento dentro da transação de negócio recebida via tx, garantindo
+// atomicidade entre a mudança na carteira e a entrada no outbox
+func (r *PostgresOutboxRepository) EnqueueOutboxEvent(ctx context.Context, tx Tx, event *OutboxEvent) error {
+	pgTx := tx.(*PostgresTx).tx
+
+	query := `
+		INSERT INTO payment_outbox (order_id, phase, status, payload, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id
+	`
+	err := pgTx.QueryRow(ctx, query, event.OrderID, event.Phase, event.Status, event.Payload).Scan(&event.ID)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue outbox event: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimPendingEvents seleciona até `limit` eventos pendentes com FOR UPDATE SKIP LOCKED, usado
+// pelo relay para varrer o outbox; o lock termina com o SELECT, não com a entrega
+func (r *PostgresOutboxRepository) ClaimPendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, order_id, phase, status, payload
+		FROM payment_outbox
+		WHERE status = $1
+		ORDER BY id ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, OutboxStatusPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var event OutboxEvent
+		if err := rows.Scan(&event.ID, &event.OrderID, &event.Phase, &event.Status, &event.Payload); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// MarkDispatched marca o evento como entregue ao callback configurado
+func (r *PostgresOutboxRepository) MarkDispatched(ctx context.Context, id int64) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE payment_outbox
+		SET status = $1, dispatched_at = NOW()
+		WHERE id = $2
+	`, OutboxStatusDispatched, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event as dispatched: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var outboxMeter = otel.Meter("payment-outbox-relay")
+
+// StartOutboxRelay inicia uma goroutine que varre periodicamente o outbox em busca de eventos
+// pendentes e os entrega via POST ao callbackURL configurado, marcando-os como dispatched ao
+// obter uma resposta 2xx. Várias instâncias podem rodar concorrentemente, mas sem lotes
+// disjuntos: o lock de ClaimPendingEvents termina com o SELECT, então um evento ainda pendente
+// pode ser entregue por mais de uma réplica.
+func StartOutboxRelay(ctx context.Context, outbox OutboxRepository, callbackURL string, interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+
+	dispatched, err := outboxMeter.Int64Counter(
+		"outbox.events.dispatched",
+		metric.WithDescription("Number of payment outbox events successfully delivered to the callback URL"),
+	)
+	if err != nil {
+		log.Printf("⚠️ [OUTBOX RELAY] failed to create dispatched counter: %v", err)
+	}
+
+	backlog, err := outboxMeter.Int64Histogram(
+		"outbox.backlog.size",
+		metric.WithDescription("Number of pending payment outbox events observed at each relay tick"),
+	)
+	if err != nil {
+		log.Printf("⚠️ [OUTBOX RELAY] failed to create backlog histogram: %v", err)
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				relayPendingEvents(ctx, outbox, httpClient, callbackURL, batchSize, dispatched, backlog)
+			}
+		}
+	}()
+}
+
+func relayPendingEvents(ctx context.Context, outbox OutboxRepository, httpClient *http.Client, callbackURL string, batchSize int, dispatched metric.Int64Counter, backlog metric.Int64Histogram) {
+	events, err := outbox.ClaimPendingEvents(ctx, batchSize)
+	if err != nil {
+		log.Printf("❌ [OUTBOX RELAY] failed to claim pending events: %v", err)
+		return
+	}
+
+	if backlog != nil {
+		backlog.Record(ctx, int64(len(events)))
+	}
+
+	for _, event := range events {
+		if err := postOutboxEvent(ctx, httpClient, callbackURL, event); err != nil {
+			log.Printf("❌ [OUTBOX RELAY] failed to dispatch event id=%d OrderID=%s: %v", event.ID, event.OrderID, err)
+			continue
+		}
+
+		if err := outbox.MarkDispatched(ctx, event.ID); err != nil {
+			log.Printf("❌ [OUTBOX RELAY] failed to mark event id=%d as dispatched: %v", event.ID, err)
+			continue
+		}
+
+		if dispatched != nil {
+			dispatched.Add(ctx, 1)
+		}
+		log.Printf("📤 [OUTBOX RELAY] dispatched event id=%d OrderID=%s Phase=%s", event.ID, event.OrderID, event.Phase)
+	}
+}
+
+func postOutboxEvent(ctx context.Context, httpClient *http.Client, callbackURL string, event OutboxEvent) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(event.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to build callback request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("callback request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
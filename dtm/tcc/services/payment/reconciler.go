@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var reconcilerMeter = otel.Meter("payment-reconciler")
+
+// StuckTransaction representa uma linha de payment_transactions presa há tempo demais em um
+// estado intermediário (Reserved/Confirming/Cancelling) - sinal de que o processo que a escreveu
+// caiu, ou nunca recebeu a reentrega do DTM, antes de alcançar um estado terminal.
+type StuckTransaction struct {
+	OrderID string
+	UserID  string
+	GID     string
+	Amount  int
+	Status  string
+}
+
+// defaultReconcileStuckAfter é o tempo mínimo parado em um estado intermediário para a linha ser
+// considerada presa - curto o suficiente para detectar o problema rápido, longo o suficiente
+// para não competir com o fluxo TRY/CONFIRM/CANCEL normal, que costuma concluir em milissegundos.
+const defaultReconcileStuckAfter = 30 * time.Second
+
+// reconcileStuckAfter lê PAYMENT_RECONCILE_STUCK_AFTER (ex: "30s"), caindo em
+// defaultReconcileStuckAfter quando a variável está ausente ou é inválida
+func reconcileStuckAfter() time.Duration {
+	raw := os.Getenv("PAYMENT_RECONCILE_STUCK_AFTER")
+	if raw == "" {
+		return defaultReconcileStuckAfter
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Printf("⚠️ [RECONCILER] invalid PAYMENT_RECONCILE_STUCK_AFTER=%q, using default %s", raw, defaultReconcileStuckAfter)
+		return defaultReconcileStuckAfter
+	}
+	return d
+}
+
+// Reconciler varre periodicamente payment_transactions por linhas presas e as reconcilia
+// consultando o DTM pelo desfecho da transação global que as originou, passando pelo
+// PaymentUseCase (ConfirmDebitWallet/CancelDebitWallet) em vez de chamar o repositório
+// diretamente, para reaproveitar as mesmas checagens de idempotência que TRY/CONFIRM/CANCEL
+// já aplicam quando o DTM reentrega uma branch.
+type Reconciler struct {
+	repository PaymentRepository
+	useCase    *PaymentUseCase
+	dtmServer  string
+
+	stuckTotal      metric.Int64Counter
+	reconciledTotal metric.Int64Counter
+}
+
+// NewReconciler cria um Reconciler; dtmServer é a base URL do servidor DTM
+// (ex: http://dtm:36789/api/dtmsvr)
+func NewReconciler(repository PaymentRepository, useCase *PaymentUseCase, dtmServer string) *Reconciler {
+	r := &Reconciler{repository: repository, useCase: useCase, dtmServer: dtmServer}
+
+	stuckTotal, err := reconcilerMeter.Int64Counter(
+		"payment_stuck_total",
+		metric.WithDescription("Number of payment_transactions rows found stuck in an intermediate state"),
+	)
+	if err != nil {
+		log.Printf("⚠️ [RECONCILER] failed to create payment_stuck_total counter: %v", err)
+	}
+	r.stuckTotal = stuckTotal
+
+	reconciledTotal, err := reconcilerMeter.Int64Counter(
+		"payment_reconciled_total",
+		metric.WithDescription("Number of stuck payment_transactions rows successfully driven to a terminal state"),
+	)
+	if err != nil {
+		log.Printf("⚠️ [RECONCILER] failed to create payment_reconciled_total counter: %v", err)
+	}
+	r.reconciledTotal = reconciledTotal
+
+	return r
+}
+
+// Start inicia a varredura periódica em uma goroutine; para ao cancelar ctx
+func (r *Reconciler) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Sweep(ctx)
+			}
+		}
+	}()
+}
+
+// Sweep executa uma varredura imediata, usada tanto pelo ticker periódico de Start quanto pelo
+// endpoint administrativo (ver HandleAdminReconcile em handlers.go)
+func (r *Reconciler) Sweep(ctx context.Context) (reconciled, stuck int) {
+	rows, err := r.repository.ListStuckTransactions(ctx,
+		[]string{TxStateReserved, TxStateConfirming, TxStateCancelling}, reconcileStuckAfter())
+	if err != nil {
+		log.Printf("❌ [RECONCILER] failed to list stuck transactions: %v", err)
+		return 0, 0
+	}
+
+	for _, row := range rows {
+		stuck++
+		if r.stuckTotal != nil {
+			r.stuckTotal.Add(ctx, 1)
+		}
+
+		if row.GID == "" {
+			log.Printf("⚠️ [RECONCILER] stuck transaction without gid, cannot query DTM | OrderID=%s Status=%s", row.OrderID, row.Status)
+			continue
+		}
+
+		global, err := queryDTMTransaction(ctx, r.dtmServer, row.GID)
+		if err != nil {
+			log.Printf("⚠️ [RECONCILER] DTM query failed for gid=%s, leaving OrderID=%s in %s: %v", row.GID, row.OrderID, row.Status, err)
+			continue
+		}
+
+		req := TCCActionRequest{OrderID: row.OrderID, UserID: row.UserID, TotalPrice: row.Amount, GID: row.GID}
+
+		switch global.Status {
+		case "succeed":
+			if err := r.useCase.ConfirmDebitWallet(ctx, req); err != nil {
+				log.Printf("❌ [RECONCILER] failed to drive OrderID=%s to %s: %v", row.OrderID, TxStateCompleted, err)
+				continue
+			}
+		case "failed":
+			if err := r.useCase.CancelDebitWallet(ctx, req); err != nil {
+				log.Printf("❌ [RECONCILER] failed to drive OrderID=%s to %s: %v", row.OrderID, TxStateCancelled, err)
+				continue
+			}
+		default:
+			log.Printf("ℹ️ [RECONCILER] gid=%s still %q globally, leaving OrderID=%s in %s", row.GID, global.Status, row.OrderID, row.Status)
+			continue
+		}
+
+		reconciled++
+		if r.reconciledTotal != nil {
+			r.reconciledTotal.Add(ctx, 1)
+		}
+		log.Printf("✅ [RECONCILER] reconciled OrderID=%s (was %s, DTM global=%s)", row.OrderID, row.Status, global.Status)
+	}
+
+	return reconciled, stuck
+}
@@ -5,17 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PostgresPaymentRepository struct {
-	pool *pgxpool.Pool
+	pool  *pgxpool.Pool
+	chaos *chaosConfig
 }
 
-func NewPostgresPaymentRepository(pool *pgxpool.Pool) *PostgresPaymentRepository {
-	return &PostgresPaymentRepository{pool: pool}
+func NewPostgresPaymentRepository(pool *pgxpool.Pool, chaos *chaosConfig) *PostgresPaymentRepository {
+	return &PostgresPaymentRepository{pool: pool, chaos: chaos}
 }
 
 // PostgresTx implementa a interface Tx
@@ -42,6 +44,10 @@ func (r *PostgresPaymentRepository) BeginTx(ctx context.Context) (Tx, error) {
 
 // GetWalletForUpdate obtém a carteira com lock pessimista (FOR UPDATE)
 func (r *PostgresPaymentRepository) GetWalletForUpdate(ctx context.Context, tx Tx, userID string) (*Wallet, error) {
+	if err := maybeChaosDeadlock(r.chaos); err != nil {
+		return nil, err
+	}
+
 	pgTx := tx.(*PostgresTx).tx
 
 	query := `
@@ -62,7 +68,7 @@ func (r *PostgresPaymentRepository) GetWalletForUpdate(ctx context.Context, tx T
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("user not found: %s", userID)
+			return nil, fmt.Errorf("%w: %s", ErrUserNotFound, userID)
 		}
 		return nil, fmt.Errorf("failed to get wallet for update: %w", err)
 	}
@@ -70,8 +76,10 @@ func (r *PostgresPaymentRepository) GetWalletForUpdate(ctx context.Context, tx T
 	return &wallet, nil
 }
 
-// TryReserveBalance reserva o saldo (TCC TRY) com lock pessimista
-func (r *PostgresPaymentRepository) TryReserveBalance(ctx context.Context, tx Tx, userID, orderID string, amount int) error {
+// TryReserveBalance reserva o saldo (TCC TRY) com lock pessimista e já cria a linha de
+// payment_transactions em TxStateReserved - TRY reserva e registra atomicamente, não há uma
+// fase "Started" observável separada neste fluxo (ver transaction_state.go)
+func (r *PostgresPaymentRepository) TryReserveBalance(ctx context.Context, tx Tx, userID, orderID, gid string, amount int) error {
 	pgTx := tx.(*PostgresTx).tx
 
 	// Atualiza available_amount
@@ -86,12 +94,11 @@ func (r *PostgresPaymentRepository) TryReserveBalance(ctx context.Context, tx Tx
 		return fmt.Errorf("failed to update available_amount: %w", err)
 	}
 
-	// Cria registro de transação com status pending
 	transactionQuery := `
-		INSERT INTO payment_transactions (user_id, order_id, amount, transaction_type, status, created_at)
-		VALUES ($1, $2, $3, 'debit', 'pending', NOW())
+		INSERT INTO payment_transactions (user_id, order_id, gid, amount, transaction_type, status, created_at, updated_at, attempt_count)
+		VALUES ($1, $2, $3, $4, 'debit', $5, NOW(), NOW(), 0)
 	`
-	_, err = pgTx.Exec(ctx, transactionQuery, userID, orderID, amount)
+	_, err = pgTx.Exec(ctx, transactionQuery, userID, orderID, gid, amount, TxStateReserved)
 	if err != nil {
 		return fmt.Errorf("failed to create transaction: %w", err)
 	}
@@ -100,11 +107,12 @@ func (r *PostgresPaymentRepository) TryReserveBalance(ctx context.Context, tx Tx
 	return nil
 }
 
-// ConfirmDebit confirma o débito (TCC CONFIRM) com lock pessimista
+// ConfirmDebit aplica a mutação de saldo da fase CONFIRM (TCC) com lock pessimista. Não toca no
+// status da payment_transaction - essa transição (Confirming -> Completed) é comitada à parte
+// pelo use case via TransitionPaymentTransaction, antes e depois de chamar este método
 func (r *PostgresPaymentRepository) ConfirmDebit(ctx context.Context, tx Tx, userID, orderID string, amount int) error {
 	pgTx := tx.(*PostgresTx).tx
 
-	// Atualiza current_amount
 	updateQuery := `
 		UPDATE wallets
 		SET current_amount = current_amount - $1,
@@ -116,26 +124,16 @@ func (r *PostgresPaymentRepository) ConfirmDebit(ctx context.Context, tx Tx, use
 		return fmt.Errorf("failed to update current_amount: %w", err)
 	}
 
-	// Atualiza status da transação para completed
-	updateStatusQuery := `
-		UPDATE payment_transactions
-		SET status = 'completed'
-		WHERE order_id = $1 AND status = 'pending'
-	`
-	_, err = pgTx.Exec(ctx, updateStatusQuery, orderID)
-	if err != nil {
-		return fmt.Errorf("failed to update transaction status: %w", err)
-	}
-
 	log.Printf("✅ [CONFIRM] Debited %d from user %s", amount, userID)
 	return nil
 }
 
-// CancelReserveBalance cancela a reserva (TCC CANCEL) com lock pessimista
+// CancelReserveBalance aplica a mutação de saldo da fase CANCEL (TCC) com lock pessimista. Não
+// toca no status da payment_transaction - essa transição (Cancelling -> Cancelled) é comitada à
+// parte pelo use case via TransitionPaymentTransaction, antes e depois de chamar este método
 func (r *PostgresPaymentRepository) CancelReserveBalance(ctx context.Context, tx Tx, userID, orderID string, amount int) error {
 	pgTx := tx.(*PostgresTx).tx
 
-	// Atualiza available_amount (devolve o valor reservado)
 	updateQuery := `
 		UPDATE wallets
 		SET available_amount = available_amount + $1,
@@ -147,47 +145,35 @@ func (r *PostgresPaymentRepository) CancelReserveBalance(ctx context.Context, tx
 		return fmt.Errorf("failed to update available_amount: %w", err)
 	}
 
-	// Atualiza status da transação para rejected
-	updateStatusQuery := `
-		UPDATE payment_transactions
-		SET status = 'rejected'
-		WHERE order_id = $1 AND status = 'pending'
-	`
-	_, err = pgTx.Exec(ctx, updateStatusQuery, orderID)
-	if err != nil {
-		return fmt.Errorf("failed to update transaction status: %w", err)
-	}
-
 	log.Printf("✅ [CANCEL] Released balance %d for user %s", amount, userID)
 	return nil
 }
 
-// GetPaymentTransactionByOrderIDAndStatus verifica se existe transação com orderID e status específicos
-func (r *PostgresPaymentRepository) GetPaymentTransactionByOrderIDAndStatus(ctx context.Context, tx Tx, orderID, status string) (bool, error) {
+// GetPaymentTransactionStatusByOrderID retorna o status da transação pelo orderID
+func (r *PostgresPaymentRepository) GetPaymentTransactionStatusByOrderID(ctx context.Context, tx Tx, orderID string) (string, error) {
 	pgTx := tx.(*PostgresTx).tx
 
 	query := `
-		SELECT transaction_id
+		SELECT status
 		FROM payment_transactions
-		WHERE order_id = $1 AND status = $2
+		WHERE order_id = $1
 	`
 
-	var id int64
-	err := pgTx.QueryRow(ctx, query, orderID, status).Scan(&id)
+	var status string
+	err := pgTx.QueryRow(ctx, query, orderID).Scan(&status)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return false, nil
+			return "", nil // Sem transação encontrada
 		}
-		return false, fmt.Errorf("failed to query payment transactions by status: %w", err)
+		return "", fmt.Errorf("failed to query payment transaction status: %w", err)
 	}
 
-	return true, nil
+	return status, nil
 }
 
-// GetPaymentTransactionStatusByOrderID retorna o status da transação pelo orderID
-func (r *PostgresPaymentRepository) GetPaymentTransactionStatusByOrderID(ctx context.Context, tx Tx, orderID string) (string, error) {
-	pgTx := tx.(*PostgresTx).tx
-
+// GetPaymentTransactionStatus é o equivalente de GetPaymentTransactionStatusByOrderID fora de uma
+// transação de negócio já aberta
+func (r *PostgresPaymentRepository) GetPaymentTransactionStatus(ctx context.Context, orderID string) (string, error) {
 	query := `
 		SELECT status
 		FROM payment_transactions
@@ -195,7 +181,7 @@ func (r *PostgresPaymentRepository) GetPaymentTransactionStatusByOrderID(ctx con
 	`
 
 	var status string
-	err := pgTx.QueryRow(ctx, query, orderID).Scan(&status)
+	err := r.pool.QueryRow(ctx, query, orderID).Scan(&status)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return "", nil // Sem transação encontrada
@@ -205,3 +191,64 @@ func (r *PostgresPaymentRepository) GetPaymentTransactionStatusByOrderID(ctx con
 
 	return status, nil
 }
+
+// TransitionPaymentTransaction move a linha de orderID de from para to em sua própria transação
+// curta, rejeitando a mutação se o estado atual não for exatamente from - tanto por (from, to)
+// não constar em validTransitions (transição ilegal, ex: Completed -> Cancelled) quanto por outra
+// chamada já ter avançado a linha (ok=false sem erro, idempotência normal em reentregas do DTM).
+// lastErr, quando não nil, é persistido em last_error para diagnóstico do Reconciler.
+func (r *PostgresPaymentRepository) TransitionPaymentTransaction(ctx context.Context, orderID, from, to string, lastErr error) (bool, error) {
+	if !isValidTransition(from, to) {
+		return false, fmt.Errorf("illegal payment transaction transition %s -> %s for order %s", from, to, orderID)
+	}
+
+	var lastErrMsg *string
+	if lastErr != nil {
+		msg := lastErr.Error()
+		lastErrMsg = &msg
+	}
+
+	query := `
+		UPDATE payment_transactions
+		SET status = $1,
+			updated_at = NOW(),
+			attempt_count = attempt_count + 1,
+			last_error = $2
+		WHERE order_id = $3 AND status = $4
+	`
+	tag, err := r.pool.Exec(ctx, query, to, lastErrMsg, orderID, from)
+	if err != nil {
+		return false, fmt.Errorf("failed to transition payment transaction %s (%s -> %s): %w", orderID, from, to, err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListStuckTransactions lista linhas em qualquer um dos states informados sem atualização há mais
+// de olderThan, usado pelo Reconciler para encontrar trabalho pendente
+func (r *PostgresPaymentRepository) ListStuckTransactions(ctx context.Context, states []string, olderThan time.Duration) ([]StuckTransaction, error) {
+	query := `
+		SELECT order_id, user_id, COALESCE(gid, ''), amount, status
+		FROM payment_transactions
+		WHERE status = ANY($1) AND updated_at < NOW() - $2::interval
+	`
+	rows, err := r.pool.Query(ctx, query, states, olderThan.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stuck payment transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var stuck []StuckTransaction
+	for rows.Next() {
+		var t StuckTransaction
+		if err := rows.Scan(&t.OrderID, &t.UserID, &t.GID, &t.Amount, &t.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan stuck payment transaction: %w", err)
+		}
+		stuck = append(stuck, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate stuck payment transactions: %w", err)
+	}
+
+	return stuck, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+// Estados possíveis de uma linha de payment_transactions. Substituem as strings livres
+// "pending"/"completed"/"rejected" usadas até aqui: Reserved cobre o antigo "pending", e as
+// fases CONFIRM/CANCEL passam por um estado intermediário (Confirming/Cancelling) comitado
+// separadamente da mutação de saldo que vem a seguir (ver TransitionPaymentTransaction e
+// ConfirmDebitWallet/CancelDebitWallet em usecases.go) - é esse meio-termo observável que dá ao
+// Reconciler (reconciler.go) algo para encontrar quando o processo cai no meio de uma fase.
+const (
+	TxStateStarted    = "started"
+	TxStateReserved   = "reserved"
+	TxStateConfirming = "confirming"
+	TxStateCompleted  = "completed"
+	TxStateCancelling = "cancelling"
+	TxStateCancelled  = "cancelled"
+	TxStateFailed     = "failed"
+)
+
+// validTransitions enumera as transições permitidas pela máquina de estados; qualquer par
+// (from, to) ausente daqui é rejeitado por TransitionPaymentTransaction. TxStateStarted não
+// aparece como origem de nenhuma transição porque TRY reserva o saldo e cria a linha já em
+// TxStateReserved atomicamente - não há uma fase "Started" observável separada neste fluxo.
+var validTransitions = map[string]map[string]bool{
+	TxStateReserved:   {TxStateConfirming: true, TxStateCancelling: true, TxStateFailed: true},
+	TxStateConfirming: {TxStateCompleted: true, TxStateFailed: true},
+	TxStateCancelling: {TxStateCancelled: true, TxStateFailed: true},
+}
+
+// isValidTransition reporta se a transição from -> to é permitida pela máquina de estados acima
+func isValidTransition(from, to string) bool {
+	targets, ok := validTransitions[from]
+	if !ok {
+		return false
+	}
+	return targets[to]
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// dtmGlobalStatus é a projeção do registro de transação global do DTM relevante para o
+// Reconciler: só o campo "status" importa aqui (o Reconciler decide o desfecho de uma linha
+// presa a partir dele), diferente do equivalente em dtm/saga/services/orders, que expõe a
+// transação inteira com suas branches para um endpoint de tracking voltado ao cliente.
+type dtmGlobalStatus struct {
+	GID    string
+	Status string
+}
+
+// dtmQueryResponse espelha o shape de resposta de {DTM_SERVER}/query?gid=...: um objeto
+// "transaction" com os campos da transação global e um array "branches" (ignorado aqui)
+type dtmQueryResponse struct {
+	Transaction struct {
+		Gid    string `json:"gid"`
+		Status string `json:"status"`
+	} `json:"transaction"`
+}
+
+// queryDTMTransaction consulta o DTM pelo estado atual de uma transação global. O registro pode
+// já ter sido coletado pelo GC do DTM (transações antigas); o chamador (Reconciler.Sweep) trata
+// esse erro como "desfecho ainda desconhecido" e deixa a linha presa para a próxima varredura,
+// em vez de forçar uma transição - mesma postura adotada por
+// dtm/saga/services/orders/transaction_status.go para o endpoint de tracking.
+func queryDTMTransaction(ctx context.Context, dtmServer, gid string) (*dtmGlobalStatus, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	url := fmt.Sprintf("%s/query?gid=%s", dtmServer, gid)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build DTM query request: %w", err)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DTM for gid %s: %w", gid, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DTM query for gid %s returned status %d", gid, resp.StatusCode)
+	}
+
+	var parsed dtmQueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode DTM query response for gid %s: %w", gid, err)
+	}
+
+	return &dtmGlobalStatus{GID: parsed.Transaction.Gid, Status: parsed.Transaction.Status}, nil
+}
@@ -2,22 +2,80 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 )
 
 // PaymentUseCase encapsula a lógica de negócio de pagamentos
 type PaymentUseCase struct {
 	repository PaymentRepository
+	sagaLog    SagaLogRepository
+	outbox     OutboxRepository
 }
 
 // NewPaymentUseCase cria uma nova instância do caso de uso
-func NewPaymentUseCase(repository PaymentRepository) *PaymentUseCase {
+func NewPaymentUseCase(repository PaymentRepository, sagaLog SagaLogRepository, outbox OutboxRepository) *PaymentUseCase {
 	return &PaymentUseCase{
 		repository: repository,
+		sagaLog:    sagaLog,
+		outbox:     outbox,
 	}
 }
 
+// appendSagaEvent grava o evento no log de auditoria sem interromper a fase TCC em caso de falha
+func (uc *PaymentUseCase) appendSagaEvent(ctx context.Context, req TCCActionRequest, phase, status string) {
+	if uc.sagaLog == nil {
+		return
+	}
+
+	event := &SagaEvent{
+		OrderID:     req.OrderID,
+		Service:     "payment",
+		Phase:       phase,
+		Status:      status,
+		PayloadHash: hashPayload(req),
+		TraceID:     traceIDFromTraceparent(req.Traceparent),
+	}
+	if err := uc.sagaLog.AppendEvent(ctx, event); err != nil {
+		log.Printf("⚠️ [SAGA LOG] failed to append event | OrderID=%s Phase=%s | Error=%v", req.OrderID, phase, err)
+	}
+}
+
+// enqueueOutboxEvent grava, dentro da mesma transação de negócio, um evento descrevendo a
+// mudança de fase TCC que acabou de ser aplicada. Ao contrário do saga_events (gravado após o
+// commit, apenas para auditoria), esta entrada é o que o relay assíncrono entrega ao callback
+// configurado - por isso precisa existir atomicamente junto com a mudança de estado.
+func (uc *PaymentUseCase) enqueueOutboxEvent(ctx context.Context, tx Tx, req TCCActionRequest, phase string) error {
+	if uc.outbox == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	event := &OutboxEvent{
+		OrderID: req.OrderID,
+		Phase:   phase,
+		Status:  OutboxStatusPending,
+		Payload: payload,
+	}
+	return uc.outbox.EnqueueOutboxEvent(ctx, tx, event)
+}
+
+// traceIDFromTraceparent extrai o trace-id de um header traceparent W3C (formato
+// 00-{trace-id}-{parent-id}-{flags}), usado apenas para preencher o log de auditoria
+func traceIDFromTraceparent(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 {
+		return ""
+	}
+	return parts[1]
+}
+
 // TryDebitWallet fase TRY do TCC - reserva o saldo
 func (uc *PaymentUseCase) TryDebitWallet(ctx context.Context, req TCCActionRequest) error {
 	log.Printf("💳 [TRY] Reserve balance: UserID=%s, Amount=%d, OrderID=%s",
@@ -41,16 +99,17 @@ func (uc *PaymentUseCase) TryDebitWallet(ctx context.Context, req TCCActionReque
 		return err
 	}
 
-	// Verifica idempotência - se já existe transação com status pending
-	exists, err := uc.repository.GetPaymentTransactionByOrderIDAndStatus(ctx, tx, req.OrderID, "pending")
+	// Verifica idempotência - se já existe transação para este pedido, a fase TRY já foi
+	// aplicada (reentrega do DTM); usa o mesmo método de status empregado por CONFIRM/CANCEL
+	txStatus, err := uc.repository.GetPaymentTransactionStatusByOrderID(ctx, tx, req.OrderID)
 	if err != nil {
-		log.Printf("❌ TRY FAILED: GetPaymentTransactionByOrderIDAndStatus | OrderID=%s | Error=%v", req.OrderID, err)
+		log.Printf("❌ TRY FAILED: GetPaymentTransactionStatusByOrderID | OrderID=%s | Error=%v", req.OrderID, err)
 
 		return err
 	}
 
-	if exists {
-		log.Printf("ℹ️ [TRY] Payment transaction already pending for OrderID=%s", req.OrderID)
+	if txStatus != "" {
+		log.Printf("ℹ️ [TRY] Payment transaction already exists (status=%s) for OrderID=%s", txStatus, req.OrderID)
 		return nil
 	}
 
@@ -61,27 +120,79 @@ func (uc *PaymentUseCase) TryDebitWallet(ctx context.Context, req TCCActionReque
 	}
 
 	// Executa a reserva
-	if err := uc.repository.TryReserveBalance(ctx, tx, req.UserID, req.OrderID, req.TotalPrice); err != nil {
+	if err := uc.repository.TryReserveBalance(ctx, tx, req.UserID, req.OrderID, req.GID, req.TotalPrice); err != nil {
 		log.Printf("❌ [TRY] Failed to reserve balance: %v", err)
 		return err
 	}
 
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, SagaPhaseTry); err != nil {
+		log.Printf("❌ [TRY] Failed to enqueue outbox event: %v", err)
+		return err
+	}
+
 	// Commit da transação
 	if err := tx.Commit(); err != nil {
 		return err
 	}
 
+	uc.appendSagaEvent(ctx, req, SagaPhaseTry, "ok")
 	return nil
 }
 
+// markPaymentTransactionFailed tenta mover a linha de from para TxStateFailed registrando cause
+// em last_error, sem propagar um eventual erro dessa transição - o chamador já está retornando o
+// erro original, e uma linha que nem sequer consegue ir a Failed continua presa em from, onde o
+// Reconciler a encontrará de qualquer forma
+func (uc *PaymentUseCase) markPaymentTransactionFailed(ctx context.Context, orderID, from string, cause error) {
+	if _, err := uc.repository.TransitionPaymentTransaction(ctx, orderID, from, TxStateFailed, cause); err != nil {
+		log.Printf("⚠️ [TRANSITION] failed to mark OrderID=%s as %s after error: %v", orderID, TxStateFailed, err)
+	}
+}
+
 // ConfirmDebitWallet fase CONFIRM do TCC - confirma o débito
 func (uc *PaymentUseCase) ConfirmDebitWallet(ctx context.Context, req TCCActionRequest) error {
 	log.Printf("✅ [CONFIRM] Confirm debit: UserID=%s, Amount=%d, OrderID=%s",
 		req.UserID, req.TotalPrice, req.OrderID)
 
+	// Verifica idempotência fora de uma transação de negócio - decide se ainda há trabalho a
+	// fazer antes de sequer tentar a transição de estado
+	status, err := uc.repository.GetPaymentTransactionStatus(ctx, req.OrderID)
+	if err != nil {
+		log.Printf("❌ CONFIRM FAILED: GetPaymentTransactionStatus | OrderID=%s | Error=%v", req.OrderID, err)
+		return err
+	}
+	switch status {
+	case "":
+		log.Printf("ℹ️ [CONFIRM] No payment transaction found for OrderID=%s", req.OrderID)
+		return nil
+	case TxStateCompleted:
+		log.Printf("ℹ️ [CONFIRM] Payment transaction already completed for OrderID=%s", req.OrderID)
+		return nil
+	case TxStateCancelled, TxStateFailed:
+		log.Printf("❌ [CONFIRM] Cannot confirm %s transaction for OrderID=%s", status, req.OrderID)
+		return fmt.Errorf("cannot confirm %s transaction for order %s", status, req.OrderID)
+	}
+
+	// Se a linha já está em Confirming, uma tentativa anterior (desta mesma chamada ou de uma
+	// reentrega/reconciliação) já comitou a transição e pode ter caído antes de debitar - segue
+	// direto para o débito em vez de tentar Reserved -> Confirming de novo, que falharia (ok=false)
+	// e faria esta chamada devolver sucesso sem jamais debitar o saldo
+	if status != TxStateConfirming {
+		ok, err := uc.repository.TransitionPaymentTransaction(ctx, req.OrderID, TxStateReserved, TxStateConfirming, nil)
+		if err != nil {
+			log.Printf("❌ CONFIRM FAILED: TransitionPaymentTransaction | OrderID=%s | Error=%v", req.OrderID, err)
+			return err
+		}
+		if !ok {
+			log.Printf("ℹ️ [CONFIRM] OrderID=%s already advanced past %s, nothing to do", req.OrderID, TxStateReserved)
+			return nil
+		}
+	}
+
 	// Inicia transação
 	tx, err := uc.repository.BeginTx(ctx)
 	if err != nil {
+		uc.markPaymentTransactionFailed(ctx, req.OrderID, TxStateConfirming, err)
 		return err
 	}
 	defer tx.Rollback()
@@ -90,34 +201,36 @@ func (uc *PaymentUseCase) ConfirmDebitWallet(ctx context.Context, req TCCActionR
 	_, err = uc.repository.GetWalletForUpdate(ctx, tx, req.UserID)
 	if err != nil {
 		log.Printf("❌ CONFIRM FAILED: GetWalletForUpdate | OrderID=%s | Error=%v", req.OrderID, err)
-
-		return err
-	}
-
-	// Verifica idempotência - se já existe transação com status completed
-	exists, err := uc.repository.GetPaymentTransactionByOrderIDAndStatus(ctx, tx, req.OrderID, "completed")
-	if err != nil {
-		log.Printf("❌ CONFIRM FAILED: GetPaymentTransactionByOrderIDAndStatus | OrderID=%s | Error=%v", req.OrderID, err)
-
+		uc.markPaymentTransactionFailed(ctx, req.OrderID, TxStateConfirming, err)
 		return err
 	}
 
-	if exists {
-		log.Printf("ℹ️ [CONFIRM] Payment transaction already completed for OrderID=%s", req.OrderID)
-		return nil
-	}
-
 	// Executa a confirmação
 	if err := uc.repository.ConfirmDebit(ctx, tx, req.UserID, req.OrderID, req.TotalPrice); err != nil {
 		log.Printf("❌ [CONFIRM] | OrderID=%s Failed to debit: %v", req.OrderID, err)
+		uc.markPaymentTransactionFailed(ctx, req.OrderID, TxStateConfirming, err)
+		return err
+	}
+
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, SagaPhaseConfirm); err != nil {
+		log.Printf("❌ [CONFIRM] | OrderID=%s Failed to enqueue outbox event: %v", req.OrderID, err)
+		uc.markPaymentTransactionFailed(ctx, req.OrderID, TxStateConfirming, err)
 		return err
 	}
 
 	// Commit da transação
 	if err := tx.Commit(); err != nil {
+		uc.markPaymentTransactionFailed(ctx, req.OrderID, TxStateConfirming, err)
 		return err
 	}
 
+	// O saldo já foi debitado e commitado - se esta transição falhar, a linha fica presa em
+	// Confirming e o Reconciler a completa mais tarde consultando o DTM, sem redebitar
+	if _, err := uc.repository.TransitionPaymentTransaction(ctx, req.OrderID, TxStateConfirming, TxStateCompleted, nil); err != nil {
+		log.Printf("⚠️ [CONFIRM] OrderID=%s debited but failed to mark %s: %v", req.OrderID, TxStateCompleted, err)
+	}
+
+	uc.appendSagaEvent(ctx, req, SagaPhaseConfirm, "ok")
 	return nil
 }
 
@@ -126,9 +239,43 @@ func (uc *PaymentUseCase) CancelDebitWallet(ctx context.Context, req TCCActionRe
 	log.Printf("🔄 [CANCEL] Cancel balance reservation: UserID=%s, Amount=%d, OrderID=%s",
 		req.UserID, req.TotalPrice, req.OrderID)
 
+	// Verifica o status atual da transação fora de uma transação de negócio
+	status, err := uc.repository.GetPaymentTransactionStatus(ctx, req.OrderID)
+	if err != nil {
+		log.Printf("❌ CANCEL FAILED: GetPaymentTransactionStatus | OrderID=%s | Error=%v", req.OrderID, err)
+		return err
+	}
+	switch status {
+	case "":
+		log.Printf("ℹ️ [CANCEL] No payment transaction found for OrderID=%s", req.OrderID)
+		return nil
+	case TxStateCancelled:
+		log.Printf("ℹ️ [CANCEL] Payment transaction already %s for OrderID=%s", TxStateCancelled, req.OrderID)
+		return nil
+	case TxStateCompleted:
+		log.Printf("❌ [CANCEL] Cannot cancel completed transaction for OrderID=%s", req.OrderID)
+		return fmt.Errorf("cannot cancel completed transaction for order %s", req.OrderID)
+	}
+
+	// Se a linha já está em Cancelling, uma tentativa anterior já comitou a transição e pode ter
+	// caído antes de devolver o saldo - segue direto para a liberação em vez de tentar
+	// Reserved -> Cancelling de novo, que falharia (ok=false) e devolveria sucesso sem liberar nada
+	if status != TxStateCancelling {
+		ok, err := uc.repository.TransitionPaymentTransaction(ctx, req.OrderID, TxStateReserved, TxStateCancelling, nil)
+		if err != nil {
+			log.Printf("❌ CANCEL FAILED: TransitionPaymentTransaction | OrderID=%s | Error=%v", req.OrderID, err)
+			return err
+		}
+		if !ok {
+			log.Printf("ℹ️ [CANCEL] OrderID=%s already advanced past %s, nothing to do", req.OrderID, TxStateReserved)
+			return nil
+		}
+	}
+
 	// Inicia transação
 	tx, err := uc.repository.BeginTx(ctx)
 	if err != nil {
+		uc.markPaymentTransactionFailed(ctx, req.OrderID, TxStateCancelling, err)
 		return err
 	}
 	defer tx.Rollback()
@@ -137,53 +284,50 @@ func (uc *PaymentUseCase) CancelDebitWallet(ctx context.Context, req TCCActionRe
 	_, err = uc.repository.GetWalletForUpdate(ctx, tx, req.UserID)
 	if err != nil {
 		log.Printf("❌ CANCEL FAILED: GetWalletForUpdate | OrderID=%s | Error=%v", req.OrderID, err)
-
+		uc.markPaymentTransactionFailed(ctx, req.OrderID, TxStateCancelling, err)
 		return err
 	}
 
-	// Verifica o status atual da transação
-	status, err := uc.repository.GetPaymentTransactionStatusByOrderID(ctx, tx, req.OrderID)
-	if err != nil {
-		log.Printf("❌ CANCEL FAILED: GetPaymentTransactionStatusByOrderID | OrderID=%s | Error=%v", req.OrderID, err)
+	if err := uc.repository.CancelReserveBalance(ctx, tx, req.UserID, req.OrderID, req.TotalPrice); err != nil {
+		log.Printf("❌ [CANCEL] ORDER_ID %s | Failed to release balance: %v", req.OrderID, err)
+		uc.markPaymentTransactionFailed(ctx, req.OrderID, TxStateCancelling, err)
 		return err
 	}
 
-	// Se não encontrar registro na tabela pelo order_id, pode retornar nil
-	if status == "" {
-		log.Printf("ℹ️ [CANCEL] No payment transaction found for OrderID=%s", req.OrderID)
-		return nil
-	}
-
-	// Se já foi rejeitado, idempotência
-	if status == "rejected" {
-		log.Printf("ℹ️ [CANCEL] Payment transaction already rejected for OrderID=%s", req.OrderID)
-		return nil
-	}
-
-	// Se foi completado, retorna erro pois não pode ser revertido
-	if status == "completed" {
-		log.Printf("❌ [CANCEL] Cannot cancel completed transaction for OrderID=%s", req.OrderID)
-		return fmt.Errorf("cannot cancel completed transaction for order %s", req.OrderID)
-	}
-
-	// Se está pending, pode cancelar
-	if err := uc.repository.CancelReserveBalance(ctx, tx, req.UserID, req.OrderID, req.TotalPrice); err != nil {
-		log.Printf("❌ [CANCEL] ORDER_ID %s | Failed to release balance: %v", req.OrderID, err)
+	if err := uc.enqueueOutboxEvent(ctx, tx, req, SagaPhaseCancel); err != nil {
+		log.Printf("❌ [CANCEL] ORDER_ID %s | Failed to enqueue outbox event: %v", req.OrderID, err)
+		uc.markPaymentTransactionFailed(ctx, req.OrderID, TxStateCancelling, err)
 		return err
 	}
 
 	// Commit da transação
 	if err := tx.Commit(); err != nil {
+		uc.markPaymentTransactionFailed(ctx, req.OrderID, TxStateCancelling, err)
 		return err
 	}
 
+	if _, err := uc.repository.TransitionPaymentTransaction(ctx, req.OrderID, TxStateCancelling, TxStateCancelled, nil); err != nil {
+		log.Printf("⚠️ [CANCEL] OrderID=%s released but failed to mark %s: %v", req.OrderID, TxStateCancelled, err)
+	}
+
+	uc.appendSagaEvent(ctx, req, SagaPhaseCancel, "ok")
 	return nil
 }
 
+// BusinessError é implementado por erros que representam um desfecho de negócio determinístico
+// (saldo insuficiente, usuário inexistente, ...), para os quais reexecutar a branch TCC não muda o
+// resultado. writeTCCResponse (handlers.go) usa esse contrato para decidir entre a convenção
+// FAILURE do DTM (409 com dtm_result, não retentar) e um 500 genérico (retentar)
+type BusinessError interface {
+	error
+	BusinessError() bool
+}
+
 // Erros customizados
 var (
 	ErrInvalidAmount       = &PaymentError{Message: "amount must be greater than 0"}
 	ErrInsufficientBalance = &PaymentError{Message: "insufficient balance"}
+	ErrUserNotFound        = &PaymentError{Message: "user not found"}
 )
 
 type PaymentError struct {
@@ -193,3 +337,11 @@ type PaymentError struct {
 func (e *PaymentError) Error() string {
 	return e.Message
 }
+
+// BusinessError marca um resultado de negócio determinístico (saldo insuficiente, usuário
+// inexistente, etc), em oposição a uma falha de infraestrutura transitória (conexão com o banco,
+// timeout, ...). writeTCCResponse usa essa distinção para traduzir o erro na convenção FAILURE
+// do DTM em vez de sempre devolver 500 - ver handlers.go
+func (e *PaymentError) BusinessError() bool {
+	return true
+}
@@ -0,0 +1,79 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dtm-labs/client/dtmcli"
+	"github.com/go-resty/resty/v2"
+)
+
+// DTMOrchestrator implementa Orchestrator delegando para o coordenador DTM, reproduzindo a
+// lógica hoje duplicada em cada serviço (dtm.go de orders/payment/inventory por protocolo)
+type DTMOrchestrator struct {
+	server string
+}
+
+// NewDTMOrchestrator cria um Orchestrator que fala com a instância DTM em `server`
+func NewDTMOrchestrator(server string) *DTMOrchestrator {
+	return &DTMOrchestrator{server: server}
+}
+
+// SubmitSaga envia uma dtmcli.Saga com uma branch (action/compensate) por participante
+func (o *DTMOrchestrator) SubmitSaga(ctx context.Context, gid string, branches []Branch) error {
+	saga := dtmcli.NewSaga(o.server, gid)
+	for _, b := range branches {
+		saga = saga.Add(b.Action, b.Compensate, b.Payload)
+	}
+
+	if err := saga.Submit(); err != nil {
+		return fmt.Errorf("DTM saga submission failed: %w", err)
+	}
+	return nil
+}
+
+// SubmitTCC registra as branches Try/Confirm/Cancel via dtmcli.TccGlobalTransaction
+func (o *DTMOrchestrator) SubmitTCC(ctx context.Context, gid string, branches []Branch) error {
+	err := dtmcli.TccGlobalTransaction(o.server, gid, func(tcc *dtmcli.Tcc) (*resty.Response, error) {
+		var resp *resty.Response
+		var err error
+		for _, b := range branches {
+			resp, err = tcc.CallBranch(b.Payload, b.Action, b.Confirm, b.Compensate)
+			if err != nil {
+				return resp, fmt.Errorf("TCC branch registration failed (%s): %w", b.Action, err)
+			}
+		}
+		return resp, nil
+	})
+	if err != nil {
+		return fmt.Errorf("DTM TCC registration failed: %w", err)
+	}
+	return nil
+}
+
+// SubmitXA coordena um 2PC clássico via dtmcli.XaGlobalTransaction2
+func (o *DTMOrchestrator) SubmitXA(ctx context.Context, gid string, branches []Branch) error {
+	err := dtmcli.XaGlobalTransaction2(o.server, gid, func(xa *dtmcli.Xa) {}, func(xa *dtmcli.Xa) (*resty.Response, error) {
+		var resp *resty.Response
+		var err error
+		for _, b := range branches {
+			resp, err = xa.CallBranch(b.Payload, b.Action)
+			if err != nil {
+				return resp, fmt.Errorf("XA branch failed (%s): %w", b.Action, err)
+			}
+		}
+		return resp, nil
+	})
+	if err != nil {
+		return fmt.Errorf("DTM XA transaction failed: %w", err)
+	}
+	return nil
+}
+
+// Status consulta o estado da transação diretamente no DTM server
+func (o *DTMOrchestrator) Status(ctx context.Context, orderID string) (TransactionStatus, error) {
+	// O DTM não indexa transações pelo OrderID de negócio, apenas pelo GID gerado por ele;
+	// serviços que precisem desse lookup devem manter o mapeamento OrderID -> GID (ex: via o
+	// log de auditoria saga_events) e consultar o DTM admin API com o GID correspondente.
+	return TransactionStatus{}, fmt.Errorf("DTM backend does not support status lookup by order id: %s", orderID)
+}
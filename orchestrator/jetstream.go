@@ -0,0 +1,264 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Subjects/suffixes usados pelo backend JetStream: cada branch vira um passo (ex: "orders.reserve",
+// "inventory.reserve", "payment.debit"), com contrapartes de compensação e conclusão
+const (
+	subjectCompensateSuffix = ".compensate"
+	subjectCompletedSuffix  = ".completed"
+	subjectFailedSuffix     = ".failed"
+)
+
+// redeliveryBackoff é a política de redelivery exponencial aplicada pelos consumers duráveis a
+// mensagens nak'd (erro transitório do participante)
+var redeliveryBackoff = []time.Duration{1 * time.Second, 5 * time.Second, 30 * time.Second}
+
+// jetStreamStep é o estado em memória de uma saga em andamento: a lista completa de branches e o
+// índice do passo atualmente pendente, necessário para decidir o próximo publish ou a ordem de
+// compensação. O que sobrevive a um restart do orquestrador é apenas a linha em saga_instances
+// (gid, step, status, last_event_seq); esta lista em memória não é reconstruída a partir dela -
+// depois de um restart, handleStepEvent aceita e descarta os eventos de um gid desconhecido, e a
+// saga fica parada no último estado gravado em saga_instances.
+type jetStreamStep struct {
+	branches []Branch
+	index    int
+}
+
+// JetStreamOrchestrator implementa Orchestrator rodando a saga Orders/Inventory/Payment de forma
+// assíncrona sobre NATS JetStream: SubmitSaga publica o primeiro passo e retorna imediatamente
+// (o handler HTTP responde 202 Accepted sem esperar o resultado), e um pool de consumers
+// duráveis avança a saga conforme os eventos `<step>.completed`/`<step>.failed` chegam
+type JetStreamOrchestrator struct {
+	conn jetstream.JetStream
+	pool NativePool
+
+	mutex   sync.Mutex
+	pending map[string]*jetStreamStep
+}
+
+// NewJetStreamOrchestrator conecta ao NATS em `url`, garante o stream que cobre os subjects de
+// saga e devolve um Orchestrator pronto para submeter sagas assíncronas. `pool` persiste
+// saga_instances (criada pela mesma migration que cria saga_events e native_transactions)
+func NewJetStreamOrchestrator(ctx context.Context, url string, pool NativePool) (*JetStreamOrchestrator, error) {
+	nc, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("jetstream orchestrator failed to connect to %s: %w", url, err)
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("jetstream orchestrator failed to create context: %w", err)
+	}
+
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     "SAGA",
+		Subjects: []string{"orders.>", "inventory.>", "payment.>"},
+	}); err != nil {
+		return nil, fmt.Errorf("jetstream orchestrator failed to create stream: %w", err)
+	}
+
+	return &JetStreamOrchestrator{
+		conn:    js,
+		pool:    pool,
+		pending: make(map[string]*jetStreamStep),
+	}, nil
+}
+
+// SubmitSaga publica o primeiro branch como um comando assíncrono e retorna assim que o
+// JetStream confirma a persistência da mensagem - o restante da saga é conduzido pelos consumers
+// iniciados por StartConsumers
+func (o *JetStreamOrchestrator) SubmitSaga(ctx context.Context, gid string, branches []Branch) error {
+	if len(branches) == 0 {
+		return fmt.Errorf("jetstream saga requires at least one branch")
+	}
+
+	o.mutex.Lock()
+	o.pending[gid] = &jetStreamStep{branches: branches, index: 0}
+	o.mutex.Unlock()
+
+	return o.publishStep(ctx, gid, branches[0], "pending")
+}
+
+// SubmitTCC não é suportado: o backend JetStream modela apenas a progressão assíncrona
+// action -> compensate de uma SAGA, sem as três fases try/confirm/cancel do TCC
+func (o *JetStreamOrchestrator) SubmitTCC(ctx context.Context, gid string, branches []Branch) error {
+	return fmt.Errorf("jetstream backend does not support TCC transactions")
+}
+
+// SubmitXA não é suportado pelas mesmas razões do backend Temporal/nativo: 2PC exige um
+// coordenador síncrono com o banco participando do protocolo PREPARE/COMMIT
+func (o *JetStreamOrchestrator) SubmitXA(ctx context.Context, gid string, branches []Branch) error {
+	return fmt.Errorf("jetstream backend does not support XA/2PC transactions")
+}
+
+// Status lê o estado persistido da saga na tabela saga_instances
+func (o *JetStreamOrchestrator) Status(ctx context.Context, orderID string) (TransactionStatus, error) {
+	status := TransactionStatus{GID: orderID}
+	err := o.pool.QueryRow(ctx, `SELECT status FROM saga_instances WHERE gid = $1`, []any{orderID}, &status.Status)
+	if err != nil {
+		return TransactionStatus{}, fmt.Errorf("failed to read saga instance status: %w", err)
+	}
+	return status, nil
+}
+
+// StartConsumers registra os consumers duráveis que avançam as sagas em andamento, consumindo
+// `*.completed` e `*.failed` de cada domínio até ctx ser cancelado. Deve ser chamado uma vez por
+// processo orquestrador.
+func (o *JetStreamOrchestrator) StartConsumers(ctx context.Context) error {
+	stream, err := o.conn.Stream(ctx, "SAGA")
+	if err != nil {
+		return fmt.Errorf("jetstream orchestrator failed to look up stream: %w", err)
+	}
+
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:        "saga-orchestrator",
+		FilterSubjects: []string{"orders.*.completed", "orders.*.failed", "inventory.*.completed", "inventory.*.failed", "payment.*.completed", "payment.*.failed"},
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		BackOff:        redeliveryBackoff,
+		MaxDeliver:     len(redeliveryBackoff) + 1,
+	})
+	if err != nil {
+		return fmt.Errorf("jetstream orchestrator failed to create consumer: %w", err)
+	}
+
+	consumeCtx, err := consumer.Consume(o.handleStepEvent)
+	if err != nil {
+		return fmt.Errorf("jetstream orchestrator failed to start consuming: %w", err)
+	}
+	defer consumeCtx.Stop()
+
+	<-ctx.Done()
+	return nil
+}
+
+// stepEvent é o payload publicado em `<step>.completed`/`<step>.failed`
+type stepEvent struct {
+	GID   string `json:"gid"`
+	Error string `json:"error,omitempty"`
+}
+
+func (o *JetStreamOrchestrator) handleStepEvent(msg jetstream.Msg) {
+	var event stepEvent
+	if err := json.Unmarshal(msg.Data(), &event); err != nil {
+		log.Printf("❌ [JETSTREAM ORCHESTRATOR] failed to decode step event on %s: %v", msg.Subject(), err)
+		_ = msg.Term()
+		return
+	}
+
+	o.mutex.Lock()
+	step, ok := o.pending[event.GID]
+	o.mutex.Unlock()
+	if !ok {
+		// Saga desconhecida (ex: restart do orquestrador) - aceita a mensagem para não travar a
+		// fila; a reconciliação de sagas perdidas fica a cargo do GID lookup via saga_instances
+		log.Printf("⚠️ [JETSTREAM ORCHESTRATOR] received event for unknown gid=%s, acking and skipping", event.GID)
+		_ = msg.Ack()
+		return
+	}
+
+	failed := subjectHasSuffix(msg.Subject(), subjectFailedSuffix)
+
+	if failed {
+		o.compensate(context.Background(), event.GID, step)
+		_ = o.recordState(context.Background(), event.GID, step.branches[step.index].Action, "failed")
+		_ = msg.Ack()
+		return
+	}
+
+	step.index++
+	if step.index >= len(step.branches) {
+		_ = o.recordState(context.Background(), event.GID, "", "succeeded")
+		o.mutex.Lock()
+		delete(o.pending, event.GID)
+		o.mutex.Unlock()
+		_ = msg.Ack()
+		return
+	}
+
+	if err := o.publishStep(context.Background(), event.GID, step.branches[step.index], "pending"); err != nil {
+		log.Printf("❌ [JETSTREAM ORCHESTRATOR] failed to publish next step for gid=%s: %v", event.GID, err)
+		_ = msg.Nak()
+		return
+	}
+
+	_ = msg.Ack()
+}
+
+// compensate publica `<step>.compensate` para todos os passos já concluídos, em ordem reversa -
+// mesma semântica de NativeOrchestrator.compensate/TemporalOrchestrator.compensateSaga
+func (o *JetStreamOrchestrator) compensate(ctx context.Context, gid string, step *jetStreamStep) {
+	for i := step.index - 1; i >= 0; i-- {
+		b := step.branches[i]
+		if b.Compensate == "" {
+			continue
+		}
+		subject := b.Action + subjectCompensateSuffix
+		payload, err := json.Marshal(b.Payload)
+		if err != nil {
+			log.Printf("⚠️ [JETSTREAM ORCHESTRATOR] failed to marshal compensate payload for gid=%s step=%s: %v", gid, b.Action, err)
+			continue
+		}
+		if _, err := o.conn.Publish(ctx, subject, payload); err != nil {
+			log.Printf("⚠️ [JETSTREAM ORCHESTRATOR] compensate publish to %s failed for gid=%s: %v", subject, gid, err)
+		}
+	}
+}
+
+// publishStep publica o comando de um branch no subject indicado por Branch.Action, carregando o
+// trace context W3C nos headers da mensagem para que o consumer do participante linke seu span ao
+// do publisher - o equivalente, sob mensageria, da propagação via payload usada no caminho HTTP
+func (o *JetStreamOrchestrator) publishStep(ctx context.Context, gid string, branch Branch, status string) error {
+	payload, err := json.Marshal(branch.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal step payload for gid=%s step=%s: %w", gid, branch.Action, err)
+	}
+
+	headers := make(nats.Header)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(headers))
+
+	ack, err := o.conn.PublishMsg(ctx, &nats.Msg{
+		Subject: branch.Action,
+		Header:  headers,
+		Data:    payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish step %s for gid=%s: %w", branch.Action, gid, err)
+	}
+
+	return o.recordState(ctx, gid, branch.Action, status, ack.Sequence)
+}
+
+func (o *JetStreamOrchestrator) recordState(ctx context.Context, gid, step, status string, lastEventSeq ...uint64) error {
+	seq := uint64(0)
+	if len(lastEventSeq) > 0 {
+		seq = lastEventSeq[0]
+	}
+
+	_, err := o.pool.Exec(ctx, `
+		INSERT INTO saga_instances (gid, step, status, last_event_seq, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (gid) DO UPDATE SET step = EXCLUDED.step, status = EXCLUDED.status,
+			last_event_seq = EXCLUDED.last_event_seq, updated_at = NOW()
+	`, gid, step, status, seq)
+	if err != nil {
+		return fmt.Errorf("failed to persist saga instance state: %w", err)
+	}
+	return nil
+}
+
+func subjectHasSuffix(subject, suffix string) bool {
+	return len(subject) >= len(suffix) && subject[len(subject)-len(suffix):] == suffix
+}
@@ -0,0 +1,118 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// NativePool é o subconjunto de pgxpool.Pool usado pelo backend nativo, restrito para evitar
+// que este pacote dependa diretamente do driver escolhido por cada serviço
+type NativePool interface {
+	Exec(ctx context.Context, sql string, args ...any) (int64, error)
+	QueryRow(ctx context.Context, sql string, args []any, dest ...any) error
+}
+
+// NativeOrchestrator é um backend leve que dispensa um coordenador externo: ele mesmo chama os
+// participantes via HTTP, em processo, e persiste o estado da transação em Postgres para
+// sobreviver a um restart do serviço
+type NativeOrchestrator struct {
+	pool NativePool
+}
+
+// NewNativeOrchestrator cria um Orchestrator que persiste estado em `pool` (tabela
+// `native_transactions`, criada pela mesma migration que cria `saga_events`)
+func NewNativeOrchestrator(pool NativePool) *NativeOrchestrator {
+	return &NativeOrchestrator{pool: pool}
+}
+
+// SubmitSaga executa as branches em sequência, compensando as anteriores em ordem reversa em
+// caso de falha - mesma semântica do dtmcli.Saga, mas conduzida por este processo
+func (o *NativeOrchestrator) SubmitSaga(ctx context.Context, gid string, branches []Branch) error {
+	if err := o.recordState(ctx, gid, "pending"); err != nil {
+		return err
+	}
+
+	executed := 0
+	for i, b := range branches {
+		if err := CallBranchActivity(ctx, b.Action, b.Payload); err != nil {
+			o.compensate(ctx, branches[:executed])
+			_ = o.recordState(ctx, gid, "failed")
+			return fmt.Errorf("saga branch %d (%s) failed: %w", i, b.Action, err)
+		}
+		executed++
+	}
+
+	return o.recordState(ctx, gid, "succeeded")
+}
+
+// SubmitTCC executa Try em cada branch e, se todas tiverem sucesso, confirma todas; qualquer
+// falha em Try aciona o Cancel das branches já tentadas
+func (o *NativeOrchestrator) SubmitTCC(ctx context.Context, gid string, branches []Branch) error {
+	if err := o.recordState(ctx, gid, "pending"); err != nil {
+		return err
+	}
+
+	tried := 0
+	for i, b := range branches {
+		if err := CallBranchActivity(ctx, b.Action, b.Payload); err != nil {
+			o.cancel(ctx, branches[:tried])
+			_ = o.recordState(ctx, gid, "failed")
+			return fmt.Errorf("tcc try %d (%s) failed: %w", i, b.Action, err)
+		}
+		tried++
+	}
+
+	for i, b := range branches {
+		if err := CallBranchActivity(ctx, b.Confirm, b.Payload); err != nil {
+			_ = o.recordState(ctx, gid, "failed")
+			return fmt.Errorf("tcc confirm %d (%s) failed: %w", i, b.Confirm, err)
+		}
+	}
+
+	return o.recordState(ctx, gid, "succeeded")
+}
+
+// SubmitXA não é suportado: 2PC exige o banco participando do protocolo (PREPARE/COMMIT), o que
+// este backend - que só orquestra chamadas HTTP - não pode fazer sem um driver XA real
+func (o *NativeOrchestrator) SubmitXA(ctx context.Context, gid string, branches []Branch) error {
+	return fmt.Errorf("native backend does not support XA/2PC transactions")
+}
+
+// Status lê o estado persistido da transação na tabela native_transactions
+func (o *NativeOrchestrator) Status(ctx context.Context, orderID string) (TransactionStatus, error) {
+	status := TransactionStatus{GID: orderID}
+	err := o.pool.QueryRow(ctx, `SELECT status FROM native_transactions WHERE gid = $1`, []any{orderID}, &status.Status)
+	if err != nil {
+		return TransactionStatus{}, fmt.Errorf("failed to read native transaction status: %w", err)
+	}
+	return status, nil
+}
+
+func (o *NativeOrchestrator) recordState(ctx context.Context, gid, status string) error {
+	_, err := o.pool.Exec(ctx, `
+		INSERT INTO native_transactions (gid, status, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (gid) DO UPDATE SET status = EXCLUDED.status, updated_at = NOW()
+	`, gid, status)
+	if err != nil {
+		return fmt.Errorf("failed to persist native transaction state: %w", err)
+	}
+	return nil
+}
+
+func (o *NativeOrchestrator) compensate(ctx context.Context, executed []Branch) {
+	for i := len(executed) - 1; i >= 0; i-- {
+		b := executed[i]
+		if b.Compensate == "" {
+			continue
+		}
+		if err := CallBranchActivity(ctx, b.Compensate, b.Payload); err != nil {
+			log.Printf("⚠️ [NATIVE ORCHESTRATOR] compensation call to %s failed: %v", b.Compensate, err)
+		}
+	}
+}
+
+func (o *NativeOrchestrator) cancel(ctx context.Context, tried []Branch) {
+	o.compensate(ctx, tried)
+}
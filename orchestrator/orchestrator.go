@@ -0,0 +1,99 @@
+// Package orchestrator abstrai o coordenador de transações distribuídas usado pelos serviços
+// (orders/inventory/payment), permitindo trocar o backend de SAGA/TCC/XA sem alterar os
+// use cases. Hoje o repositório depende diretamente do DTM; este pacote existe para que
+// orquestradores alternativos (Temporal, um motor nativo em Go, JetStream) possam ser plugados
+// via a variável de ambiente ORCHESTRATOR_BACKEND, o que viabiliza comparar latência/throughput
+// do mesmo workload entre coordenadores distintos.
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Branch representa um participante de uma transação distribuída (SAGA/TCC/XA)
+type Branch struct {
+	// Action é a URL (ou endereço lógico) chamada na fase de execução/try
+	Action string
+	// Compensate é a URL chamada para desfazer a ação (vazio quando não há compensação, ex: XA)
+	Compensate string
+	// Confirm é a URL chamada na fase de confirmação (apenas TCC)
+	Confirm string
+	// Payload é serializado e enviado para cada chamada de branch
+	Payload any
+}
+
+// TransactionStatus representa o estado observável de uma transação distribuída
+type TransactionStatus struct {
+	GID    string `json:"gid"`
+	Status string `json:"status"` // pending | succeeded | failed | compensating
+}
+
+// Orchestrator é implementado por cada backend de coordenação (DTM, Temporal, nativo)
+type Orchestrator interface {
+	// SubmitSaga envia uma sequência de branches com compensação (action/compensate) executadas
+	// em ordem, desfazendo as anteriores em caso de falha
+	SubmitSaga(ctx context.Context, gid string, branches []Branch) error
+
+	// SubmitTCC registra as branches Try/Confirm/Cancel de uma transação TCC
+	SubmitTCC(ctx context.Context, gid string, branches []Branch) error
+
+	// SubmitXA coordena um 2PC clássico entre os participantes informados
+	SubmitXA(ctx context.Context, gid string, branches []Branch) error
+
+	// Status consulta o estado atual de uma transação pelo seu GID/OrderID
+	Status(ctx context.Context, orderID string) (TransactionStatus, error)
+}
+
+// Backend identifica a implementação de Orchestrator selecionada via ORCHESTRATOR_BACKEND
+type Backend string
+
+const (
+	BackendDTM       Backend = "dtm"
+	BackendTemporal  Backend = "temporal"
+	BackendNative    Backend = "native"
+	BackendJetStream Backend = "jetstream"
+)
+
+// BackendFromEnv lê ORCHESTRATOR_BACKEND (default "dtm")
+func BackendFromEnv() Backend {
+	switch Backend(os.Getenv("ORCHESTRATOR_BACKEND")) {
+	case BackendTemporal:
+		return BackendTemporal
+	case BackendNative:
+		return BackendNative
+	case BackendJetStream:
+		return BackendJetStream
+	default:
+		return BackendDTM
+	}
+}
+
+// New constrói o Orchestrator correspondente ao backend selecionado
+func New(ctx context.Context, backend Backend, cfg Config) (Orchestrator, error) {
+	switch backend {
+	case BackendDTM:
+		return NewDTMOrchestrator(cfg.DTMServer), nil
+	case BackendTemporal:
+		return NewTemporalOrchestrator(cfg.TemporalHostPort, cfg.TemporalNamespace, cfg.TemporalTaskQueue)
+	case BackendNative:
+		return NewNativeOrchestrator(cfg.Pool), nil
+	case BackendJetStream:
+		return NewJetStreamOrchestrator(ctx, cfg.NatsURL, cfg.Pool)
+	default:
+		return nil, fmt.Errorf("unknown orchestrator backend: %s", backend)
+	}
+}
+
+// Config agrega os parâmetros necessários para construir qualquer um dos backends suportados
+type Config struct {
+	DTMServer         string
+	TemporalHostPort  string
+	TemporalNamespace string
+	TemporalTaskQueue string
+	// NatsURL é usado apenas pelo backend JetStream
+	NatsURL string
+	// Pool é usado pelos backends nativo e JetStream, que persistem estado em Postgres
+	Pool NativePool
+}
@@ -0,0 +1,164 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/workflow"
+)
+
+const activityTimeout = 10 * time.Second
+
+var httpClient = resty.New()
+
+// TemporalOrchestrator implementa Orchestrator executando cada fase da transação como uma
+// Temporal activity, com as compensações disparadas pela própria workflow (compensateSaga/
+// cancelTCC) assim que uma branch falha.
+type TemporalOrchestrator struct {
+	client    client.Client
+	taskQueue string
+}
+
+// NewTemporalOrchestrator conecta ao Temporal server e devolve um Orchestrator pronto para uso
+func NewTemporalOrchestrator(hostPort, namespace, taskQueue string) (*TemporalOrchestrator, error) {
+	c, err := client.Dial(client.Options{
+		HostPort:  hostPort,
+		Namespace: namespace,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to temporal server: %w", err)
+	}
+
+	return &TemporalOrchestrator{client: c, taskQueue: taskQueue}, nil
+}
+
+// SubmitSaga inicia a SagaWorkflow e aguarda sua conclusão (mesma semântica síncrona do backend
+// nativo; os backends DTM e JetStream retornam antes de as branches concluírem)
+func (o *TemporalOrchestrator) SubmitSaga(ctx context.Context, gid string, branches []Branch) error {
+	run, err := o.client.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        gid,
+		TaskQueue: o.taskQueue,
+	}, SagaWorkflow, branches)
+	if err != nil {
+		return fmt.Errorf("failed to start temporal saga workflow: %w", err)
+	}
+
+	return run.Get(ctx, nil)
+}
+
+// SubmitTCC inicia a TCCWorkflow e aguarda sua conclusão; o Cancel de cada branch TCC usa a URL
+// de Compensate e o Confirm é disparado como etapa final da workflow
+func (o *TemporalOrchestrator) SubmitTCC(ctx context.Context, gid string, branches []Branch) error {
+	run, err := o.client.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        gid,
+		TaskQueue: o.taskQueue,
+	}, TCCWorkflow, branches)
+	if err != nil {
+		return fmt.Errorf("failed to start temporal tcc workflow: %w", err)
+	}
+
+	return run.Get(ctx, nil)
+}
+
+// SubmitXA não é suportado pelo backend Temporal: XA exige um coordenador de 2PC real (locks
+// distribuídos mantidos pelo banco), que a Temporal não provê nativamente.
+func (o *TemporalOrchestrator) SubmitXA(ctx context.Context, gid string, branches []Branch) error {
+	return fmt.Errorf("temporal backend does not support XA/2PC transactions")
+}
+
+// Status consulta a execução da workflow pelo GID (usado como Workflow ID)
+func (o *TemporalOrchestrator) Status(ctx context.Context, orderID string) (TransactionStatus, error) {
+	desc, err := o.client.DescribeWorkflowExecution(ctx, orderID, "")
+	if err != nil {
+		return TransactionStatus{}, fmt.Errorf("failed to describe temporal workflow: %w", err)
+	}
+
+	status := TransactionStatus{GID: orderID, Status: "pending"}
+	switch desc.WorkflowExecutionInfo.GetStatus().String() {
+	case "WORKFLOW_EXECUTION_STATUS_COMPLETED":
+		status.Status = "succeeded"
+	case "WORKFLOW_EXECUTION_STATUS_FAILED", "WORKFLOW_EXECUTION_STATUS_TERMINATED":
+		status.Status = "failed"
+	}
+
+	return status, nil
+}
+
+// SagaWorkflow executa cada branch em sequência, compensando as anteriores (em ordem reversa)
+// assim que uma falha; equivalente ao dtmcli.Saga mas rodando como uma Temporal workflow
+func SagaWorkflow(ctx workflow.Context, branches []Branch) error {
+	ao := workflow.ActivityOptions{StartToCloseTimeout: activityTimeout}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	executed := 0
+	for i, b := range branches {
+		if err := workflow.ExecuteActivity(ctx, CallBranchActivity, b.Action, b.Payload).Get(ctx, nil); err != nil {
+			compensateSaga(ctx, branches[:executed])
+			return fmt.Errorf("saga branch %d (%s) failed: %w", i, b.Action, err)
+		}
+		executed++
+	}
+
+	return nil
+}
+
+// TCCWorkflow registra e confirma cada branch; uma falha em Try aciona o Cancel das branches já
+// tentadas, e uma falha em Confirm é devolvida sem disparar Cancel
+func TCCWorkflow(ctx workflow.Context, branches []Branch) error {
+	ao := workflow.ActivityOptions{StartToCloseTimeout: activityTimeout}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	tried := 0
+	for i, b := range branches {
+		if err := workflow.ExecuteActivity(ctx, CallBranchActivity, b.Action, b.Payload).Get(ctx, nil); err != nil {
+			cancelTCC(ctx, branches[:tried])
+			return fmt.Errorf("tcc try %d (%s) failed: %w", i, b.Action, err)
+		}
+		tried++
+	}
+
+	for i, b := range branches {
+		if err := workflow.ExecuteActivity(ctx, CallBranchActivity, b.Confirm, b.Payload).Get(ctx, nil); err != nil {
+			return fmt.Errorf("tcc confirm %d (%s) failed: %w", i, b.Confirm, err)
+		}
+	}
+
+	return nil
+}
+
+func compensateSaga(ctx workflow.Context, executed []Branch) {
+	for i := len(executed) - 1; i >= 0; i-- {
+		b := executed[i]
+		if b.Compensate == "" {
+			continue
+		}
+		_ = workflow.ExecuteActivity(ctx, CallBranchActivity, b.Compensate, b.Payload).Get(ctx, nil)
+	}
+}
+
+func cancelTCC(ctx workflow.Context, tried []Branch) {
+	for i := len(tried) - 1; i >= 0; i-- {
+		b := tried[i]
+		if b.Compensate == "" {
+			continue
+		}
+		_ = workflow.ExecuteActivity(ctx, CallBranchActivity, b.Compensate, b.Payload).Get(ctx, nil)
+	}
+}
+
+// CallBranchActivity faz o POST HTTP para a URL de uma branch, igual às chamadas que o DTM faz
+// hoje para os endpoints /try, /confirm, /cancel e /compensate dos serviços
+func CallBranchActivity(ctx context.Context, url string, payload any) error {
+	resp, err := httpClient.R().SetContext(ctx).SetBody(payload).Post(url)
+	if err != nil {
+		return fmt.Errorf("branch call to %s failed: %w", url, err)
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return fmt.Errorf("branch call to %s returned status %d", url, resp.StatusCode())
+	}
+	return nil
+}
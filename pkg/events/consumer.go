@@ -0,0 +1,37 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// redeliveryBackoff é a política de redelivery exponencial aplicada a mensagens nak'd - mesma
+// janela usada pelo consumer de orchestrator/jetstream.go, para manter o comportamento de
+// replay/retry consistente entre os dois usos de JetStream no repositório
+var redeliveryBackoff = []time.Duration{1 * time.Second, 5 * time.Second, 30 * time.Second}
+
+// NewDurablePullConsumer cria (ou reaproveita) um consumer pull durável filtrando por
+// `filterSubjects`, para que um coletor de benchmark ou serviço de auditoria possa fazer Fetch
+// dos eventos publicados por um Emitter a partir de onde parou, mesmo depois de um restart
+func NewDurablePullConsumer(ctx context.Context, js jetstream.JetStream, streamName, durableName string, filterSubjects []string) (jetstream.Consumer, error) {
+	stream, err := js.Stream(ctx, streamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up stream %s: %w", streamName, err)
+	}
+
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:        durableName,
+		FilterSubjects: filterSubjects,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		BackOff:        redeliveryBackoff,
+		MaxDeliver:     len(redeliveryBackoff) + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create durable consumer %s on stream %s: %w", durableName, streamName, err)
+	}
+
+	return consumer, nil
+}
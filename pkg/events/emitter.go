@@ -0,0 +1,87 @@
+// Package events fornece um emissor de eventos de ciclo de vida de transação sobre NATS
+// JetStream, complementando o plano de controle HTTP/DTM (que não deixa rastro nenhum depois que
+// uma branch responde) com um log durável e ordenado de cada transição de fase
+// (saga.submitted, xa.prepared, tcc.try, ...). Isso é o que permite a um coletor de benchmark ou
+// serviço de auditoria reconstruir latência por branch e retries sem instrumentar cada serviço ad
+// hoc, e sem perder eventos publicados enquanto o consumer estava fora do ar (armazenamento em
+// arquivo + consumers pull duráveis).
+//
+// Não é o mesmo JetStream de orchestrator/jetstream.go: aquele é um barramento de comando/evento
+// que conduz a execução de uma SAGA assíncrona; este pacote é write-mostly, só para observação -
+// publica depois que a transição já aconteceu, nunca decide o que fazer a seguir.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// TransactionEvent descreve uma transição de fase de uma transação distribuída (SAGA/XA/TCC)
+type TransactionEvent struct {
+	GID        string    `json:"gid"`
+	OrderID    string    `json:"order_id"`
+	TraceID    string    `json:"trace_id,omitempty"`
+	SpanID     string    `json:"span_id,omitempty"`
+	UserID     string    `json:"user_id,omitempty"`
+	ProductID  string    `json:"product_id,omitempty"`
+	EventType  string    `json:"event_type"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	EmittedAt  time.Time `json:"emitted_at"`
+}
+
+// Emitter publica eventos de transição de fase de uma transação distribuída
+type Emitter interface {
+	Emit(ctx context.Context, event TransactionEvent) error
+}
+
+// JetStreamEmitter implementa Emitter publicando em um stream JetStream com armazenamento em
+// arquivo, no subject `tx.events.<event_type>`
+type JetStreamEmitter struct {
+	js         jetstream.JetStream
+	streamName string
+}
+
+// NewJetStreamEmitter conecta a `natsURL`, garante a existência do stream `streamName` cobrindo
+// `subjects` com armazenamento em arquivo (sobrevive a um restart do NATS) e devolve um emissor
+// pronto para uso
+func NewJetStreamEmitter(ctx context.Context, natsURL, streamName string, subjects []string) (*JetStreamEmitter, error) {
+	nc, err := nats.Connect(natsURL)
+	if err != nil {
+		return nil, fmt.Errorf("events emitter failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("events emitter failed to create jetstream context: %w", err)
+	}
+
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     streamName,
+		Subjects: subjects,
+		Storage:  jetstream.FileStorage,
+	}); err != nil {
+		return nil, fmt.Errorf("events emitter failed to create stream %s: %w", streamName, err)
+	}
+
+	return &JetStreamEmitter{js: js, streamName: streamName}, nil
+}
+
+// Emit publica o evento no subject `tx.events.<event_type>`
+func (e *JetStreamEmitter) Emit(ctx context.Context, event TransactionEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transaction event %s: %w", event.EventType, err)
+	}
+
+	subject := fmt.Sprintf("tx.events.%s", event.EventType)
+	if _, err := e.js.Publish(ctx, subject, payload); err != nil {
+		return fmt.Errorf("failed to publish transaction event to %s: %w", subject, err)
+	}
+
+	return nil
+}
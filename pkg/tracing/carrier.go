@@ -0,0 +1,25 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ExtractAndStart extrai o trace context de um carrier genérico e inicia, a partir dele, um span
+// filho chamado spanName. Consolida o padrão hoje duplicado em cada serviço TCC/SAGA como
+// startSpanFromActionPayload/startSpanFromPayload, que montam um propagation.MapCarrier a partir
+// dos campos Traceparent/Tracestate do payload da ação.
+//
+// Diferente de InjectMap (a forma que o 2PC/XA replica localmente via dtmcli.Xa.BranchHeaders, um hook de headers HTTP
+// que o resty interno do dtmcli aplica antes de cada chamada de branch), o TCC e a SAGA
+// orquestradora não têm equivalente: as chamadas try/confirm/cancel são feitas pelo próprio
+// servidor DTM - um processo separado que este código não instrumenta - então o trace context
+// precisa viajar no corpo da ação (TCCActionRequest/SagaActionRequest.Traceparent/Tracestate) em
+// vez de em um header HTTP injetado por este pacote.
+func ExtractAndStart(ctx context.Context, tracer trace.Tracer, spanName string, carrier propagation.TextMapCarrier) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	return tracer.Start(ctx, spanName)
+}
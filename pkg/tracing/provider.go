@@ -0,0 +1,74 @@
+// Package tracing centraliza a configuração de OpenTelemetry comum aos serviços do benchmark:
+// o TracerProvider exportando para o OTel Collector via OTLP/HTTP e o TextMapPropagator composto
+// (W3C TraceContext + Baggage) usado tanto para extrair o contexto de requisições HTTP recebidas
+// quanto para injetá-lo em chamadas HTTP de saída. Este
+// pacote não é importado pelos serviços em dtm/* porque não há go.mod ligando os módulos - cada
+// serviço replica localmente a parte mínima necessária (em geral só a linha de
+// otel.SetTextMapPropagator dentro do seu próprio initTracer), mas pkg/tracing documenta a forma
+// canônica dessa configuração para quando os módulos forem unificados.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// Config agrupa os parâmetros necessários para montar um TracerProvider
+type Config struct {
+	ServiceName    string
+	ServiceVersion string
+	OTLPEndpoint   string
+}
+
+// Propagator é o TextMapPropagator padrão usado por todos os serviços: TraceContext para o
+// traceparent/tracestate do W3C e Baggage para atributos de negócio propagados entre branches
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	)
+}
+
+// NewTracerProvider cria o TracerProvider exportando via OTLP/HTTP, registra-o globalmente e
+// instala o Propagator composto como TextMapPropagator global
+func NewTracerProvider(ctx context.Context, cfg Config) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpoint(cfg.OTLPEndpoint),
+		otlptracehttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	version := cfg.ServiceVersion
+	if version == "" {
+		version = "1.0.0"
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(cfg.ServiceName),
+			semconv.ServiceVersion(version),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(Propagator())
+
+	return tp, nil
+}
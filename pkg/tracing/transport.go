@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// roundTripper injeta o TextMapPropagator global em toda requisição de saída, para que chamadas
+// HTTP entre serviços carreguem traceparent/tracestate/baggage sem que o chamador precise lembrar
+// de fazê-lo manualmente
+type roundTripper struct {
+	base http.RoundTripper
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	return rt.base.RoundTrip(req)
+}
+
+// WrapClient retorna uma cópia de client cujo Transport injeta o trace context propagado em toda
+// requisição de saída. Use para clientes *http.Client usados fora do fluxo coordenado pelo DTM
+// (que tem seu próprio mecanismo de headers, ver InjectMap)
+func WrapClient(client *http.Client) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *client
+	wrapped.Transport = &roundTripper{base: base}
+	return &wrapped
+}
+
+// InjectMap propaga o trace context de ctx para um map[string]string, formato esperado por APIs
+// que não aceitam um http.RoundTripper customizado - por exemplo dtmcli.Xa.BranchHeaders, usado
+// para anexar traceparent/tracestate às chamadas de branch do 2PC/XA feitas pelo resty interno do
+// DTM. Não se aplica ao TCC/SAGA orquestrador: ver o comentário de ExtractAndStart em carrier.go
+// para o porquê.
+func InjectMap(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}